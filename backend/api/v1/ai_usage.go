@@ -0,0 +1,47 @@
+package v1
+
+// AIUsageRequest 查询生成摘要消耗的 token，日期格式为 2006-01-02，按 feishu.timezone 划分，
+// 截止日期包含当天，默认为最近 30 天。sheet_id 为空时统计所有表格
+type AIUsageRequest struct {
+	SpreadsheetToken string `json:"sheet_id"`
+	DateFrom         string `json:"date_from"`
+	DateTo           string `json:"date_to"`
+}
+
+// AIUsageTotal 一段时间内调用模型的次数和消耗的 token
+type AIUsageTotal struct {
+	Calls            int `json:"calls"`
+	FailedCalls      int `json:"failed_calls"`
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// AIUsageDaily 每天的用量，没有调用的日期同样返回
+type AIUsageDaily struct {
+	Date string `json:"date"`
+	AIUsageTotal
+}
+
+// AIUsageSheet 每个表格的用量，按消耗的 token 从多到少排列
+type AIUsageSheet struct {
+	SheetID   string `json:"sheet_id"`
+	SheetName string `json:"sheet_name"`
+	AIUsageTotal
+}
+
+// AIUsageBudget 当前的预算和已消耗的 token，预算为 0 表示不限制，Paused 表示已暂停生成摘要
+type AIUsageBudget struct {
+	Daily       int  `json:"daily"`
+	DailyUsed   int  `json:"daily_used"`
+	Monthly     int  `json:"monthly"`
+	MonthlyUsed int  `json:"monthly_used"`
+	Paused      bool `json:"paused"`
+}
+
+type AIUsageResponse struct {
+	Total  AIUsageTotal   `json:"total"`
+	Daily  []AIUsageDaily `json:"daily"`
+	Sheets []AIUsageSheet `json:"sheets"`
+	Budget AIUsageBudget  `json:"budget"`
+}
@@ -7,7 +7,10 @@ var (
 	ErrUnauthorized        = newError(401, "Unauthorized")
 	ErrNotFound            = newError(404, "Not Found")
 	ErrInternalServerError = newError(500, "Internal Server Error")
+	ErrServiceUnavailable  = newError(503, "Service Unavailable")
 
 	// more biz errors
 	ErrEmailAlreadyUse = newError(1001, "The email is already in use.")
+	ErrSheetSyncing    = newError(1101, "The sheet is already syncing.")
+	ErrOriginMirrored  = newError(1102, "The origin sheet is already mirrored by another sheet.")
 )
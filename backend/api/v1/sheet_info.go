@@ -0,0 +1,92 @@
+package v1
+
+import "time"
+
+// ColumnMapping 列映射，每一列可以填写列字母（如 B）或第一行的表头名称，多维表格填写字段名称
+type ColumnMapping struct {
+	Title string            `json:"title" validate:"required"`
+	Link  string            `json:"link"`
+	Date  string            `json:"date"`
+	Extra map[string]string `json:"extra"`
+	// Abstract、Keyword 回写 AI 摘要和关键字的列，仅支持电子表格，留空则不回写
+	Abstract string `json:"abstract"`
+	Keyword  string `json:"keyword"`
+}
+
+type SheetRegisterRequest struct {
+	SpreadsheetToken string         `json:"sheet_id" validate:"required"`
+	SheetName        string         `json:"sheet_name"`
+	SourceType       string         `json:"source_type" validate:"omitempty,oneof=sheet bitable"`
+	TableID          string         `json:"table_id"`
+	SyncInterval     int            `json:"sync_interval" validate:"omitempty,min=60"`
+	ColumnMapping    *ColumnMapping `json:"column_mapping"`
+	// OriginSheetID、OriginSheetName 登记 RPA 拷贝的镜像表格时填写原始加密表格的 token 和名称
+	OriginSheetID   string `json:"origin_sheet_id"`
+	OriginSheetName string `json:"origin_sheet_name"`
+}
+
+type SheetUpdateStatusRequest struct {
+	SpreadsheetToken string `json:"sheet_id" validate:"required"`
+	Status           string `json:"status" validate:"required,oneof=active paused"`
+}
+
+// SheetMuteRequest 开启或关闭表格新增文章的群机器人通知
+type SheetMuteRequest struct {
+	SpreadsheetToken string `json:"sheet_id" validate:"required"`
+	Muted            bool   `json:"muted"`
+}
+
+// SheetMirrorReportRequest RPA 每次拷贝完加密表格后上报的结果
+type SheetMirrorReportRequest struct {
+	// SpreadsheetToken 拷贝到的个人表格
+	SpreadsheetToken string `json:"sheet_id" validate:"required"`
+	OriginSheetID    string `json:"origin_sheet_id"`
+	OriginSheetName  string `json:"origin_sheet_name"`
+	// RowCount 拷贝的文章行数，不包括表头
+	RowCount int `json:"row_count" validate:"min=0"`
+	// Checksum 拷贝内容的校验值，由 RPA 计算，与上次相同时不会触发同步
+	Checksum string `json:"checksum" validate:"required,max=64"`
+}
+
+type SheetMirrorReportResponse struct {
+	SyncScheduled bool `json:"sync_scheduled"`
+}
+
+type SheetRemoveRequest struct {
+	SpreadsheetToken string `json:"sheet_id" validate:"required"`
+}
+
+type SheetInfoItem struct {
+	SheetID          string           `json:"sheet_id"`
+	SheetName        string           `json:"sheet_name"`
+	SourceType       string           `json:"source_type"`
+	TableID          string           `json:"table_id"`
+	OriginSheetID    string           `json:"origin_sheet_id"`
+	OriginSheetName  string           `json:"origin_sheet_name"`
+	MirrorRowCount   int              `json:"mirror_row_count"`
+	MirrorReportedAt *time.Time       `json:"mirror_reported_at"`
+	MirrorStale      bool             `json:"mirror_stale"`
+	Status           string           `json:"status"`
+	SyncInterval     int              `json:"sync_interval"`
+	NotifyMuted      bool             `json:"notify_muted"`
+	UpdateLog        string           `json:"update_log"`
+	LastSyncAt       *time.Time       `json:"last_sync_at"`
+	RuntimeState     string           `json:"runtime_state"`
+	ColumnMapping    ColumnMapping    `json:"column_mapping"`
+	LastSyncResult   *SheetSyncResult `json:"last_sync_result"`
+}
+
+type SheetSyncResult struct {
+	Skipped bool     `json:"skipped"`
+	Rows    int      `json:"rows"`
+	Deleted []string `json:"deleted"`
+	Written int      `json:"written"`
+}
+
+type SheetListResponse struct {
+	List []SheetInfoItem `json:"list"`
+}
+
+type FeiShuEventResponse struct {
+	Challenge string `json:"challenge,omitempty"`
+}
@@ -1,19 +1,39 @@
 package v1
 
+import "time"
+
+// 文章列表的排序方式
+const (
+	ListSortPosition = "position"
+	ListSortDateDesc = "date_desc"
+	ListSortDateAsc  = "date_asc"
+)
+
 type ShengCaiListRequest struct {
 	SpreadsheetToken string `json:"sheet_id" validate:"required"`
+	TabID            string `json:"tab_id"`
 	Page             int    `json:"page" validate:"required,min=1"`
+	// DateFrom、DateTo 按发布日期筛选，格式为 2006-01-02，包含两端的日期
+	DateFrom string `json:"date_from" validate:"omitempty,datetime=2006-01-02"`
+	DateTo   string `json:"date_to" validate:"omitempty,datetime=2006-01-02"`
+	// Sort 默认按文章在表格中的位置排序，date_desc、date_asc 按发布日期排序
+	Sort string `json:"sort" validate:"omitempty,oneof=position date_desc date_asc"`
 }
 
 type ShengCaiListResponse struct {
 	TotalCount int `json:"total_count"`
 	List       []struct {
-		SheetID     string `json:"sheet_id"`
-		Title       string `json:"title"`
-		Link        string `json:"link"`
-		ReleaseDate string `json:"release_date"`
-		Abstract    string `json:"abstract"`
-		Keyword     string `json:"keyword"`
+		SheetID     string            `json:"sheet_id"`
+		TabID       string            `json:"tab_id"`
+		TabTitle    string            `json:"tab_title"`
+		Title       string            `json:"title"`
+		Link        string            `json:"link"`
+		LinkType    string            `json:"link_type"`
+		ReleaseDate string            `json:"release_date"`
+		PublishedAt *time.Time        `json:"published_at"`
+		Abstract    string            `json:"abstract"`
+		Keyword     string            `json:"keyword"`
+		Extra       map[string]string `json:"extra"`
 	} `json:"list"`
 }
 
@@ -24,4 +44,60 @@ type ShengCaiGetMetaDataRequest struct {
 type ShengCaiGetMetaDataResponse struct {
 	SheetName string `json:"sheet_name"`
 	UpdateLog string `json:"update_log"`
+	// UpdatedAt 表格最后修改的时间，与 UpdateLog 相同
+	UpdatedAt *time.Time `json:"updated_at"`
+	Tabs      []struct {
+		TabID    string `json:"tab_id"`
+		TabTitle string `json:"tab_title"`
+		TabIndex int    `json:"tab_index"`
+	} `json:"tabs"`
+}
+
+type ShengCaiDetailRequest struct {
+	SpreadsheetToken string `json:"sheet_id" validate:"required"`
+	Link             string `json:"link" validate:"required"`
+}
+
+// ShengCaiDetailResponse 文章详情，AIExtra 为模型补充的信息，AIState 为摘要的生成结果：skipped、ok、fallback、failed
+type ShengCaiDetailResponse struct {
+	SheetID         string            `json:"sheet_id"`
+	TabID           string            `json:"tab_id"`
+	TabTitle        string            `json:"tab_title"`
+	Title           string            `json:"title"`
+	Link            string            `json:"link"`
+	LinkType        string            `json:"link_type"`
+	ReleaseDate     string            `json:"release_date"`
+	PublishedAt     *time.Time        `json:"published_at"`
+	Abstract        string            `json:"abstract"`
+	Keyword         string            `json:"keyword"`
+	AIExtra         map[string]string `json:"ai_extra"`
+	AIState         string            `json:"ai_state"`
+	Extra           map[string]string `json:"extra"`
+	Content         string            `json:"content"`
+	ContentMarkdown string            `json:"content_markdown"`
+}
+
+type ShengCaiCommentsRequest struct {
+	SpreadsheetToken string `json:"sheet_id" validate:"required"`
+	Link             string `json:"link" validate:"required"`
+}
+
+// ShengCaiCommentReply 评论中的一条回复，第一条回复为评论本身
+type ShengCaiCommentReply struct {
+	ReplyID   string     `json:"reply_id"`
+	UserID    string     `json:"user_id"`
+	Content   string     `json:"content"`
+	ReplyTime *time.Time `json:"reply_time"`
+}
+
+// ShengCaiComment 文档中的一条评论及其回复
+type ShengCaiComment struct {
+	CommentID string                 `json:"comment_id"`
+	Quote     string                 `json:"quote"`
+	Solved    bool                   `json:"solved"`
+	Replies   []ShengCaiCommentReply `json:"replies"`
+}
+
+type ShengCaiCommentsResponse struct {
+	List []ShengCaiComment `json:"list"`
 }
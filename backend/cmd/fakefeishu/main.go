@@ -0,0 +1,29 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+
+	"shengcai/pkg/fakefeishu"
+)
+
+// 本地模拟飞书开放平台，将配置中的 feishu.base_url 指向该服务即可离线运行同步流程：
+//
+//	go run ./cmd/fakefeishu -addr :8090 -fixtures test/fixtures/feishu
+func main() {
+	var addr = flag.String("addr", ":8090", "listen address, eg: -addr :8090")
+	var dir = flag.String("fixtures", "test/fixtures/feishu", "fixtures directory, eg: -fixtures ./test/fixtures/feishu")
+	flag.Parse()
+
+	fixtures, err := fakefeishu.LoadFixtures(*dir)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("fake feishu listening on %s, %d spreadsheets, %d documents, %d faults\n",
+		*addr, len(fixtures.Spreadsheets), len(fixtures.Documents), len(fixtures.Faults))
+	if err = http.ListenAndServe(*addr, fakefeishu.NewServer(fixtures)); err != nil {
+		panic(err)
+	}
+}
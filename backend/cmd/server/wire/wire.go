@@ -11,7 +11,10 @@ import (
 	"shengcai/internal/server"
 	"shengcai/internal/service"
 	"shengcai/pkg/app"
+	"shengcai/pkg/blob"
+	"shengcai/pkg/feishu"
 	"shengcai/pkg/jwt"
+	"shengcai/pkg/llm"
 	"shengcai/pkg/log"
 	"shengcai/pkg/server/http"
 	"shengcai/pkg/sid"
@@ -27,6 +30,9 @@ var repositorySet = wire.NewSet(
 	repository.NewSheetInfoRepository,
 	repository.NewCellDataRepository,
 	repository.NewAIRepository,
+	repository.NewAIUsageRepository,
+	repository.NewCommentRepository,
+	repository.NewMediaRepository,
 )
 
 var serviceSet = wire.NewSet(
@@ -34,12 +40,21 @@ var serviceSet = wire.NewSet(
 	service.NewUserService,
 	service.NewFeiShuService,
 	service.NewShengCaiService,
+	service.NewSheetInfoService,
+	service.NewEventService,
+	service.NewNotifierService,
+	service.NewMediaService,
+	service.NewAIUsageService,
 )
 
 var handlerSet = wire.NewSet(
 	handler.NewHandler,
 	handler.NewUserHandler,
 	handler.NewShengCaiHandler,
+	handler.NewSheetInfoHandler,
+	handler.NewEventHandler,
+	handler.NewMediaHandler,
+	handler.NewAIUsageHandler,
 )
 
 var serverSet = wire.NewSet(
@@ -67,6 +82,9 @@ func NewWire(*viper.Viper, *log.Logger) (*app.App, func(), error) {
 		serverSet,
 		sid.NewSid,
 		jwt.NewJwt,
+		feishu.NewClient,
+		blob.NewStore,
+		llm.NewProvider,
 		newApp,
 	))
 }
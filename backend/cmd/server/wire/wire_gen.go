@@ -14,7 +14,10 @@ import (
 	"shengcai/internal/server"
 	"shengcai/internal/service"
 	"shengcai/pkg/app"
+	"shengcai/pkg/blob"
+	"shengcai/pkg/feishu"
 	"shengcai/pkg/jwt"
+	"shengcai/pkg/llm"
 	"shengcai/pkg/log"
 	"shengcai/pkg/server/http"
 	"shengcai/pkg/sid"
@@ -34,12 +37,30 @@ func NewWire(viperViper *viper.Viper, logger *log.Logger) (*app.App, func(), err
 	userService := service.NewUserService(serviceService, userRepository)
 	userHandler := handler.NewUserHandler(handlerHandler, userService)
 	sheetInfoRepository := repository.NewSheetInfoRepository(repositoryRepository)
-	aiRepository := repository.NewAIRepository(repositoryRepository)
+	provider, err := llm.NewProvider(viperViper)
+	if err != nil {
+		return nil, nil, err
+	}
+	aiUsageRepository := repository.NewAIUsageRepository(repositoryRepository)
+	aiRepository := repository.NewAIRepository(repositoryRepository, provider, aiUsageRepository)
 	cellDataRepository := repository.NewCellDataRepository(repositoryRepository, aiRepository)
-	feiShuService := service.NewFeiShuService(serviceService, sheetInfoRepository, cellDataRepository)
-	shengCaiService := service.NewShengCaiService(serviceService, feiShuService, cellDataRepository)
+	client := feishu.NewClient(viperViper)
+	commentRepository := repository.NewCommentRepository(repositoryRepository)
+	mediaRepository := repository.NewMediaRepository(repositoryRepository)
+	store := blob.NewStore(viperViper)
+	mediaService := service.NewMediaService(serviceService, client, mediaRepository, store)
+	feiShuService := service.NewFeiShuService(serviceService, client, sheetInfoRepository, cellDataRepository, commentRepository, mediaService)
+	notifierService := service.NewNotifierService(serviceService, client, cellDataRepository)
+	shengCaiService := service.NewShengCaiService(serviceService, feiShuService, cellDataRepository, sheetInfoRepository, commentRepository, notifierService)
 	shengCaiHandler := handler.NewShengCaiHandler(handlerHandler, shengCaiService)
-	httpServer := server.NewHTTPServer(logger, viperViper, jwtJWT, userHandler, shengCaiHandler)
+	eventService := service.NewEventService(serviceService, shengCaiService, sheetInfoRepository)
+	sheetInfoService := service.NewSheetInfoService(serviceService, sheetInfoRepository, eventService)
+	sheetInfoHandler := handler.NewSheetInfoHandler(handlerHandler, sheetInfoService)
+	eventHandler := handler.NewEventHandler(handlerHandler, eventService)
+	mediaHandler := handler.NewMediaHandler(handlerHandler, mediaService)
+	aiUsageService := service.NewAIUsageService(serviceService, aiUsageRepository, sheetInfoRepository)
+	aiUsageHandler := handler.NewAIUsageHandler(handlerHandler, aiUsageService)
+	httpServer := server.NewHTTPServer(logger, viperViper, jwtJWT, userHandler, shengCaiHandler, sheetInfoHandler, eventHandler, mediaHandler, aiUsageHandler)
 	job := server.NewJob(logger, shengCaiService, aiRepository)
 	appApp := newApp(httpServer, job)
 	return appApp, func() {
@@ -48,11 +69,11 @@ func NewWire(viperViper *viper.Viper, logger *log.Logger) (*app.App, func(), err
 
 // wire.go:
 
-var repositorySet = wire.NewSet(repository.NewDB, repository.NewRepository, repository.NewTransaction, repository.NewUserRepository, repository.NewShengCaiRepository, repository.NewSheetInfoRepository, repository.NewCellDataRepository, repository.NewAIRepository)
+var repositorySet = wire.NewSet(repository.NewDB, repository.NewRepository, repository.NewTransaction, repository.NewUserRepository, repository.NewShengCaiRepository, repository.NewSheetInfoRepository, repository.NewCellDataRepository, repository.NewAIRepository, repository.NewAIUsageRepository, repository.NewCommentRepository, repository.NewMediaRepository)
 
-var serviceSet = wire.NewSet(service.NewService, service.NewUserService, service.NewFeiShuService, service.NewShengCaiService)
+var serviceSet = wire.NewSet(service.NewService, service.NewUserService, service.NewFeiShuService, service.NewShengCaiService, service.NewSheetInfoService, service.NewEventService, service.NewNotifierService, service.NewMediaService, service.NewAIUsageService)
 
-var handlerSet = wire.NewSet(handler.NewHandler, handler.NewUserHandler, handler.NewShengCaiHandler)
+var handlerSet = wire.NewSet(handler.NewHandler, handler.NewUserHandler, handler.NewShengCaiHandler, handler.NewSheetInfoHandler, handler.NewEventHandler, handler.NewMediaHandler, handler.NewAIUsageHandler)
 
 var serverSet = wire.NewSet(server.NewHTTPServer, server.NewJob)
 
@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"net/http"
+	v1 "shengcai/api/v1"
+	"shengcai/internal/service"
+)
+
+type AIUsageHandler struct {
+	*Handler
+	aiUsageService service.AIUsageService
+}
+
+func NewAIUsageHandler(handler *Handler, aiUsageService service.AIUsageService) *AIUsageHandler {
+	return &AIUsageHandler{
+		Handler:        handler,
+		aiUsageService: aiUsageService,
+	}
+}
+
+func (h *AIUsageHandler) Stats(ctx *gin.Context) {
+	req := new(v1.AIUsageRequest)
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		h.logger.WithContext(ctx).Error("AIUsageHandler.Stats!!! ctx.ShouldBindJSON error", zap.Error(err))
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	if stats, err := h.aiUsageService.Stats(ctx, req); err != nil {
+		h.logger.WithContext(ctx).Error("AIUsageHandler.Stats!!! aiUsageService.Stats error", zap.Error(err))
+		v1.HandleError(ctx, statusFromError(err), err, nil)
+		return
+	} else {
+		v1.HandleSuccess(ctx, stats)
+		return
+	}
+}
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	v1 "shengcai/api/v1"
+	"shengcai/internal/service"
+)
+
+type EventHandler struct {
+	*Handler
+	eventService service.EventService
+}
+
+func NewEventHandler(handler *Handler, eventService service.EventService) *EventHandler {
+	return &EventHandler{
+		Handler:      handler,
+		eventService: eventService,
+	}
+}
+
+// FeiShu 接收飞书事件订阅的推送，飞书要求直接返回 JSON 而不是统一的响应结构
+func (h *EventHandler) FeiShu(ctx *gin.Context) {
+	body, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		h.logger.WithContext(ctx).Error("EventHandler.FeiShu!!! read body error", zap.Error(err))
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	resp, err := h.eventService.Handle(ctx, &service.EventRequest{
+		Timestamp: ctx.GetHeader("X-Lark-Request-Timestamp"),
+		Nonce:     ctx.GetHeader("X-Lark-Request-Nonce"),
+		Signature: ctx.GetHeader("X-Lark-Signature"),
+		Body:      body,
+	})
+	if err != nil {
+		h.logger.WithContext(ctx).Error("EventHandler.FeiShu!!! eventService.Handle error", zap.Error(err))
+		v1.HandleError(ctx, statusFromError(err), err, nil)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
@@ -1,7 +1,10 @@
 package handler
 
 import (
+	"errors"
 	"github.com/gin-gonic/gin"
+	"net/http"
+	v1 "shengcai/api/v1"
 	"shengcai/pkg/jwt"
 	"shengcai/pkg/log"
 )
@@ -24,3 +27,19 @@ func GetUserIdFromCtx(ctx *gin.Context) string {
 	}
 	return v.(*jwt.MyCustomClaims).UserId
 }
+
+func statusFromError(err error) int {
+	switch {
+	case errors.Is(err, v1.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, v1.ErrBadRequest):
+		return http.StatusBadRequest
+	case errors.Is(err, v1.ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, v1.ErrOriginMirrored):
+		return http.StatusConflict
+	case errors.Is(err, v1.ErrServiceUnavailable):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
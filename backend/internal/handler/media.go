@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"mime"
+	"net/http"
+	"net/url"
+	v1 "shengcai/api/v1"
+	"shengcai/internal/service"
+	"strconv"
+)
+
+// inlineContentTypes 允许在浏览器中直接显示的类型，其余一律作为附件下载，
+// 避免飞书返回的 text/html、image/svg+xml 等内容在本站域名下执行脚本
+var inlineContentTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+type MediaHandler struct {
+	*Handler
+	mediaService service.MediaService
+}
+
+func NewMediaHandler(handler *Handler, mediaService service.MediaService) *MediaHandler {
+	return &MediaHandler{
+		Handler:      handler,
+		mediaService: mediaService,
+	}
+}
+
+// Get 返回文章中引用的图片或附件
+func (h *MediaHandler) Get(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || id <= 0 {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	media, reader, err := h.mediaService.Open(ctx, id)
+	if err != nil {
+		h.logger.WithContext(ctx).Error("MediaHandler.Get!!! mediaService.Open error", zap.Int("id", id), zap.Error(err))
+		v1.HandleError(ctx, statusFromError(err), err, nil)
+		return
+	}
+	defer reader.Close()
+
+	headers := map[string]string{
+		// 内容按哈希保存，不会变化
+		"Cache-Control":          "private, max-age=31536000, immutable",
+		"ETag":                   strconv.Quote(media.Hash),
+		"X-Content-Type-Options": "nosniff",
+	}
+	contentType, disposition := "application/octet-stream", "attachment"
+	if mediaType, _, err := mime.ParseMediaType(media.ContentType); err == nil && inlineContentTypes[mediaType] {
+		contentType, disposition = mediaType, "inline"
+	}
+	if media.FileName != "" {
+		disposition = fmt.Sprintf("%s; filename*=UTF-8''%s", disposition, url.PathEscape(media.FileName))
+	}
+	headers["Content-Disposition"] = disposition
+	ctx.DataFromReader(http.StatusOK, media.Size, contentType, reader, headers)
+}
@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+	"net/http"
+	v1 "shengcai/api/v1"
+	"shengcai/internal/service"
+)
+
+type SheetInfoHandler struct {
+	*Handler
+	sheetInfoService service.SheetInfoService
+}
+
+func NewSheetInfoHandler(handler *Handler, sheetInfoService service.SheetInfoService) *SheetInfoHandler {
+	return &SheetInfoHandler{
+		Handler:          handler,
+		sheetInfoService: sheetInfoService,
+	}
+}
+
+func (h *SheetInfoHandler) Register(ctx *gin.Context) {
+	req := new(v1.SheetRegisterRequest)
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		h.logger.WithContext(ctx).Error("SheetInfoHandler.Register!!! ctx.ShouldBindJSON error", zap.Error(err))
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		h.logger.WithContext(ctx).Error("SheetInfoHandler.Register!!! validate.Struct error", zap.Error(err))
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	if err := h.sheetInfoService.Register(ctx, req); err != nil {
+		h.logger.WithContext(ctx).Error("SheetInfoHandler.Register!!! sheetInfoService.Register error", zap.Error(err))
+		v1.HandleError(ctx, statusFromError(err), err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, nil)
+}
+
+func (h *SheetInfoHandler) UpdateStatus(ctx *gin.Context) {
+	req := new(v1.SheetUpdateStatusRequest)
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		h.logger.WithContext(ctx).Error("SheetInfoHandler.UpdateStatus!!! ctx.ShouldBindJSON error", zap.Error(err))
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		h.logger.WithContext(ctx).Error("SheetInfoHandler.UpdateStatus!!! validate.Struct error", zap.Error(err))
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	if err := h.sheetInfoService.UpdateStatus(ctx, req); err != nil {
+		h.logger.WithContext(ctx).Error("SheetInfoHandler.UpdateStatus!!! sheetInfoService.UpdateStatus error", zap.Error(err))
+		v1.HandleError(ctx, statusFromError(err), err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, nil)
+}
+
+func (h *SheetInfoHandler) Mute(ctx *gin.Context) {
+	req := new(v1.SheetMuteRequest)
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		h.logger.WithContext(ctx).Error("SheetInfoHandler.Mute!!! ctx.ShouldBindJSON error", zap.Error(err))
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		h.logger.WithContext(ctx).Error("SheetInfoHandler.Mute!!! validate.Struct error", zap.Error(err))
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	if err := h.sheetInfoService.Mute(ctx, req); err != nil {
+		h.logger.WithContext(ctx).Error("SheetInfoHandler.Mute!!! sheetInfoService.Mute error", zap.Error(err))
+		v1.HandleError(ctx, statusFromError(err), err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, nil)
+}
+
+// ReportMirror RPA 拷贝完加密表格后调用，通过接口签名校验请求来源
+func (h *SheetInfoHandler) ReportMirror(ctx *gin.Context) {
+	req := new(v1.SheetMirrorReportRequest)
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		h.logger.WithContext(ctx).Error("SheetInfoHandler.ReportMirror!!! ctx.ShouldBindJSON error", zap.Error(err))
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		h.logger.WithContext(ctx).Error("SheetInfoHandler.ReportMirror!!! validate.Struct error", zap.Error(err))
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	if result, err := h.sheetInfoService.ReportMirror(ctx, req); err != nil {
+		h.logger.WithContext(ctx).Error("SheetInfoHandler.ReportMirror!!! sheetInfoService.ReportMirror error", zap.Error(err))
+		v1.HandleError(ctx, statusFromError(err), err, nil)
+		return
+	} else {
+		v1.HandleSuccess(ctx, result)
+		return
+	}
+}
+
+func (h *SheetInfoHandler) Remove(ctx *gin.Context) {
+	req := new(v1.SheetRemoveRequest)
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		h.logger.WithContext(ctx).Error("SheetInfoHandler.Remove!!! ctx.ShouldBindJSON error", zap.Error(err))
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		h.logger.WithContext(ctx).Error("SheetInfoHandler.Remove!!! validate.Struct error", zap.Error(err))
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	if err := h.sheetInfoService.Remove(ctx, req); err != nil {
+		h.logger.WithContext(ctx).Error("SheetInfoHandler.Remove!!! sheetInfoService.Remove error", zap.Error(err))
+		v1.HandleError(ctx, statusFromError(err), err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, nil)
+}
+
+func (h *SheetInfoHandler) List(ctx *gin.Context) {
+	if list, err := h.sheetInfoService.List(ctx); err != nil {
+		h.logger.WithContext(ctx).Error("SheetInfoHandler.List!!! sheetInfoService.List error", zap.Error(err))
+		v1.HandleError(ctx, statusFromError(err), err, nil)
+		return
+	} else {
+		v1.HandleSuccess(ctx, list)
+		return
+	}
+}
@@ -71,6 +71,56 @@ func (h *ShengCaiHandler) GetMetaData(ctx *gin.Context) {
 	}
 }
 
+func (h *ShengCaiHandler) Detail(ctx *gin.Context) {
+	req := new(v1.ShengCaiDetailRequest)
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		h.logger.WithContext(ctx).Error("ShengCaiHandler.Detail!!! ctx.ShouldBindJSON error", zap.Error(err))
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		h.logger.WithContext(ctx).Error("ShengCaiHandler.Detail!!! validate.Struct error", zap.Error(err))
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	if detail, err := h.shengCaiService.Detail(ctx, req); err != nil {
+		h.logger.WithContext(ctx).Error("ShengCaiHandler.Detail!!! shengCaiService.Detail error", zap.Error(err))
+		v1.HandleError(ctx, statusFromError(err), err, nil)
+		return
+	} else {
+		v1.HandleSuccess(ctx, detail)
+		return
+	}
+}
+
+func (h *ShengCaiHandler) Comments(ctx *gin.Context) {
+	req := new(v1.ShengCaiCommentsRequest)
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		h.logger.WithContext(ctx).Error("ShengCaiHandler.Comments!!! ctx.ShouldBindJSON error", zap.Error(err))
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		h.logger.WithContext(ctx).Error("ShengCaiHandler.Comments!!! validate.Struct error", zap.Error(err))
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	if comments, err := h.shengCaiService.Comments(ctx, req); err != nil {
+		h.logger.WithContext(ctx).Error("ShengCaiHandler.Comments!!! shengCaiService.Comments error", zap.Error(err))
+		v1.HandleError(ctx, statusFromError(err), err, nil)
+		return
+	} else {
+		v1.HandleSuccess(ctx, comments)
+		return
+	}
+}
+
 func (h *ShengCaiHandler) CreateData(ctx *gin.Context) {
 	if err := h.shengCaiService.CreateData(ctx); err != nil {
 		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
@@ -39,15 +39,37 @@ func StrictAuth(j *jwt.JWT, logger *log.Logger) gin.HandlerFunc {
 	}
 }
 
-func NoStrictAuth(j *jwt.JWT, logger *log.Logger) gin.HandlerFunc {
+// ReadAuth 与 StrictAuth 一样要求登录，但 token 也可以来自 Cookie 或 accessToken 参数，
+// 用于 <img> 等无法设置请求头的只读请求
+func ReadAuth(j *jwt.JWT, logger *log.Logger) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		tokenString := ctx.Request.Header.Get("Authorization")
+		tokenString := requestToken(ctx)
 		if tokenString == "" {
-			tokenString, _ = ctx.Cookie("accessToken")
+			v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+			ctx.Abort()
+			return
 		}
-		if tokenString == "" {
-			tokenString = ctx.Query("accessToken")
+
+		claims, err := j.ParseToken(tokenString)
+		if err != nil {
+			logger.WithContext(ctx).Error("token error", zap.Any("data", map[string]interface{}{
+				"url":    ctx.Request.URL,
+				"params": ctx.Params,
+			}), zap.Error(err))
+			v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+			ctx.Abort()
+			return
 		}
+
+		ctx.Set("claims", claims)
+		recoveryLoggerFunc(ctx, logger)
+		ctx.Next()
+	}
+}
+
+func NoStrictAuth(j *jwt.JWT, logger *log.Logger) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		tokenString := requestToken(ctx)
 		if tokenString == "" {
 			ctx.Next()
 			return
@@ -65,6 +87,18 @@ func NoStrictAuth(j *jwt.JWT, logger *log.Logger) gin.HandlerFunc {
 	}
 }
 
+// requestToken 依次从 Authorization 请求头、accessToken Cookie 和 accessToken 参数中读取 token
+func requestToken(ctx *gin.Context) string {
+	tokenString := ctx.Request.Header.Get("Authorization")
+	if tokenString == "" {
+		tokenString, _ = ctx.Cookie("accessToken")
+	}
+	if tokenString == "" {
+		tokenString = ctx.Query("accessToken")
+	}
+	return tokenString
+}
+
 func recoveryLoggerFunc(ctx *gin.Context, logger *log.Logger) {
 	if userInfo, ok := ctx.MustGet("claims").(*jwt.MyCustomClaims); ok {
 		logger.WithValue(ctx, zap.String("UserId", userInfo.UserId))
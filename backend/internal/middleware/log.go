@@ -8,6 +8,7 @@ import (
 	"go.uber.org/zap"
 	"io"
 	"shengcai/pkg/log"
+	"strings"
 	"time"
 )
 
@@ -49,6 +50,10 @@ type bodyLogWriter struct {
 }
 
 func (w bodyLogWriter) Write(b []byte) (int, error) {
-	w.body.Write(b)
+	// 图片、附件等二进制响应不记录内容
+	if contentType := w.Header().Get("Content-Type"); contentType == "" ||
+		strings.HasPrefix(contentType, "application/json") || strings.HasPrefix(contentType, "text/") {
+		w.body.Write(b)
+	}
 	return w.ResponseWriter.Write(b)
 }
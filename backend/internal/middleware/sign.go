@@ -1,17 +1,51 @@
 package middleware
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"github.com/duke-git/lancet/v2/cryptor"
 	"github.com/gin-gonic/gin"
 	"github.com/spf13/viper"
+	"io"
 	"net/http"
 	v1 "shengcai/api/v1"
 	"shengcai/pkg/log"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
+const (
+	// defaultSignKey 旧版配置文件中的默认密钥，视为未配置
+	defaultSignKey = "123456"
+	// defaultSignMaxSkew 时间戳与服务器时间允许的最大偏差，同时也是 Nonce 的保留时间
+	defaultSignMaxSkew = 5 * time.Minute
+	// maxSignBodySize 参与签名的请求体的最大长度
+	maxSignBodySize = 1 << 20
+)
+
+// SignMiddleware 校验 security.api_sign 签名，签名覆盖请求体的 SHA-256，
+// 时间戳超出 security.api_sign.max_skew 或 Nonce 重复的请求视为重放。
+// app_key、app_security 未配置或为默认值时，需要签名的接口一律返回 503
 func SignMiddleware(logger *log.Logger, conf *viper.Viper) gin.HandlerFunc {
+	appKey := conf.GetString("security.api_sign.app_key")
+	appSecurity := conf.GetString("security.api_sign.app_security")
+	if appKey == "" || appSecurity == "" || appKey == defaultSignKey || appSecurity == defaultSignKey {
+		logger.Warn("security.api_sign is not configured, signed APIs are disabled")
+		return func(ctx *gin.Context) {
+			v1.HandleError(ctx, http.StatusServiceUnavailable, v1.ErrServiceUnavailable, nil)
+			ctx.Abort()
+		}
+	}
+	maxSkew := conf.GetDuration("security.api_sign.max_skew")
+	if maxSkew <= 0 {
+		maxSkew = defaultSignMaxSkew
+	}
+	nonces := newNonceCache(maxSkew)
+
 	return func(ctx *gin.Context) {
 		requiredHeaders := []string{"Timestamp", "Nonce", "Sign", "App-Version"}
 
@@ -24,11 +58,29 @@ func SignMiddleware(logger *log.Logger, conf *viper.Viper) gin.HandlerFunc {
 			}
 		}
 
+		timestamp, err := strconv.ParseInt(ctx.Request.Header.Get("Timestamp"), 10, 64)
+		now := time.Now()
+		if err != nil || now.Sub(time.Unix(timestamp, 0)).Abs() > maxSkew {
+			v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+			ctx.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxSignBodySize))
+		if err != nil {
+			v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+			ctx.Abort()
+			return
+		}
+		ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+		bodyHash := sha256.Sum256(body)
+
 		data := map[string]string{
-			"AppKey":     conf.GetString("security.api_sign.app_key"),
+			"AppKey":     appKey,
 			"Timestamp":  ctx.Request.Header.Get("Timestamp"),
 			"Nonce":      ctx.Request.Header.Get("Nonce"),
 			"AppVersion": ctx.Request.Header.Get("App-Version"),
+			"BodyHash":   hex.EncodeToString(bodyHash[:]),
 		}
 
 		var keys []string
@@ -41,13 +93,48 @@ func SignMiddleware(logger *log.Logger, conf *viper.Viper) gin.HandlerFunc {
 		for _, k := range keys {
 			str += k + data[k]
 		}
-		str += conf.GetString("security.api_sign.app_security")
+		str += appSecurity
 
 		if ctx.Request.Header.Get("Sign") != strings.ToUpper(cryptor.Md5String(str)) {
 			v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
 			ctx.Abort()
 			return
 		}
+		// 签名通过后再记录 Nonce，避免伪造的请求占用
+		if !nonces.add(ctx.Request.Header.Get("Nonce"), now) {
+			logger.WithContext(ctx).Warn("SignMiddleware!!! nonce reused")
+			v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+			ctx.Abort()
+			return
+		}
 		ctx.Next()
 	}
 }
+
+// nonceCache 记录时间窗口内出现过的 Nonce，只在当前进程内去重
+type nonceCache struct {
+	mu   sync.Mutex
+	ttl  time.Duration
+	seen map[string]time.Time
+}
+
+func newNonceCache(ttl time.Duration) *nonceCache {
+	return &nonceCache{ttl: ttl, seen: make(map[string]time.Time)}
+}
+
+// add 记录 Nonce，时间窗口内已出现过时返回 false
+func (c *nonceCache) add(nonce string, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, expireAt := range c.seen {
+		if now.After(expireAt) {
+			delete(c.seen, key)
+		}
+	}
+	if _, ok := c.seen[nonce]; ok {
+		return false
+	}
+	// 时间戳在 ±ttl 内有效，Nonce 至少保留 2 倍 ttl 才能覆盖整个窗口
+	c.seen[nonce] = now.Add(2 * c.ttl)
+	return true
+}
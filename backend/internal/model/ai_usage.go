@@ -0,0 +1,38 @@
+package model
+
+import "time"
+
+// 调用模型的用途
+const (
+	// AIPurposeSummary 总结一篇完整的文章
+	AIPurposeSummary = "summary"
+	// AIPurposeChunk 总结长文章的一个分段
+	AIPurposeChunk = "chunk"
+	// AIPurposeMerge 合并长文章相邻分段的摘要
+	AIPurposeMerge = "merge"
+	// AIPurposeReduce 综合各分段的摘要得到全文的摘要
+	AIPurposeReduce = "reduce"
+)
+
+// AIUsage 一次调用模型消耗的 token，失败的调用同样记录
+type AIUsage struct {
+	ID               int       `gorm:"primaryKey;not null" json:"-"`
+	SheetID          string    `gorm:"column:sheet_id;type:varchar(100)" json:"sheet_id"`
+	CellDataID       int       `gorm:"column:cell_data_id;type:int" json:"cell_data_id"`
+	Purpose          string    `gorm:"column:purpose;type:varchar(20)" json:"purpose"`
+	Model            string    `gorm:"column:model;type:varchar(100)" json:"model"`
+	PromptTokens     int       `gorm:"column:prompt_tokens;type:int;default:0" json:"prompt_tokens"`
+	CompletionTokens int       `gorm:"column:completion_tokens;type:int;default:0" json:"completion_tokens"`
+	LatencyMs        int64     `gorm:"column:latency_ms;type:int;default:0" json:"latency_ms"`
+	Error            string    `gorm:"column:error;type:varchar(255)" json:"error"`
+	CreatedAt        time.Time `gorm:"column:create_at;type:timestamp;not null;autoCreateTime" json:"create_at"`
+}
+
+func (AIUsage) TableName() string {
+	return "ai_usage"
+}
+
+// TotalTokens 本次调用消耗的 token 总数
+func (u *AIUsage) TotalTokens() int {
+	return u.PromptTokens + u.CompletionTokens
+}
@@ -2,20 +2,52 @@ package model
 
 import "time"
 
+// AI 生成摘要的结果
+const (
+	// AIStateSkipped 未开启 AI 或文章没有内容
+	AIStateSkipped = "skipped"
+	// AIStateOK 模型按 JSON 格式返回了有效的结果
+	AIStateOK = "ok"
+	// AIStateFallback 模型返回的 JSON 无效，按旧的文本格式解析成功
+	AIStateFallback = "fallback"
+	// AIStateFailed 调用模型失败或无法解析模型的输出
+	AIStateFailed = "failed"
+	// AIStatePending 等待生成摘要，文章保存后或 token 预算恢复后生成
+	AIStatePending = "pending"
+)
+
 type CellData struct {
-	ID           int       `gorm:"primaryKey;not null" json:"-"`
-	SheetID      string    `gorm:"column:sheet_id;type:varchar(100)" json:"sheet_id"`
-	Title        string    `gorm:"column:title;type:varchar(255)" json:"title"`
-	Link         string    `gorm:"column:link;type:varchar(255)" json:"link"`
-	ReleaseDate  string    `gorm:"column:release_date;type:varchar(50)" json:"release_date"`
-	Content      string    `gorm:"column:content;type:text" json:"content"`
-	Abstract     string    `gorm:"column:abstract;type:varchar(1000)" json:"abstract"`
-	Keyword      string    `gorm:"column:keyword;type:varchar(1000)" json:"keyword"`
-	SortNumber   int       `gorm:"column:sort_number;type:int" json:"sort_number"`
-	CreatedAt    time.Time `gorm:"column:create_at;type:timestamp;not null;autoCreateTime" json:"-"`
-	UpdatedAt    time.Time `gorm:"column:update_at;type:timestamp;not null;autoUpdateTime" json:"-"`
-	Deleted      bool      `gorm:"column:deleted;type:tinyint(4);default:0" json:"-"`
-	RuntimeState string    `gorm:"column:runtime_state;type:varchar(255)" json:"runtime_state"`
+	ID              int               `gorm:"primaryKey;not null" json:"-"`
+	SheetID         string            `gorm:"column:sheet_id;type:varchar(100)" json:"sheet_id"`
+	TabID           string            `gorm:"column:tab_id;type:varchar(50)" json:"tab_id"`
+	TabTitle        string            `gorm:"column:tab_title;type:varchar(255)" json:"tab_title"`
+	TabIndex        int               `gorm:"column:tab_index;type:int" json:"tab_index"`
+	Title           string            `gorm:"column:title;type:varchar(255)" json:"title"`
+	Link            string            `gorm:"column:link;type:varchar(255)" json:"link"`
+	LinkType        string            `gorm:"column:link_type;type:varchar(20)" json:"link_type"`
+	RecordID        string            `gorm:"column:record_id;type:varchar(50)" json:"record_id"`
+	ReleaseDate     string            `gorm:"column:release_date;type:varchar(50)" json:"release_date"`
+	PublishedAt     *time.Time        `gorm:"column:published_at;type:timestamp" json:"published_at"`
+	Content         string            `gorm:"column:content;type:text" json:"content"`
+	ContentMarkdown string            `gorm:"column:content_markdown;type:mediumtext" json:"content_markdown"`
+	ContentHash     string            `gorm:"column:content_hash;type:char(64)" json:"-"`
+	Abstract        string            `gorm:"column:abstract;type:varchar(1000)" json:"abstract"`
+	Keyword         string            `gorm:"column:keyword;type:varchar(1000)" json:"keyword"`
+	AIExtra         map[string]string `gorm:"column:ai_extra;type:text;serializer:json" json:"ai_extra"`
+	AIState         string            `gorm:"column:ai_state;type:varchar(20)" json:"ai_state"`
+	AIError         string            `gorm:"column:ai_error;type:varchar(255)" json:"ai_error"`
+	WrittenAbstract string            `gorm:"column:written_abstract;type:varchar(1000)" json:"-"`
+	WrittenKeyword  string            `gorm:"column:written_keyword;type:varchar(1000)" json:"-"`
+	WrittenAt       *time.Time        `gorm:"column:written_at;type:timestamp" json:"-"`
+	SortNumber      int               `gorm:"column:sort_number;type:int" json:"sort_number"`
+	Extra           map[string]string `gorm:"column:extra;type:text;serializer:json" json:"extra"`
+	CreatedAt       time.Time         `gorm:"column:create_at;type:timestamp;not null;autoCreateTime" json:"-"`
+	UpdatedAt       time.Time         `gorm:"column:update_at;type:timestamp;not null;autoUpdateTime" json:"-"`
+	Deleted         bool              `gorm:"column:deleted;type:tinyint(4);default:0" json:"-"`
+	DeletedAt       *time.Time        `gorm:"column:deleted_at;type:timestamp" json:"-"`
+	RuntimeState    string            `gorm:"column:runtime_state;type:varchar(255)" json:"runtime_state"`
+	// CommentDigest 读者评论的摘录，只在生成摘要时使用，不保存
+	CommentDigest string `gorm:"-" json:"-"`
 }
 
 func (CellData) TableName() string {
@@ -0,0 +1,37 @@
+package model
+
+// ColumnMapping 描述电子表格各列与文章字段的对应关系。
+// 每一列既可以写列字母（如 "B"），也可以写第一行中的表头名称（如 "发布日期"），
+// 表头名称优先，"URL" 这类与列字母相同的表头按名称匹配。
+// 多维表格中填写字段名称。
+type ColumnMapping struct {
+	// Title 标题所在的列，未配置 Link 时单元格需要是带链接的文本
+	Title string `json:"title"`
+	// Link 链接单独所在的列，未配置时使用标题单元格中的链接
+	Link string `json:"link,omitempty"`
+	// Date 发布日期所在的列
+	Date string `json:"date"`
+	// Extra 需要额外保存的列，key 为保存到 cell_data.extra 中的字段名
+	Extra map[string]string `json:"extra,omitempty"`
+	// Abstract、Keyword 回写 AI 摘要和关键字的列，配置后开启回写，仅支持电子表格
+	Abstract string `json:"abstract,omitempty"`
+	Keyword  string `json:"keyword,omitempty"`
+}
+
+// DefaultColumnMapping 生财表格的默认布局：B 列为标题及链接，D 列为发布日期
+func DefaultColumnMapping() ColumnMapping {
+	return ColumnMapping{
+		Title: "B",
+		Date:  "D",
+	}
+}
+
+// IsZero 判断是否未配置任何列
+func (m ColumnMapping) IsZero() bool {
+	return m.Title == "" && m.Link == "" && m.Date == "" && len(m.Extra) == 0 && !m.WriteBack()
+}
+
+// WriteBack 判断是否开启了摘要和关键字的回写
+func (m ColumnMapping) WriteBack() bool {
+	return m.Abstract != "" || m.Keyword != ""
+}
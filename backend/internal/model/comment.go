@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// Comment 文档评论中的一条回复，每条评论的第一条回复为评论本身
+type Comment struct {
+	ID         int        `gorm:"primaryKey;not null" json:"-"`
+	CellDataID int        `gorm:"column:cell_data_id;type:int" json:"-"`
+	CommentID  string     `gorm:"column:comment_id;type:varchar(50)" json:"comment_id"`
+	ReplyID    string     `gorm:"column:reply_id;type:varchar(50)" json:"reply_id"`
+	UserID     string     `gorm:"column:user_id;type:varchar(100)" json:"user_id"`
+	Content    string     `gorm:"column:content;type:text" json:"content"`
+	Quote      string     `gorm:"column:quote;type:varchar(1000)" json:"quote"`
+	Solved     bool       `gorm:"column:solved;type:tinyint(4);default:0" json:"solved"`
+	ReplyTime  *time.Time `gorm:"column:reply_time;type:timestamp" json:"reply_time"`
+	CreatedAt  time.Time  `gorm:"column:create_at;type:timestamp;not null;autoCreateTime" json:"-"`
+}
+
+func (Comment) TableName() string {
+	return "comment"
+}
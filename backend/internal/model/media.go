@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// Media 从飞书下载的图片或附件，内容相同的文件共用同一个存储对象
+type Media struct {
+	ID int `gorm:"primaryKey;not null" json:"id"`
+	// Token 飞书素材的 file_token
+	Token       string `gorm:"column:token;type:varchar(100);uniqueIndex" json:"token"`
+	Hash        string `gorm:"column:hash;type:char(64);index" json:"hash"`
+	Size        int64  `gorm:"column:size;type:bigint" json:"size"`
+	ContentType string `gorm:"column:content_type;type:varchar(100)" json:"content_type"`
+	FileName    string `gorm:"column:file_name;type:varchar(255)" json:"file_name"`
+	// StorageKey 在存储中的 key，即内容的 SHA256
+	StorageKey string    `gorm:"column:storage_key;type:varchar(255)" json:"-"`
+	CreatedAt  time.Time `gorm:"column:create_at;type:timestamp;not null;autoCreateTime" json:"-"`
+}
+
+func (Media) TableName() string {
+	return "media"
+}
@@ -1,18 +1,147 @@
 package model
 
-import "time"
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	SheetStatusActive = "active"
+	SheetStatusPaused = "paused"
+)
+
+// 数据源类型，sheet_id 分别为电子表格的 spreadsheet_token 和多维表格的 app_token
+const (
+	SourceTypeSheet   = "sheet"
+	SourceTypeBitable = "bitable"
+)
 
 type SheetInfo struct {
-	ID           int       `gorm:"primaryKey;not null" json:"-"`
-	SheetID      string    `gorm:"column:sheet_id;type:varchar(255)" json:"sheet_id"`
-	SheetName    string    `gorm:"column:sheet_name;type:varchar(255)" json:"sheet_name"`
-	UpdateLog    string    `gorm:"column:update_log;type:varchar(255)" json:"update_log"`
-	CreatedAt    time.Time `gorm:"column:create_at;type:timestamp;not null;autoCreateTime" json:"-"`
-	UpdatedAt    time.Time `gorm:"column:update_at;type:timestamp;not null;autoUpdateTime" json:"-"`
-	Deleted      bool      `gorm:"column:deleted;type:tinyint(4);default:0" json:"-"`
-	RuntimeState string    `gorm:"column:runtime_state;type:varchar(255)" json:"runtime_state"`
+	ID               int           `gorm:"primaryKey;not null" json:"-"`
+	SheetID          string        `gorm:"column:sheet_id;type:varchar(255)" json:"sheet_id"`
+	SheetName        string        `gorm:"column:sheet_name;type:varchar(255)" json:"sheet_name"`
+	SourceType       string        `gorm:"column:source_type;type:varchar(20);default:sheet" json:"source_type"`
+	TableID          string        `gorm:"column:table_id;type:varchar(50)" json:"table_id"`
+	OriginSheetID    string        `gorm:"column:origin_sheet_id;type:varchar(255)" json:"origin_sheet_id"`
+	OriginSheetName  string        `gorm:"column:origin_sheet_name;type:varchar(255)" json:"origin_sheet_name"`
+	MirrorRowCount   int           `gorm:"column:mirror_row_count;type:int;default:0" json:"mirror_row_count"`
+	MirrorChecksum   string        `gorm:"column:mirror_checksum;type:varchar(64)" json:"mirror_checksum"`
+	MirrorReportedAt *time.Time    `gorm:"column:mirror_reported_at;type:timestamp" json:"mirror_reported_at"`
+	UpdateLog        string        `gorm:"column:update_log;type:varchar(255)" json:"update_log"`
+	Status           string        `gorm:"column:status;type:varchar(20);default:active" json:"status"`
+	SyncInterval     int           `gorm:"column:sync_interval;type:int;default:300" json:"sync_interval"`
+	NotifyMuted      bool          `gorm:"column:notify_muted;type:tinyint(4);default:0" json:"notify_muted"`
+	LastSyncAt       *time.Time    `gorm:"column:last_sync_at;type:timestamp" json:"last_sync_at"`
+	ColumnMapping    ColumnMapping `gorm:"column:column_mapping;type:text;serializer:json" json:"column_mapping"`
+	LatestModifyTime string        `gorm:"column:latest_modify_time;type:varchar(20)" json:"latest_modify_time"`
+	Revision         int           `gorm:"column:revision;type:int;default:0" json:"revision"`
+	LastSyncResult   *SyncResult   `gorm:"column:last_sync_result;type:text" json:"last_sync_result"`
+	CreatedAt        time.Time     `gorm:"column:create_at;type:timestamp;not null;autoCreateTime" json:"-"`
+	UpdatedAt        time.Time     `gorm:"column:update_at;type:timestamp;not null;autoUpdateTime" json:"-"`
+	Deleted          bool          `gorm:"column:deleted;type:tinyint(4);default:0" json:"-"`
+	RuntimeState     string        `gorm:"column:runtime_state;type:varchar(255)" json:"runtime_state"`
+}
+
+// SyncResult 一次同步的结果
+type SyncResult struct {
+	// Skipped 表格没有变化，跳过了本次同步
+	Skipped bool `json:"skipped"`
+	// Rows 本次同步读取到的文章行数
+	Rows int `json:"rows"`
+	// Deleted 本次同步中从表格里消失、被标记为删除的链接
+	Deleted []string `json:"deleted"`
+	// Created 本次同步中新增文章的链接，只用于发送通知，不保存到 last_sync_result
+	Created []string `json:"-"`
+	// Written 本次回写到表格中的单元格数
+	Written int `json:"written,omitempty"`
+}
+
+// Value 以 JSON 格式保存同步结果，使其可以直接用于 Updates
+func (r SyncResult) Value() (driver.Value, error) {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (r *SyncResult) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(v, r)
+	case string:
+		return json.Unmarshal([]byte(v), r)
+	default:
+		return fmt.Errorf("unsupported type for SyncResult: %T", value)
+	}
 }
 
 func (SheetInfo) TableName() string {
 	return "sheet_info"
 }
+
+// IsDue 判断表格是否到了下一次同步的时间
+func (s *SheetInfo) IsDue(now time.Time) bool {
+	if s.Status != SheetStatusActive {
+		return false
+	}
+	if s.LastSyncAt == nil {
+		return true
+	}
+	return now.Sub(*s.LastSyncAt) >= time.Duration(s.SyncInterval)*time.Second
+}
+
+// IsBitable 判断数据源是否为多维表格
+func (s *SheetInfo) IsBitable() bool {
+	return s.SourceType == SourceTypeBitable
+}
+
+// IsMirror 判断表格是否为 RPA 拷贝的镜像表格。镜像表格的 SheetID 为 RPA 拷贝到的个人表格，
+// OriginSheetID、OriginSheetName 为原始的加密表格
+func (s *SheetInfo) IsMirror() bool {
+	return s.OriginSheetID != ""
+}
+
+// PublicSheetID 对外展示的表格 token，镜像表格使用原始表格的 token
+func (s *SheetInfo) PublicSheetID() string {
+	if s.IsMirror() {
+		return s.OriginSheetID
+	}
+	return s.SheetID
+}
+
+// DisplayName 对外展示的表格名称，依次使用原始表格名称、表格名称和 token
+func (s *SheetInfo) DisplayName() string {
+	switch {
+	case s.IsMirror() && s.OriginSheetName != "":
+		return s.OriginSheetName
+	case s.SheetName != "":
+		return s.SheetName
+	}
+	return s.PublicSheetID()
+}
+
+// MirrorStale 判断镜像表格是否已过期：从未收到或超过 staleAfter 没有收到 RPA 的拷贝上报，
+// 或者上报之后的同步读取到的文章行数与上报的不一致
+func (s *SheetInfo) MirrorStale(now time.Time, staleAfter time.Duration) bool {
+	if !s.IsMirror() {
+		return false
+	}
+	if s.MirrorReportedAt == nil || now.Sub(*s.MirrorReportedAt) > staleAfter {
+		return true
+	}
+	synced := s.LastSyncAt != nil && !s.LastSyncAt.Before(*s.MirrorReportedAt)
+	return synced && s.LastSyncResult != nil && !s.LastSyncResult.Skipped && s.LastSyncResult.Rows != s.MirrorRowCount
+}
+
+// GetColumnMapping 返回表格的列映射，电子表格未配置时使用默认布局
+func (s *SheetInfo) GetColumnMapping() ColumnMapping {
+	if s.ColumnMapping.IsZero() && !s.IsBitable() {
+		return DefaultColumnMapping()
+	}
+	return s.ColumnMapping
+}
@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// SummaryCache 按正文哈希、提示词版本和模型缓存的摘要，不同表格中相同的文章只总结一次
+type SummaryCache struct {
+	ID            int               `gorm:"primaryKey;not null" json:"-"`
+	ContentHash   string            `gorm:"column:content_hash;type:char(64);uniqueIndex:uniq_content_hash_prompt_version_model" json:"content_hash"`
+	PromptVersion string            `gorm:"column:prompt_version;type:varchar(20);uniqueIndex:uniq_content_hash_prompt_version_model" json:"prompt_version"`
+	Model         string            `gorm:"column:model;type:varchar(100);uniqueIndex:uniq_content_hash_prompt_version_model" json:"model"`
+	Abstract      string            `gorm:"column:abstract;type:varchar(1000)" json:"abstract"`
+	Keyword       string            `gorm:"column:keyword;type:varchar(1000)" json:"keyword"`
+	AIExtra       map[string]string `gorm:"column:ai_extra;type:text;serializer:json" json:"ai_extra"`
+	AIState       string            `gorm:"column:ai_state;type:varchar(20)" json:"ai_state"`
+	CreatedAt     time.Time         `gorm:"column:create_at;type:timestamp;not null;autoCreateTime" json:"-"`
+}
+
+func (SummaryCache) TableName() string {
+	return "summary_cache"
+}
@@ -1,46 +1,109 @@
 package repository
 
 import (
-	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
-	"net/http"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"os"
 	"regexp"
+	"shengcai/internal/model"
+	"shengcai/pkg/llm"
 	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrSummaryUnparsable 模型的输出既不是有效的 JSON，也不符合旧的文本格式
+var ErrSummaryUnparsable = errors.New("unparsable summary")
+
+// summaryPromptVersion 提示词的版本，修改提示词或解析规则后递增，使缓存的摘要失效
+const summaryPromptVersion = "2"
+
+// summaryFormat 要求模型按 JSON 返回摘要和关键字，extra 为可选的补充信息
+const summaryFormat = `请只返回一个 JSON 对象，不要包含其他内容，格式如下：
+{"abstract": "摘要", "keywords": ["关键字1", "关键字2", "关键字3"], "extra": {"类型": "经验分享"}}
+其中 extra 可以省略，用于补充文章类型、适合的读者等信息，值均为字符串。`
+
+// summaryPrompt 总结一篇完整的文章
+const summaryPrompt = "你是一位优秀的文章总结高手。请根据我提供的文字，总结出一个简洁的摘要，要求在100字以内，并提取出至少3个关键字。\n" + summaryFormat
+
+// chunkPrompt 总结长文章的一部分，需要填入当前是第几部分和总的部分数
+const chunkPrompt = "你是一位优秀的文章总结高手。我提供的文字是一篇长文章的第 %d 部分（共 %d 部分），请总结这一部分的要点，要求在200字以内，并提取出至少3个关键字。\n" + summaryFormat
+
+// mergePrompt 各部分的摘要仍然过长时，先将相邻的几部分合并为一段摘要
+const mergePrompt = "你是一位优秀的文章总结高手。我提供的是一篇长文章中连续几个部分的摘要和关键字，每行一个部分，请将它们合并为一段摘要，要求在200字以内，并提取出至少3个关键字。\n" + summaryFormat
+
+// reducePrompt 综合各部分的摘要得到整篇文章的摘要和关键字
+const reducePrompt = "你是一位优秀的文章总结高手。我提供的是一篇长文章按顺序排列的各部分的摘要和关键字，每行一个部分，请综合这些内容，总结出整篇文章的简洁摘要，要求在100字以内，并选出最能代表全文的3到8个关键字。\n" + summaryFormat
+
+// 长文章分段总结的默认值，可以通过 ai.chunk_tokens、ai.max_chunks 调整
+const (
+	defaultChunkTokens = 6000
+	defaultMaxChunks   = 20
+	// maxMergeLevels 各部分的摘要逐层合并的最大层数，避免分段过小时反复合并
+	maxMergeLevels = 3
+)
+
+// 摘要结果的校验规则，与 cell_data 的字段长度保持一致
+const (
+	maxAbstractLength = 1000
+	maxKeywordLength  = 50
+	maxKeywords       = 20
 )
 
 type AIRepository interface {
-	GenerateAbstractAndKeyword(ctx context.Context, content string) (string, string, error)
+	Summarize(ctx context.Context, article SummaryArticle) (*Summary, error)
+}
+
+// SummaryArticle 需要生成摘要的文章，SheetID 和 CellDataID 用于记录 token 用量
+type SummaryArticle struct {
+	SheetID    string
+	CellDataID int
+	Content    string
+}
+
+// Summary AI 生成的摘要和关键字
+type Summary struct {
+	Abstract string
+	Keywords []string
+	Extra    map[string]string
+	// State 结果的来源，见 model.AIState*
+	State string
+	// Warning 不影响结果的问题，如 JSON 无效时按旧的文本格式解析、长文章的分段被跳过
+	Warning string
+	// Cached 摘要来自缓存，没有调用模型
+	Cached bool
+}
+
+// Keyword 以英文逗号连接的关键字，与 cell_data.keyword 的格式一致
+func (s *Summary) Keyword() string {
+	return strings.Join(s.Keywords, ",")
 }
 
 func NewAIRepository(
 	r *Repository,
+	provider llm.Provider,
+	usageRepository AIUsageRepository,
 ) AIRepository {
 	return &aiRepository{
-		Repository: r,
+		Repository:      r,
+		provider:        provider,
+		usageRepository: usageRepository,
 	}
 }
 
 type aiRepository struct {
 	*Repository
-}
-
-// Define the structure of the response based on the provided JSON format
-type Message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
-}
-
-type Choice struct {
-	Index   int     `json:"index"`
-	Message Message `json:"message"`
-}
-
-type Response struct {
-	Choices []Choice `json:"choices"`
+	// provider 生成摘要的大模型，由 llm 配置决定
+	provider llm.Provider
+	// usageRepository 记录每次调用消耗的 token，并据此检查预算
+	usageRepository AIUsageRepository
 }
 
 // Function to extract abstract and keywords
@@ -79,79 +142,352 @@ func extractAbstractAndKeywords(content string) (string, string) {
 	return abstract, formattedKeywords
 }
 
-func (r *aiRepository) GenerateAbstractAndKeyword(ctx context.Context, content string) (string, string, error) {
+// Summarize 生成文章的摘要和关键字，超过 ai.chunk_tokens 的文章分段总结后合并。
+// 未开启 AI 或没有内容时返回 AIStateSkipped，当天或当月的 token 已达到预算时返回 ErrBudgetExceeded，
+// 调用模型失败或无法解析输出时返回错误，由调用方记录。预算只在开始总结一篇文章前检查，长文章可能略微超出预算
+// 相同正文、提示词版本和模型的摘要直接使用缓存，不调用模型，也不受预算限制
+func (r *aiRepository) Summarize(ctx context.Context, article SummaryArticle) (*Summary, error) {
 	aiGenerate := os.Getenv("ai_generate")
 	if aiGenerate == "" {
 		aiGenerate = r.conf.GetString("ai.generate")
 	}
 	if aiGenerate == "close" {
-		return "", "", nil
+		return &Summary{State: model.AIStateSkipped}, nil
 	}
 
 	// Check if content is empty or starts with "Error processing link"
-	if strings.TrimSpace(content) == "" || strings.HasPrefix(content, "Error processing link") {
-		return "", "", nil
+	content := article.Content
+	if strings.TrimSpace(content) == "" || isFetchError(content) {
+		return &Summary{State: model.AIStateSkipped}, nil
 	}
 
-	// Define the request payload
-	payload := map[string]interface{}{
-		"model": "deepseek-chat",
-		"messages": []map[string]string{
-			{"role": "system", "content": "你是一位优秀的文章总结高手。请根据我提供的文字，总结出一个简洁的摘要，要求在100字以内，并提取出至少3个关键字。请将结果以以下格式返回：\n\n摘要：xxx\n关键字：xxx,xxx,xxx"},
-			{"role": "user", "content": content},
-		},
-		"stream": false,
+	contentHash := ContentHash(content)
+	if summary, err := r.cachedSummary(ctx, contentHash); err != nil || summary != nil {
+		return summary, err
 	}
 
-	// Convert payload to JSON
-	jsonData, err := json.Marshal(payload)
+	budget, err := r.usageRepository.Budget(ctx, time.Now())
 	if err != nil {
-		return "", "", fmt.Errorf("error marshalling JSON: %v", err)
+		return nil, err
+	}
+	if budget.Exceeded() {
+		return nil, ErrBudgetExceeded
 	}
 
-	// Create a new request
-	req, err := http.NewRequest("POST", "https://api.deepseek.com/chat/completions", bytes.NewBuffer(jsonData))
+	var summary *Summary
+	if chunks := llm.SplitText(content, r.chunkTokens()); len(chunks) == 1 {
+		summary, err = r.chat(ctx, article, model.AIPurposeSummary, summaryPrompt, content)
+	} else {
+		summary, err = r.summarizeChunks(ctx, article, chunks)
+	}
 	if err != nil {
-		return "", "", fmt.Errorf("error creating request: %v", err)
+		return nil, err
 	}
+	r.cacheSummary(ctx, contentHash, summary)
+	return summary, nil
+}
+
+// ContentHash 计算正文归一化后的 SHA-256：去掉零宽字符，连续的空白合并为一个空格。
+// 只有排版不同的正文得到相同的哈希，不会重新生成摘要
+func ContentHash(content string) string {
+	content = strings.Map(func(r rune) rune {
+		switch r {
+		case '\u200b', '\u200c', '\u200d', '\ufeff':
+			return -1
+		}
+		return r
+	}, content)
+	sum := sha256.Sum256([]byte(strings.Join(strings.Fields(content), " ")))
+	return hex.EncodeToString(sum[:])
+}
 
-	apiKey := os.Getenv("api_key")
-	if apiKey == "" {
-		apiKey = r.conf.GetString("open.api_key")
+// cacheModel 缓存的摘要所属的模型，未配置 llm.model 时使用服务的默认模型，按 llm.provider 区分
+func (r *aiRepository) cacheModel() string {
+	if name := r.conf.GetString("llm.model"); name != "" {
+		return name
 	}
+	if provider := r.conf.GetString("llm.provider"); provider != "" {
+		return provider
+	}
+	return "openai"
+}
 
-	// Set the headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+apiKey)
+// cachedSummary 查找相同正文、提示词版本和模型的摘要，没有缓存时返回 nil
+func (r *aiRepository) cachedSummary(ctx context.Context, contentHash string) (*Summary, error) {
+	var cache model.SummaryCache
+	err := r.DB(ctx).
+		Where("content_hash = ? AND prompt_version = ? AND model = ?", contentHash, summaryPromptVersion, r.cacheModel()).
+		First(&cache).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Summary{
+		Abstract: cache.Abstract,
+		Keywords: splitKeywords(cache.Keyword),
+		Extra:    cache.AIExtra,
+		State:    cache.AIState,
+		Cached:   true,
+	}, nil
+}
 
-	// Create an HTTP client and send the request
-	client := &http.Client{}
-	resp, err := client.Do(req)
+// cacheSummary 缓存生成的摘要，并发生成相同正文的摘要时保留先写入的一条，写入失败只记录日志
+func (r *aiRepository) cacheSummary(ctx context.Context, contentHash string, summary *Summary) {
+	cache := &model.SummaryCache{
+		ContentHash:   contentHash,
+		PromptVersion: summaryPromptVersion,
+		Model:         r.cacheModel(),
+		Abstract:      summary.Abstract,
+		Keyword:       summary.Keyword(),
+		AIExtra:       summary.Extra,
+		AIState:       summary.State,
+	}
+	if err := r.DB(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(cache).Error; err != nil {
+		r.logger.WithContext(ctx).Error("cache summary failed", zap.String("content_hash", contentHash), zap.Error(err))
+	}
+}
+
+// chunkTokens 单次请求中正文的最大 token 数，超过时分段总结，需要为提示词和输出留出余量
+func (r *aiRepository) chunkTokens() int {
+	if tokens := r.conf.GetInt("ai.chunk_tokens"); tokens > 0 {
+		return tokens
+	}
+	return defaultChunkTokens
+}
+
+// maxChunks 一篇文章最多总结的分段数，超出的部分不参与总结，限制单篇文章的调用次数
+func (r *aiRepository) maxChunks() int {
+	if chunks := r.conf.GetInt("ai.max_chunks"); chunks > 0 {
+		return chunks
+	}
+	return defaultMaxChunks
+}
+
+// summarizeChunks 分段总结长文章：先总结每个分段，各部分的摘要仍然过长时逐层合并，
+// 最后综合为整篇文章的摘要和关键字。单个分段无法解析时跳过并记录在 Warning 中
+func (r *aiRepository) summarizeChunks(ctx context.Context, article SummaryArticle, chunks []string) (*Summary, error) {
+	var warnings []string
+	if maxChunks := r.maxChunks(); len(chunks) > maxChunks {
+		warnings = append(warnings, fmt.Sprintf("only the first %d of %d chunks are summarized", maxChunks, len(chunks)))
+		chunks = chunks[:maxChunks]
+	}
+
+	partials, err := r.summarizeParts(ctx, article, model.AIPurposeChunk, chunks, func(i int) string {
+		return fmt.Sprintf(chunkPrompt, i+1, len(chunks))
+	}, &warnings)
 	if err != nil {
-		return "", "", fmt.Errorf("error sending request: %v", err)
+		return nil, err
+	}
+
+	combined := strings.Join(partials, "\n")
+	for level := 0; level < maxMergeLevels && llm.EstimateTokens(combined) > r.chunkTokens(); level++ {
+		groups := llm.SplitText(combined, r.chunkTokens())
+		if partials, err = r.summarizeParts(ctx, article, model.AIPurposeMerge, groups, func(int) string { return mergePrompt }, &warnings); err != nil {
+			return nil, err
+		}
+		combined = strings.Join(partials, "\n")
 	}
-	defer resp.Body.Close()
 
-	// Read the response body
-	body, err := ioutil.ReadAll(resp.Body)
+	summary, err := r.chat(ctx, article, model.AIPurposeReduce, reducePrompt, combined)
 	if err != nil {
-		return "", "", fmt.Errorf("error reading response body: %v", err)
+		return nil, err
 	}
+	if summary.Warning != "" {
+		warnings = append(warnings, summary.Warning)
+	}
+	summary.Warning = strings.Join(warnings, "; ")
+	return summary, nil
+}
 
-	// Parse the response JSON
-	var apiResponse Response
-	err = json.Unmarshal(body, &apiResponse)
+// summarizeParts 依次总结每一部分，返回每部分一行的摘要和关键字。调用模型失败时直接返回，
+// 无法解析的部分跳过，全部无法解析时返回 ErrSummaryUnparsable
+func (r *aiRepository) summarizeParts(ctx context.Context, article SummaryArticle, purpose string, parts []string, prompt func(i int) string, warnings *[]string) ([]string, error) {
+	lines := make([]string, 0, len(parts))
+	var failures []string
+	for i, part := range parts {
+		summary, err := r.chat(ctx, article, purpose, prompt(i), part)
+		if errors.Is(err, ErrSummaryUnparsable) {
+			failures = append(failures, fmt.Sprintf("part %d: %v", i+1, err))
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, fmt.Sprintf("第%d部分：%s（关键字：%s）", len(lines)+1,
+			strings.ReplaceAll(summary.Abstract, "\n", " "), summary.Keyword()))
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrSummaryUnparsable, strings.Join(failures, "; "))
+	}
+	*warnings = append(*warnings, failures...)
+	return lines, nil
+}
+
+// chat 调用模型并解析输出，每次调用都记录消耗的 token 和耗时
+func (r *aiRepository) chat(ctx context.Context, article SummaryArticle, purpose string, prompt string, content string) (*Summary, error) {
+	start := time.Now()
+	response, err := r.provider.Chat(ctx, &llm.ChatRequest{
+		Messages: []llm.Message{
+			{Role: llm.RoleSystem, Content: prompt},
+			{Role: llm.RoleUser, Content: content},
+		},
+		// 部分兼容 OpenAI 的服务不支持 response_format，可以关闭，仍然通过提示词要求 JSON
+		JSON: !r.conf.IsSet("llm.json_mode") || r.conf.GetBool("llm.json_mode"),
+	})
+	r.recordUsage(ctx, article, purpose, time.Since(start), response, err)
 	if err != nil {
-		return "", "", fmt.Errorf("error unmarshalling JSON: %v", err)
+		return nil, err
 	}
+	return parseSummary(response.Content)
+}
 
-	// Extract the content where role is "assistant"
-	for _, choice := range apiResponse.Choices {
-		if choice.Message.Role == "assistant" {
-			abstract, keyword := extractAbstractAndKeywords(choice.Message.Content)
-			return abstract, keyword, nil
+// recordUsage 保存一次调用的用量，保存失败只记录日志，不影响摘要的生成
+func (r *aiRepository) recordUsage(ctx context.Context, article SummaryArticle, purpose string, latency time.Duration, response *llm.ChatResponse, chatErr error) {
+	usage := &model.AIUsage{
+		SheetID:    article.SheetID,
+		CellDataID: article.CellDataID,
+		Purpose:    purpose,
+		Model:      r.conf.GetString("llm.model"),
+		LatencyMs:  latency.Milliseconds(),
+	}
+	if response != nil {
+		usage.PromptTokens = response.PromptTokens
+		usage.CompletionTokens = response.CompletionTokens
+		if response.Model != "" {
+			usage.Model = response.Model
 		}
 	}
+	if chatErr != nil {
+		usage.Error = truncateError(chatErr.Error())
+	}
+	if err := r.usageRepository.Create(ctx, usage); err != nil {
+		r.logger.WithContext(ctx).Error("record ai usage failed", zap.String("sheet_id", article.SheetID), zap.Error(err))
+	}
+}
+
+// parseSummary 优先按 JSON 解析模型的输出，失败时按旧的“摘要：…关键字：…”格式解析
+func parseSummary(output string) (*Summary, error) {
+	summary, jsonErr := parseJSONSummary(output)
+	if jsonErr == nil {
+		summary.State = model.AIStateOK
+		return summary, nil
+	}
+
+	summary, textErr := parseTextSummary(output)
+	if textErr == nil {
+		summary.State = model.AIStateFallback
+		summary.Warning = jsonErr.Error()
+		return summary, nil
+	}
+	return nil, fmt.Errorf("%w: %v; %v", ErrSummaryUnparsable, jsonErr, textErr)
+}
 
-	return "", "", fmt.Errorf("no assistant role found in the response")
+// parseJSONSummary 解析 JSON 格式的输出，兼容模型在 JSON 外包裹的 Markdown 代码块或说明文字
+func parseJSONSummary(output string) (*Summary, error) {
+	start, end := strings.Index(output, "{"), strings.LastIndex(output, "}")
+	if start < 0 || end < start {
+		return nil, errors.New("no JSON object found")
+	}
+
+	var result struct {
+		Abstract string                 `json:"abstract"`
+		Keywords json.RawMessage        `json:"keywords"`
+		Extra    map[string]interface{} `json:"extra"`
+	}
+	if err := json.Unmarshal([]byte(output[start:end+1]), &result); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	// keywords 应为数组，也接受以逗号分隔的字符串
+	var keywords []string
+	if err := json.Unmarshal(result.Keywords, &keywords); err != nil {
+		var keyword string
+		if err = json.Unmarshal(result.Keywords, &keyword); err != nil {
+			return nil, errors.New("keywords is not an array of strings")
+		}
+		keywords = splitKeywords(keyword)
+	}
+
+	summary := &Summary{Abstract: result.Abstract, Keywords: keywords}
+	if len(result.Extra) > 0 {
+		summary.Extra = make(map[string]string, len(result.Extra))
+		for key, value := range result.Extra {
+			summary.Extra[key] = extraText(value)
+		}
+	}
+	if err := summary.validate(); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// textSummaryReplacer 去掉模型常加的 Markdown 标记，统一冒号，使旧的正则可以匹配
+var textSummaryReplacer = strings.NewReplacer("**", "", "__", "", "#", "", "摘要:", "摘要：", "关键字:", "关键字：", "关键词：", "关键字：", "关键词:", "关键字：")
+
+// parseTextSummary 按旧的文本格式解析
+func parseTextSummary(output string) (*Summary, error) {
+	abstract, keyword := extractAbstractAndKeywords(textSummaryReplacer.Replace(output) + "\n")
+	summary := &Summary{Abstract: abstract, Keywords: splitKeywords(strings.SplitN(keyword, "\n", 2)[0])}
+	if err := summary.validate(); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// validate 检查摘要不为空，清理关键字中的空白、重复和过长的项，超出字段长度时截断
+func (s *Summary) validate() error {
+	s.Abstract = strings.TrimSpace(s.Abstract)
+	if s.Abstract == "" {
+		return errors.New("abstract is empty")
+	}
+	if utf8.RuneCountInString(s.Abstract) > maxAbstractLength {
+		s.Abstract = string([]rune(s.Abstract)[:maxAbstractLength])
+	}
+
+	keywords := make([]string, 0, len(s.Keywords))
+	seen := make(map[string]bool)
+	for _, keyword := range s.Keywords {
+		// 关键字以逗号连接保存，关键字本身不能包含逗号
+		keyword = strings.TrimSpace(strings.NewReplacer(",", "", "，", "").Replace(keyword))
+		if keyword == "" || seen[keyword] || utf8.RuneCountInString(keyword) > maxKeywordLength {
+			continue
+		}
+		seen[keyword] = true
+		keywords = append(keywords, keyword)
+		if len(keywords) == maxKeywords {
+			break
+		}
+	}
+	if len(keywords) == 0 {
+		return errors.New("keywords is empty")
+	}
+	s.Keywords = keywords
+	return nil
+}
+
+func splitKeywords(keyword string) []string {
+	return strings.FieldsFunc(keyword, func(r rune) bool {
+		return r == ',' || r == '，' || r == '、'
+	})
+}
+
+// extraText 将补充信息的值转换为字符串，数组以逗号连接
+func extraText(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			items = append(items, extraText(item))
+		}
+		return strings.Join(items, ",")
+	case nil:
+		return ""
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
 }
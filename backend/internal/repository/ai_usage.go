@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"shengcai/internal/model"
+	"time"
+)
+
+// ErrBudgetExceeded 当天或当月消耗的 token 已达到预算，暂停生成摘要
+var ErrBudgetExceeded = errors.New("ai token budget exceeded")
+
+// AIUsageFilter token 用量的查询条件，时间范围左闭右开
+type AIUsageFilter struct {
+	SheetID string
+	From    time.Time
+	To      time.Time
+}
+
+// TokenBudget 每日和每月的 token 预算及已消耗的 token，预算为 0 表示不限制
+type TokenBudget struct {
+	Daily       int
+	DailyUsed   int
+	Monthly     int
+	MonthlyUsed int
+}
+
+// Exceeded 判断是否已达到任一预算
+func (b *TokenBudget) Exceeded() bool {
+	return (b.Daily > 0 && b.DailyUsed >= b.Daily) || (b.Monthly > 0 && b.MonthlyUsed >= b.Monthly)
+}
+
+type AIUsageRepository interface {
+	Create(ctx context.Context, usage *model.AIUsage) error
+	List(ctx context.Context, filter AIUsageFilter) ([]*model.AIUsage, error)
+	Budget(ctx context.Context, now time.Time) (*TokenBudget, error)
+}
+
+func NewAIUsageRepository(
+	r *Repository,
+) AIUsageRepository {
+	return &aiUsageRepository{
+		Repository: r,
+	}
+}
+
+type aiUsageRepository struct {
+	*Repository
+}
+
+func (r *aiUsageRepository) Create(ctx context.Context, usage *model.AIUsage) error {
+	return r.DB(ctx).Create(usage).Error
+}
+
+// List 按时间顺序返回范围内的调用记录
+func (r *aiUsageRepository) List(ctx context.Context, filter AIUsageFilter) ([]*model.AIUsage, error) {
+	query := r.DB(ctx).Where("create_at >= ? AND create_at < ?", filter.From, filter.To)
+	if filter.SheetID != "" {
+		query = query.Where("sheet_id = ?", filter.SheetID)
+	}
+	var list []*model.AIUsage
+	if err := query.Order("create_at").Order("id").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Budget 返回 ai.daily_token_budget、ai.monthly_token_budget 及当天、当月已消耗的 token，
+// 日期按 feishu.timezone 划分
+func (r *aiUsageRepository) Budget(ctx context.Context, now time.Time) (*TokenBudget, error) {
+	budget := &TokenBudget{
+		Daily:   r.conf.GetInt("ai.daily_token_budget"),
+		Monthly: r.conf.GetInt("ai.monthly_token_budget"),
+	}
+	now = now.In(r.timezone())
+	if budget.Daily > 0 {
+		day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		used, err := r.sumTokens(ctx, day)
+		if err != nil {
+			return nil, err
+		}
+		budget.DailyUsed = used
+	}
+	if budget.Monthly > 0 {
+		month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		used, err := r.sumTokens(ctx, month)
+		if err != nil {
+			return nil, err
+		}
+		budget.MonthlyUsed = used
+	}
+	return budget, nil
+}
+
+func (r *aiUsageRepository) sumTokens(ctx context.Context, since time.Time) (int, error) {
+	var total int
+	err := r.DB(ctx).Model(&model.AIUsage{}).
+		Select("COALESCE(SUM(prompt_tokens + completion_tokens), 0)").
+		Where("create_at >= ?", since).
+		Scan(&total).Error
+	return total, err
+}
@@ -3,18 +3,38 @@ package repository
 import (
 	"context"
 	"errors"
-	"fmt"
+	"go.uber.org/zap"
 	"gorm.io/gorm"
+	"reflect"
 	v1 "shengcai/api/v1"
 	"shengcai/internal/model"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// CellDataFilter 文章列表的查询条件
+type CellDataFilter struct {
+	SheetID string
+	TabID   string
+	// PublishedFrom、PublishedTo 发布时间的范围，左闭右开，为空时不限制，没有发布时间的文章不在范围内
+	PublishedFrom *time.Time
+	PublishedTo   *time.Time
+	// Sort 排序方式，默认按文章在表格中的位置
+	Sort string
+}
+
 type CellDataRepository interface {
-	Create(ctx context.Context, cellData *model.CellData) error
-	List(ctx context.Context, filter struct {
-		SheetID string `json:"sheet_id" validate:"required"`
-	}, page int) (*v1.ShengCaiListResponse, error)
+	Create(ctx context.Context, cellData *model.CellData) (bool, error)
+	List(ctx context.Context, filter CellDataFilter, page int) (*v1.ShengCaiListResponse, error)
 	GetMetaData(ctx context.Context, sheetId string) (*v1.ShengCaiGetMetaDataResponse, error)
+	MarkDeleted(ctx context.Context, sheetId string, keepLinks map[string]struct{}) ([]string, error)
+	MarkDeletedRecords(ctx context.Context, sheetId string, keepRecordIDs map[string]struct{}) ([]string, error)
+	GetByLink(ctx context.Context, sheetId string, link string) (*model.CellData, error)
+	ListWriteBack(ctx context.Context, sheetId string) ([]*model.CellData, error)
+	Updates(ctx context.Context, id int, values map[string]interface{}) error
+	Summarize(ctx context.Context, id int, commentDigest string) error
+	SummarizePending(ctx context.Context, sheetId string, limit int) (int, error)
 }
 
 func NewCellDataRepository(
@@ -32,73 +52,239 @@ type cellDataRepository struct {
 	aiRepository AIRepository
 }
 
-func (r *cellDataRepository) Create(ctx context.Context, cellData *model.CellData) error {
+// Create 新增或更新一篇文章，返回是否新增了文章。
+// 是否重新生成摘要只看 cellData.ContentHash，调用方未设置时按 Content 计算，Markdown 和评论摘录不参与，
+// 避免 Markdown、评论获取失败或素材地址改写导致重复生成摘要。
+// 新增或正文变化的文章标记为 AIStatePending，并同步到 cellData.AIState，
+// 由调用方在事务提交后调用 Summarize 生成摘要，避免调用模型时占用事务，token 用量也不会随事务回滚
+func (r *cellDataRepository) Create(ctx context.Context, cellData *model.CellData) (bool, error) {
 	var existingCellData model.CellData
 
-	// 检查数据库中是否已存在相同的 Link
-	err := r.DB(ctx).Where("sheet_id =?", cellData.SheetID).Where("link = ?", cellData.Link).First(&existingCellData).Error
-	fmt.Println("===================================")
-	fmt.Println(err)
-	fmt.Println("===================================")
+	// 检查数据库中是否已存在相同的 Link，多维表格的记录按 record_id 匹配
+	query := r.DB(ctx).Where("sheet_id =?", cellData.SheetID)
+	if cellData.RecordID != "" {
+		query = query.Where("record_id = ?", cellData.RecordID)
+	} else {
+		query = query.Where("link = ?", cellData.Link)
+	}
+	err := query.First(&existingCellData).Error
 
 	if err == nil {
-		// 如果找到了相同的 Link，比较 ReleaseDate
-		if existingCellData.ReleaseDate == cellData.ReleaseDate && existingCellData.SortNumber == cellData.SortNumber {
-			// 如果 ReleaseDate 相同，则不进行任何操作
-			return nil
-		} else {
-			// 如果 ReleaseDate 不同，则更新 Content 和 ReleaseDate
-			existingCellData.Content = cellData.Content
-			existingCellData.ReleaseDate = cellData.ReleaseDate
-			existingCellData.SortNumber = cellData.SortNumber
-
-			abstract, keyword, err := r.aiRepository.GenerateAbstractAndKeyword(ctx, existingCellData.Content)
-			if err != nil {
-				fmt.Println("+++++++++++++++++++++++++++++++++++")
-				fmt.Println(err)
-				fmt.Println("+++++++++++++++++++++++++++++++++++")
+		// 调用方通过 ID 关联评论等数据
+		cellData.ID = existingCellData.ID
+
+		// 已被标记删除的文章重新出现在表格中时恢复
+		restored := existingCellData.Deleted
+		existingCellData.Deleted = false
+		existingCellData.DeletedAt = nil
+
+		// 正文获取失败时保留已保存的正文和摘要，只更新元数据
+		fetchFailed := isFetchError(cellData.Content) && existingCellData.Content != "" && !isFetchError(existingCellData.Content)
+		// 早期保存的文章没有正文哈希，按已保存的正文补齐
+		hashMissing := existingCellData.ContentHash == ""
+		if hashMissing {
+			existingCellData.ContentHash = ContentHash(existingCellData.Content)
+		}
+		if cellData.ContentHash == "" {
+			cellData.ContentHash = ContentHash(cellData.Content)
+		}
+		contentChanged := !fetchFailed && existingCellData.ContentHash != cellData.ContentHash
+
+		metaChanged := restored || hashMissing ||
+			existingCellData.Title != cellData.Title ||
+			existingCellData.Link != cellData.Link ||
+			existingCellData.TabID != cellData.TabID ||
+			existingCellData.TabTitle != cellData.TabTitle ||
+			existingCellData.TabIndex != cellData.TabIndex ||
+			(!fetchFailed && existingCellData.LinkType != cellData.LinkType) ||
+			existingCellData.ReleaseDate != cellData.ReleaseDate ||
+			existingCellData.SortNumber != cellData.SortNumber ||
+			!sameTime(existingCellData.PublishedAt, cellData.PublishedAt) ||
+			!reflect.DeepEqual(existingCellData.Extra, cellData.Extra)
+		existingCellData.Title = cellData.Title
+		// 多维表格的记录按 record_id 匹配，链接可能被修改
+		existingCellData.Link = cellData.Link
+		existingCellData.TabID = cellData.TabID
+		existingCellData.TabTitle = cellData.TabTitle
+		existingCellData.TabIndex = cellData.TabIndex
+		if !fetchFailed {
+			existingCellData.LinkType = cellData.LinkType
+		}
+		existingCellData.ReleaseDate = cellData.ReleaseDate
+		existingCellData.SortNumber = cellData.SortNumber
+		existingCellData.Extra = cellData.Extra
+		// 发布日期只影响元数据，解析规则或时区变化后随下一次同步更新
+		existingCellData.PublishedAt = cellData.PublishedAt
+
+		// 正文不变时素材地址的改写、重新获取到的 Markdown 只更新保存的内容，Markdown 获取失败时保留原来的
+		if !fetchFailed && !contentChanged {
+			if existingCellData.Content != cellData.Content {
+				existingCellData.Content = cellData.Content
+				metaChanged = true
+			}
+			if cellData.ContentMarkdown != "" && existingCellData.ContentMarkdown != cellData.ContentMarkdown {
+				existingCellData.ContentMarkdown = cellData.ContentMarkdown
+				metaChanged = true
 			}
+		}
 
-			existingCellData.Abstract = abstract
-			existingCellData.Keyword = keyword
-			if err := r.DB(ctx).Save(&existingCellData).Error; err != nil {
-				fmt.Println("-----------------------------------")
-				fmt.Println(err)
-				fmt.Println("-----------------------------------")
-				return err
+		// 表格中插入行导致的序号变化、发布日期的修改都只更新元数据，正文变化时才重新生成摘要
+		if !contentChanged {
+			if metaChanged {
+				return false, r.DB(ctx).Save(&existingCellData).Error
 			}
+			return false, nil
+		}
+
+		existingCellData.Content = cellData.Content
+		existingCellData.ContentMarkdown = cellData.ContentMarkdown
+		existingCellData.ContentHash = cellData.ContentHash
+		existingCellData.CommentDigest = cellData.CommentDigest
+		existingCellData.AIState = model.AIStatePending
+		cellData.AIState = model.AIStatePending
+		if err := r.DB(ctx).Save(&existingCellData).Error; err != nil {
+			r.logger.WithContext(ctx).Error("update article failed", zap.String("link", cellData.Link), zap.Error(err))
+			return false, err
 		}
 	} else if errors.Is(err, gorm.ErrRecordNotFound) {
-		abstract, keyword, err := r.aiRepository.GenerateAbstractAndKeyword(ctx, cellData.Content)
-		if err != nil {
-			fmt.Println("+++++++++++++++++++++++++++++++++++")
-			fmt.Println(err)
-			fmt.Println("+++++++++++++++++++++++++++++++++++")
+		// 如果没有找到相同的 Link，则进行新建，摘要在事务提交后生成，token 用量按文章记录
+		if cellData.ContentHash == "" {
+			cellData.ContentHash = ContentHash(cellData.Content)
 		}
-		cellData.Abstract = abstract
-		cellData.Keyword = keyword
-
-		// 如果没有找到相同的 Link，则进行新建
+		cellData.AIState = model.AIStatePending
 		if err := r.DB(ctx).Create(cellData).Error; err != nil {
-			fmt.Println("***********************************")
-			fmt.Println(err)
-			fmt.Println("***********************************")
-			return err
+			r.logger.WithContext(ctx).Error("create article failed", zap.String("link", cellData.Link), zap.Error(err))
+			return false, err
 		}
+		return true, nil
 	} else {
 		// 如果查询出错且错误不是 "记录未找到"，则返回查询错误
+		return false, err
+	}
+
+	return false, nil
+}
+
+// Summarize 为文章生成摘要，不能在事务中调用。评论摘录不保存，由调用方传入
+func (r *cellDataRepository) Summarize(ctx context.Context, id int, commentDigest string) error {
+	var cellData model.CellData
+	if err := r.DB(ctx).First(&cellData, id).Error; err != nil {
 		return err
 	}
+	cellData.CommentDigest = commentDigest
+	r.summarize(ctx, &cellData)
+	return r.saveSummary(ctx, &cellData)
+}
+
+// SummarizePending 为等待生成摘要的文章补齐摘要，包括超出 token 预算而暂停的和保存后没来得及生成的，
+// 按新增的顺序处理，再次超出预算时停止，返回本次处理的文章数
+func (r *cellDataRepository) SummarizePending(ctx context.Context, sheetId string, limit int) (int, error) {
+	var list []*model.CellData
+	if err := r.DB(ctx).
+		Where("sheet_id = ? AND deleted = ? AND ai_state = ?", sheetId, false, model.AIStatePending).
+		Order("id").Limit(limit).Find(&list).Error; err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, cellData := range list {
+		r.summarize(ctx, cellData)
+		if cellData.AIState == model.AIStatePending {
+			break
+		}
+		if err := r.saveSummary(ctx, cellData); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// summarize 生成文章的摘要和关键字，失败时保留文章并记录原因，不影响同步。
+// 超出 token 预算时标记为 AIStatePending，保留原有的摘要，等待预算恢复后由 SummarizePending 补齐
+func (r *cellDataRepository) summarize(ctx context.Context, cellData *model.CellData) {
+	summary, err := r.aiRepository.Summarize(ctx, SummaryArticle{
+		SheetID:    cellData.SheetID,
+		CellDataID: cellData.ID,
+		Content:    summaryContent(cellData),
+	})
+	if errors.Is(err, ErrBudgetExceeded) {
+		r.logger.WithContext(ctx).Info("ai token budget exceeded, summary pending", zap.String("link", cellData.Link))
+		cellData.AIState = model.AIStatePending
+		cellData.AIError = truncateError(err.Error())
+		return
+	}
+	if err != nil {
+		r.logger.WithContext(ctx).Error("summarize article failed", zap.String("link", cellData.Link), zap.Error(err))
+		cellData.Abstract = ""
+		cellData.Keyword = ""
+		cellData.AIExtra = nil
+		cellData.AIState = model.AIStateFailed
+		cellData.AIError = truncateError(err.Error())
+		return
+	}
+	if summary.Warning != "" {
+		r.logger.WithContext(ctx).Warn("summary generated with warnings", zap.String("link", cellData.Link), zap.String("warning", summary.Warning))
+	}
+	cellData.Abstract = summary.Abstract
+	cellData.Keyword = summary.Keyword()
+	cellData.AIExtra = summary.Extra
+	cellData.AIState = summary.State
+	cellData.AIError = truncateError(summary.Warning)
+}
 
-	return nil
+// saveSummary 只更新摘要相关的字段
+func (r *cellDataRepository) saveSummary(ctx context.Context, cellData *model.CellData) error {
+	return r.DB(ctx).Model(cellData).
+		Select("abstract", "keyword", "ai_extra", "ai_state", "ai_error").
+		Updates(cellData).Error
+}
+
+// truncateError 截断错误信息以适应 ai_error 的长度
+func truncateError(message string) string {
+	if runes := []rune(message); len(runes) > 255 {
+		return string(runes[:255])
+	}
+	return message
+}
+
+// isFetchError 判断正文是否为获取文档失败时记录的错误信息
+func isFetchError(content string) bool {
+	return strings.HasPrefix(content, "Error processing link")
+}
+
+// sameTime 比较两个可能为空的时间
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Equal(*b)
+}
+
+// summaryContent 生成摘要时优先使用保留了文档结构的 Markdown，并附上评论摘录
+func summaryContent(cellData *model.CellData) string {
+	content := cellData.Content
+	if cellData.ContentMarkdown != "" {
+		content = cellData.ContentMarkdown
+	}
+	if cellData.CommentDigest != "" && content != "" {
+		content += "\n\n" + cellData.CommentDigest
+	}
+	return content
 }
 
-func (r *cellDataRepository) List(ctx context.Context, filter struct {
-	SheetID string `json:"sheet_id" validate:"required"`
-}, page int) (*v1.ShengCaiListResponse, error) {
+func (r *cellDataRepository) List(ctx context.Context, filter CellDataFilter, page int) (*v1.ShengCaiListResponse, error) {
 	// 构建查询条件
 	query := r.DB(ctx)
-	query = query.Where("sheet_id = ?", filter.SheetID)
+	query = query.Where("sheet_id = ?", filter.SheetID).Where("deleted = ?", false)
+	if filter.TabID != "" {
+		query = query.Where("tab_id = ?", filter.TabID)
+	}
+	if filter.PublishedFrom != nil {
+		query = query.Where("published_at >= ?", *filter.PublishedFrom)
+	}
+	if filter.PublishedTo != nil {
+		query = query.Where("published_at < ?", *filter.PublishedTo)
+	}
 
 	// 获取总记录数
 	var totalCount int64
@@ -108,7 +294,14 @@ func (r *cellDataRepository) List(ctx context.Context, filter struct {
 
 	// 执行分页查询
 	var list []*model.CellData
-	err := query.Order("sort_number ASC").Offset((page - 1) * 10).Limit(10).Find(&list).Error
+	switch filter.Sort {
+	case v1.ListSortDateDesc:
+		// 没有发布时间的文章排在最后
+		query = query.Order("published_at IS NULL").Order("published_at DESC")
+	case v1.ListSortDateAsc:
+		query = query.Order("published_at IS NULL").Order("published_at ASC")
+	}
+	err := query.Order("tab_index ASC").Order("sort_number ASC").Offset((page - 1) * 10).Limit(10).Find(&list).Error
 	if err != nil {
 		return nil, err
 	}
@@ -117,30 +310,45 @@ func (r *cellDataRepository) List(ctx context.Context, filter struct {
 	result := &v1.ShengCaiListResponse{
 		TotalCount: int(totalCount),
 		List: make([]struct {
-			SheetID     string `json:"sheet_id"`
-			Title       string `json:"title"`
-			Link        string `json:"link"`
-			ReleaseDate string `json:"release_date"`
-			Abstract    string `json:"abstract"`
-			Keyword     string `json:"keyword"`
+			SheetID     string            `json:"sheet_id"`
+			TabID       string            `json:"tab_id"`
+			TabTitle    string            `json:"tab_title"`
+			Title       string            `json:"title"`
+			Link        string            `json:"link"`
+			LinkType    string            `json:"link_type"`
+			ReleaseDate string            `json:"release_date"`
+			PublishedAt *time.Time        `json:"published_at"`
+			Abstract    string            `json:"abstract"`
+			Keyword     string            `json:"keyword"`
+			Extra       map[string]string `json:"extra"`
 		}, len(list)),
 	}
 
 	for i, item := range list {
 		result.List[i] = struct {
-			SheetID     string `json:"sheet_id"`
-			Title       string `json:"title"`
-			Link        string `json:"link"`
-			ReleaseDate string `json:"release_date"`
-			Abstract    string `json:"abstract"`
-			Keyword     string `json:"keyword"`
+			SheetID     string            `json:"sheet_id"`
+			TabID       string            `json:"tab_id"`
+			TabTitle    string            `json:"tab_title"`
+			Title       string            `json:"title"`
+			Link        string            `json:"link"`
+			LinkType    string            `json:"link_type"`
+			ReleaseDate string            `json:"release_date"`
+			PublishedAt *time.Time        `json:"published_at"`
+			Abstract    string            `json:"abstract"`
+			Keyword     string            `json:"keyword"`
+			Extra       map[string]string `json:"extra"`
 		}{
 			SheetID:     item.SheetID,
+			TabID:       item.TabID,
+			TabTitle:    item.TabTitle,
 			Title:       item.Title,
 			Link:        item.Link,
+			LinkType:    item.LinkType,
 			ReleaseDate: item.ReleaseDate,
+			PublishedAt: item.PublishedAt,
 			Abstract:    item.Abstract,
 			Keyword:     item.Keyword,
+			Extra:       item.Extra,
 		}
 	}
 
@@ -150,12 +358,13 @@ func (r *cellDataRepository) List(ctx context.Context, filter struct {
 func (r *cellDataRepository) GetMetaData(ctx context.Context, sheetId string) (*v1.ShengCaiGetMetaDataResponse, error) {
 	var result v1.ShengCaiGetMetaDataResponse
 	var sheetInfo struct {
-		SheetName string `gorm:"column:sheet_name"`
-		UpdateLog string `gorm:"column:update_log"`
+		SheetName        string `gorm:"column:sheet_name"`
+		UpdateLog        string `gorm:"column:update_log"`
+		LatestModifyTime string `gorm:"column:latest_modify_time"`
 	}
 
 	// 查询 sheet_info 表
-	if err := r.db.WithContext(ctx).Table("sheet_info").Where("sheet_id = ?", sheetId).Select("sheet_name, update_log").First(&sheetInfo).Error; err != nil {
+	if err := r.db.WithContext(ctx).Table("sheet_info").Where("sheet_id = ?", sheetId).Select("sheet_name, update_log, latest_modify_time").First(&sheetInfo).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			result.SheetName = ""
 			result.UpdateLog = ""
@@ -166,7 +375,140 @@ func (r *cellDataRepository) GetMetaData(ctx context.Context, sheetId string) (*
 		// 将查询结果赋值给响应结构体
 		result.SheetName = sheetInfo.SheetName
 		result.UpdateLog = sheetInfo.UpdateLog
+		if seconds, err := strconv.ParseInt(sheetInfo.LatestModifyTime, 10, 64); err == nil {
+			updatedAt := time.Unix(seconds, 0)
+			result.UpdatedAt = &updatedAt
+		}
+	}
+
+	// 按工作表在原表格中的顺序返回已采集的工作表
+	if err := r.DB(ctx).Model(&model.CellData{}).
+		Where("sheet_id = ?", sheetId).
+		Where("deleted = ?", false).
+		Where("tab_id <> ''").
+		Select("tab_id, tab_title, MIN(tab_index) AS tab_index").
+		Group("tab_id, tab_title").
+		Order("tab_index ASC").
+		Scan(&result.Tabs).Error; err != nil {
+		return nil, err
 	}
 
 	return &result, nil
 }
+
+// MarkDeleted 将表格中已不存在的文章标记为删除，返回本次被标记的链接
+func (r *cellDataRepository) MarkDeleted(ctx context.Context, sheetId string, keepLinks map[string]struct{}) ([]string, error) {
+	var links []string
+	if err := r.DB(ctx).Model(&model.CellData{}).
+		Where("sheet_id = ?", sheetId).
+		Where("deleted = ?", false).
+		Pluck("link", &links).Error; err != nil {
+		return nil, err
+	}
+
+	var removed []string
+	for _, link := range links {
+		if _, ok := keepLinks[link]; !ok {
+			removed = append(removed, link)
+		}
+	}
+	if len(removed) == 0 {
+		return nil, nil
+	}
+
+	// 分批更新，避免 IN 条件过长
+	now := time.Now()
+	for start := 0; start < len(removed); start += 500 {
+		end := start + 500
+		if end > len(removed) {
+			end = len(removed)
+		}
+		if err := r.DB(ctx).Model(&model.CellData{}).
+			Where("sheet_id = ?", sheetId).
+			Where("link IN ?", removed[start:end]).
+			Updates(map[string]interface{}{
+				"deleted":    true,
+				"deleted_at": now,
+			}).Error; err != nil {
+			return nil, err
+		}
+	}
+	return removed, nil
+}
+
+// MarkDeletedRecords 将多维表格中已不存在的记录标记为删除，返回本次被标记记录的链接
+func (r *cellDataRepository) MarkDeletedRecords(ctx context.Context, sheetId string, keepRecordIDs map[string]struct{}) ([]string, error) {
+	var records []struct {
+		ID       int
+		RecordID string
+		Link     string
+	}
+	if err := r.DB(ctx).Model(&model.CellData{}).
+		Where("sheet_id = ?", sheetId).
+		Where("deleted = ?", false).
+		Where("record_id <> ''").
+		Select("id, record_id, link").
+		Scan(&records).Error; err != nil {
+		return nil, err
+	}
+
+	var ids []int
+	var removed []string
+	for _, record := range records {
+		if _, ok := keepRecordIDs[record.RecordID]; !ok {
+			ids = append(ids, record.ID)
+			removed = append(removed, record.Link)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	// 分批更新，避免 IN 条件过长
+	now := time.Now()
+	for start := 0; start < len(ids); start += 500 {
+		end := start + 500
+		if end > len(ids) {
+			end = len(ids)
+		}
+		if err := r.DB(ctx).Model(&model.CellData{}).
+			Where("id IN ?", ids[start:end]).
+			Updates(map[string]interface{}{
+				"deleted":    true,
+				"deleted_at": now,
+			}).Error; err != nil {
+			return nil, err
+		}
+	}
+	return removed, nil
+}
+
+// GetByLink 获取表格中某一篇未删除的文章
+func (r *cellDataRepository) GetByLink(ctx context.Context, sheetId string, link string) (*model.CellData, error) {
+	var cellData model.CellData
+	if err := r.DB(ctx).Where("sheet_id = ?", sheetId).Where("link = ?", link).Where("deleted = ?", false).First(&cellData).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, v1.ErrNotFound
+		}
+		return nil, err
+	}
+	return &cellData, nil
+}
+
+// ListWriteBack 获取表格中已生成摘要或关键字、需要检查是否回写的文章，只查询回写用到的字段
+func (r *cellDataRepository) ListWriteBack(ctx context.Context, sheetId string) ([]*model.CellData, error) {
+	var list []*model.CellData
+	if err := r.DB(ctx).
+		Where("sheet_id = ?", sheetId).
+		Where("deleted = ?", false).
+		Where("(abstract <> '' OR keyword <> '')").
+		Select("id, link, abstract, keyword, written_abstract, written_keyword").
+		Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *cellDataRepository) Updates(ctx context.Context, id int, values map[string]interface{}) error {
+	return r.DB(ctx).Model(&model.CellData{}).Where("id = ?", id).Updates(values).Error
+}
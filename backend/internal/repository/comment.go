@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"shengcai/internal/model"
+)
+
+type CommentRepository interface {
+	Replace(ctx context.Context, cellDataID int, comments []*model.Comment) error
+	ListByCellData(ctx context.Context, cellDataID int) ([]*model.Comment, error)
+}
+
+func NewCommentRepository(
+	r *Repository,
+) CommentRepository {
+	return &commentRepository{
+		Repository: r,
+	}
+}
+
+type commentRepository struct {
+	*Repository
+}
+
+// Replace 用最新获取的评论替换文章已保存的评论，文档中删除的评论随之删除
+func (r *commentRepository) Replace(ctx context.Context, cellDataID int, comments []*model.Comment) error {
+	if err := r.DB(ctx).Where("cell_data_id = ?", cellDataID).Delete(&model.Comment{}).Error; err != nil {
+		return err
+	}
+	if len(comments) == 0 {
+		return nil
+	}
+	for _, comment := range comments {
+		comment.ID = 0
+		comment.CellDataID = cellDataID
+	}
+	return r.DB(ctx).CreateInBatches(comments, 100).Error
+}
+
+// ListByCellData 按回复时间返回文章的所有评论
+func (r *commentRepository) ListByCellData(ctx context.Context, cellDataID int) ([]*model.Comment, error) {
+	var list []*model.Comment
+	if err := r.DB(ctx).Where("cell_data_id = ?", cellDataID).Order("reply_time ASC").Order("id ASC").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
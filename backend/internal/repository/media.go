@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	v1 "shengcai/api/v1"
+	"shengcai/internal/model"
+)
+
+type MediaRepository interface {
+	Create(ctx context.Context, media *model.Media) error
+	GetByID(ctx context.Context, id int) (*model.Media, error)
+	GetByToken(ctx context.Context, token string) (*model.Media, error)
+}
+
+func NewMediaRepository(
+	r *Repository,
+) MediaRepository {
+	return &mediaRepository{
+		Repository: r,
+	}
+}
+
+type mediaRepository struct {
+	*Repository
+}
+
+// Create 保存素材，同一个 token 已存在时直接使用已有的记录
+func (r *mediaRepository) Create(ctx context.Context, media *model.Media) error {
+	existing, err := r.GetByToken(ctx, media.Token)
+	if err == nil {
+		*media = *existing
+		return nil
+	}
+	if !errors.Is(err, v1.ErrNotFound) {
+		return err
+	}
+	return r.DB(ctx).Create(media).Error
+}
+
+func (r *mediaRepository) GetByID(ctx context.Context, id int) (*model.Media, error) {
+	var media model.Media
+	if err := r.DB(ctx).Where("id = ?", id).First(&media).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, v1.ErrNotFound
+		}
+		return nil, err
+	}
+	return &media, nil
+}
+
+func (r *mediaRepository) GetByToken(ctx context.Context, token string) (*model.Media, error) {
+	var media model.Media
+	if err := r.DB(ctx).Where("token = ?", token).First(&media).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, v1.ErrNotFound
+		}
+		return nil, err
+	}
+	return &media, nil
+}
@@ -64,6 +64,19 @@ func (r *Repository) Transaction(ctx context.Context, fn func(ctx context.Contex
 	})
 }
 
+// timezone 按 feishu.timezone 划分日期，未配置或无法识别时使用服务器的本地时区
+func (r *Repository) timezone() *time.Location {
+	name := r.conf.GetString("feishu.timezone")
+	if name == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
 func NewDB(conf *viper.Viper, l *log.Logger) *gorm.DB {
 	var (
 		db  *gorm.DB
@@ -3,13 +3,19 @@ package repository
 import (
 	"context"
 	"errors"
-	"fmt"
 	"gorm.io/gorm"
+	v1 "shengcai/api/v1"
 	"shengcai/internal/model"
 )
 
 type SheetInfoRepository interface {
 	Create(ctx context.Context, sheetInfo *model.SheetInfo) error
+	Save(ctx context.Context, sheetInfo *model.SheetInfo) error
+	Updates(ctx context.Context, sheetId string, values map[string]interface{}) error
+	GetBySheetID(ctx context.Context, sheetId string) (*model.SheetInfo, error)
+	GetByOriginSheetID(ctx context.Context, originSheetId string) (*model.SheetInfo, error)
+	List(ctx context.Context) ([]*model.SheetInfo, error)
+	ListActive(ctx context.Context) ([]*model.SheetInfo, error)
 }
 
 func NewSheetInfoRepository(
@@ -30,8 +36,7 @@ func (r *sheetInfoRepository) Create(ctx context.Context, sheetInfo *model.Sheet
 	err := r.DB(ctx).Where("sheet_id = ?", sheetInfo.SheetID).First(&existingSheetInfo).Error
 
 	if err == nil {
-		// 如果找到了相同的 sheet_id，则返回自定义错误或跳过创建
-		fmt.Printf("sheet with sheet_id '%s' already exists\n", sheetInfo.SheetID)
+		// 已登记的表格直接跳过，重复登记不会修改已有的配置
 		return nil
 	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
 		// 如果查询出错且错误不是 "记录未找到"，则返回查询错误
@@ -44,3 +49,59 @@ func (r *sheetInfoRepository) Create(ctx context.Context, sheetInfo *model.Sheet
 	}
 	return nil
 }
+
+func (r *sheetInfoRepository) Save(ctx context.Context, sheetInfo *model.SheetInfo) error {
+	if err := r.DB(ctx).Save(sheetInfo).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// Updates 只更新指定的列，避免同步过程中覆盖管理接口对状态的修改
+func (r *sheetInfoRepository) Updates(ctx context.Context, sheetId string, values map[string]interface{}) error {
+	if err := r.DB(ctx).Model(&model.SheetInfo{}).Where("sheet_id = ?", sheetId).Updates(values).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetBySheetID 查询表格登记信息，包含已移除的记录
+func (r *sheetInfoRepository) GetBySheetID(ctx context.Context, sheetId string) (*model.SheetInfo, error) {
+	var sheetInfo model.SheetInfo
+	if err := r.DB(ctx).Where("sheet_id = ?", sheetId).First(&sheetInfo).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, v1.ErrNotFound
+		}
+		return nil, err
+	}
+	return &sheetInfo, nil
+}
+
+// GetByOriginSheetID 查询原始表格对应的镜像表格，不包含已移除的记录
+func (r *sheetInfoRepository) GetByOriginSheetID(ctx context.Context, originSheetId string) (*model.SheetInfo, error) {
+	var sheetInfo model.SheetInfo
+	if err := r.DB(ctx).Where("origin_sheet_id = ?", originSheetId).Where("deleted = ?", false).
+		Order("id DESC").First(&sheetInfo).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, v1.ErrNotFound
+		}
+		return nil, err
+	}
+	return &sheetInfo, nil
+}
+
+func (r *sheetInfoRepository) List(ctx context.Context) ([]*model.SheetInfo, error) {
+	var list []*model.SheetInfo
+	if err := r.DB(ctx).Where("deleted = ?", false).Order("id ASC").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *sheetInfoRepository) ListActive(ctx context.Context) ([]*model.SheetInfo, error) {
+	var list []*model.SheetInfo
+	if err := r.DB(ctx).Where("deleted = ?", false).Where("status = ?", model.SheetStatusActive).Order("id ASC").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
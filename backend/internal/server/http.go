@@ -20,6 +20,10 @@ func NewHTTPServer(
 	jwt *jwt.JWT,
 	userHandler *handler.UserHandler,
 	shengCaiHandler *handler.ShengCaiHandler,
+	sheetInfoHandler *handler.SheetInfoHandler,
+	eventHandler *handler.EventHandler,
+	mediaHandler *handler.MediaHandler,
+	aiUsageHandler *handler.AIUsageHandler,
 ) *http.Server {
 	gin.SetMode(gin.DebugMode)
 	s := http.NewServer(
@@ -58,6 +62,8 @@ func NewHTTPServer(
 		{
 			noAuthRouter.POST("/register", userHandler.Register)
 			noAuthRouter.POST("/login", userHandler.Login)
+			// 飞书事件订阅，通过 Verification Token 和签名校验请求来源
+			noAuthRouter.POST("/feishu/event", eventHandler.FeiShu)
 		}
 		// Non-strict permission routing group
 		noStrictAuthRouter := v1.Group("/").Use(middleware.NoStrictAuth(jwt, logger))
@@ -66,13 +72,35 @@ func NewHTTPServer(
 
 			noStrictAuthRouter.POST("/list", shengCaiHandler.List)
 			noStrictAuthRouter.POST("/get_meta_data", shengCaiHandler.GetMetaData)
+			noStrictAuthRouter.POST("/detail", shengCaiHandler.Detail)
+			noStrictAuthRouter.POST("/comments", shengCaiHandler.Comments)
 			//noStrictAuthRouter.POST("/create_data", shengCaiHandler.CreateData)
 		}
 
+		// 文章中的图片和附件，允许通过 Cookie 或参数携带 token
+		readAuthRouter := v1.Group("/").Use(middleware.ReadAuth(jwt, logger))
+		{
+			readAuthRouter.GET("/media/:id", mediaHandler.Get)
+		}
+
+		// RPA 等脚本调用的接口，通过 security.api_sign 签名校验
+		signRouter := v1.Group("/").Use(middleware.SignMiddleware(logger, conf))
+		{
+			signRouter.POST("/sheet/mirror/report", sheetInfoHandler.ReportMirror)
+		}
+
 		// Strict permission routing group
 		strictAuthRouter := v1.Group("/").Use(middleware.StrictAuth(jwt, logger))
 		{
 			strictAuthRouter.PUT("/user", userHandler.UpdateProfile)
+
+			strictAuthRouter.POST("/sheet/register", sheetInfoHandler.Register)
+			strictAuthRouter.POST("/sheet/update_status", sheetInfoHandler.UpdateStatus)
+			strictAuthRouter.POST("/sheet/mute", sheetInfoHandler.Mute)
+			strictAuthRouter.POST("/sheet/remove", sheetInfoHandler.Remove)
+			strictAuthRouter.POST("/sheet/list", sheetInfoHandler.List)
+
+			strictAuthRouter.POST("/ai/usage", aiUsageHandler.Stats)
 		}
 	}
 
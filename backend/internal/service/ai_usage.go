@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	v1 "shengcai/api/v1"
+	"shengcai/internal/model"
+	"shengcai/internal/repository"
+	"sort"
+	"time"
+)
+
+// 查询 token 用量的默认天数和最大天数
+const (
+	defaultUsageDays = 30
+	maxUsageDays     = 366
+)
+
+type AIUsageService interface {
+	Stats(ctx context.Context, req *v1.AIUsageRequest) (*v1.AIUsageResponse, error)
+}
+
+func NewAIUsageService(
+	service *Service,
+	aiUsageRepo repository.AIUsageRepository,
+	sheetInfoRepo repository.SheetInfoRepository,
+) AIUsageService {
+	return &aiUsageService{
+		Service:       service,
+		aiUsageRepo:   aiUsageRepo,
+		sheetInfoRepo: sheetInfoRepo,
+	}
+}
+
+type aiUsageService struct {
+	*Service
+	aiUsageRepo   repository.AIUsageRepository
+	sheetInfoRepo repository.SheetInfoRepository
+}
+
+// Stats 按天和按表格汇总 token 用量，并返回当前的预算
+func (s *aiUsageService) Stats(ctx context.Context, req *v1.AIUsageRequest) (*v1.AIUsageResponse, error) {
+	now := time.Now().In(s.timezone())
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).AddDate(0, 0, 1)
+	if req.DateTo != "" {
+		date, err := time.ParseInLocation("2006-01-02", req.DateTo, s.timezone())
+		if err != nil {
+			return nil, v1.ErrBadRequest
+		}
+		to = date.AddDate(0, 0, 1)
+	}
+	from := to.AddDate(0, 0, -defaultUsageDays)
+	if req.DateFrom != "" {
+		date, err := time.ParseInLocation("2006-01-02", req.DateFrom, s.timezone())
+		if err != nil {
+			return nil, v1.ErrBadRequest
+		}
+		from = date
+	}
+	if !from.Before(to) || to.Sub(from) > maxUsageDays*24*time.Hour {
+		return nil, v1.ErrBadRequest
+	}
+
+	list, err := s.aiUsageRepo.List(ctx, repository.AIUsageFilter{SheetID: req.SpreadsheetToken, From: from, To: to})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &v1.AIUsageResponse{}
+	dailyIndex := make(map[string]int)
+	for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+		date := day.Format("2006-01-02")
+		dailyIndex[date] = len(result.Daily)
+		result.Daily = append(result.Daily, v1.AIUsageDaily{Date: date})
+	}
+	sheetIndex := make(map[string]int)
+	for _, usage := range list {
+		addUsage(&result.Total, usage)
+		if i, ok := dailyIndex[usage.CreatedAt.In(s.timezone()).Format("2006-01-02")]; ok {
+			addUsage(&result.Daily[i].AIUsageTotal, usage)
+		}
+		i, ok := sheetIndex[usage.SheetID]
+		if !ok {
+			i = len(result.Sheets)
+			sheetIndex[usage.SheetID] = i
+			result.Sheets = append(result.Sheets, v1.AIUsageSheet{SheetID: usage.SheetID})
+		}
+		addUsage(&result.Sheets[i].AIUsageTotal, usage)
+	}
+
+	if len(result.Sheets) > 0 {
+		sheets, err := s.sheetInfoRepo.List(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, sheet := range sheets {
+			if i, ok := sheetIndex[sheet.SheetID]; ok {
+				result.Sheets[i].SheetName = sheet.DisplayName()
+			}
+		}
+		sort.SliceStable(result.Sheets, func(i, j int) bool {
+			return result.Sheets[i].TotalTokens > result.Sheets[j].TotalTokens
+		})
+	}
+
+	budget, err := s.aiUsageRepo.Budget(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+	result.Budget = v1.AIUsageBudget{
+		Daily:       budget.Daily,
+		DailyUsed:   budget.DailyUsed,
+		Monthly:     budget.Monthly,
+		MonthlyUsed: budget.MonthlyUsed,
+		Paused:      budget.Exceeded(),
+	}
+	return result, nil
+}
+
+func addUsage(total *v1.AIUsageTotal, usage *model.AIUsage) {
+	total.Calls++
+	if usage.Error != "" {
+		total.FailedCalls++
+	}
+	total.PromptTokens += usage.PromptTokens
+	total.CompletionTokens += usage.CompletionTokens
+	total.TotalTokens += usage.TotalTokens()
+}
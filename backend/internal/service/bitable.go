@@ -0,0 +1,283 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"go.uber.org/zap"
+	"net/http"
+	"net/url"
+	"shengcai/internal/model"
+	"strings"
+	"sync"
+	"time"
+)
+
+// bitablePageSize 多维表格记录接口每页的最大条数
+const bitablePageSize = 500
+
+// SaveBitableData 同步多维表格中的记录，sheet_id 为多维表格的 app_token。
+// 每条记录以 record_id 作为标识，修改和删除都按记录追踪。
+func (s *feiShuService) SaveBitableData(ctx context.Context, appID string, appSecret string, sheetInfo *model.SheetInfo) (*model.SyncResult, error) {
+	appToken := sheetInfo.SheetID
+	lastModifyTime, err := s.getLatestModifyTime(ctx, appID, appSecret, appToken, "bitable")
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.sheetInfoRepo.Updates(ctx, appToken, map[string]interface{}{
+		"update_log": s.formatUpdateLog(ctx, lastModifyTime),
+	}); err != nil {
+		return nil, err
+	}
+
+	// 多维表格没有版本号，只根据最后修改时间判断是否需要同步
+	if sheetInfo.LatestModifyTime != "" && sheetInfo.LatestModifyTime == lastModifyTime {
+		s.logger.WithContext(ctx).Info("bitable unchanged, skip sync",
+			zap.String("sheet_id", appToken), zap.String("latest_modify_time", lastModifyTime))
+		return &model.SyncResult{Skipped: true}, nil
+	}
+
+	mapping := sheetInfo.GetColumnMapping()
+	if mapping.Title == "" {
+		return nil, fmt.Errorf("title field is not configured")
+	}
+
+	tables, err := s.getBitableTables(ctx, appID, appSecret, appToken)
+	if err != nil {
+		return nil, err
+	}
+	if sheetInfo.TableID != "" {
+		var selected []SheetTab
+		for _, table := range tables {
+			if table.SheetID == sheetInfo.TableID {
+				selected = append(selected, table)
+			}
+		}
+		if len(selected) == 0 {
+			return nil, fmt.Errorf("table %s not found in bitable %s", sheetInfo.TableID, appToken)
+		}
+		tables = selected
+	}
+
+	// 记录本次同步中出现过的记录，用于识别被删除的记录
+	seenRecords := make(map[string]struct{})
+	var created createdLinks
+
+	// 逐个同步数据表，单个数据表失败不影响其他数据表
+	var failedTables []string
+	for _, table := range tables {
+		if err = s.saveBitableTableData(ctx, appID, appSecret, appToken, table, mapping, seenRecords, &created); err != nil {
+			s.logger.WithContext(ctx).Error("feiShuService.saveBitableTableData error",
+				zap.String("sheet_id", appToken), zap.String("table_id", table.SheetID), zap.Error(err))
+			failedTables = append(failedTables, fmt.Sprintf("%s(%s): %v", table.Title, table.SheetID, err))
+		}
+	}
+	result := &model.SyncResult{Rows: len(seenRecords), Created: created.list()}
+	if len(failedTables) > 0 {
+		return result, fmt.Errorf("%d of %d tables failed: %s", len(failedTables), len(tables), strings.Join(failedTables, "; "))
+	}
+
+	// 只有完整读取了所有数据表，才能确定哪些记录已经被删除
+	if len(tables) > 0 {
+		if result.Deleted, err = s.cellDataRepo.MarkDeletedRecords(ctx, appToken, seenRecords); err != nil {
+			return result, err
+		}
+		if len(result.Deleted) > 0 {
+			s.logger.WithContext(ctx).Info("records removed from bitable",
+				zap.String("sheet_id", appToken), zap.Strings("links", result.Deleted))
+		}
+	}
+
+	return result, s.sheetInfoRepo.Updates(ctx, appToken, map[string]interface{}{
+		"latest_modify_time": lastModifyTime,
+	})
+}
+
+// getBitableTables 获取多维表格中的所有数据表，数据表作为工作表保存到 cell_data 的 tab 字段
+func (s *feiShuService) getBitableTables(ctx context.Context, appID string, appSecret string, appToken string) ([]SheetTab, error) {
+	var tables []SheetTab
+	pageToken := ""
+	for {
+		path := fmt.Sprintf("/open-apis/bitable/v1/apps/%s/tables?page_size=100&page_token=%s", appToken, url.QueryEscape(pageToken))
+
+		var response struct {
+			Code int    `json:"code"`
+			Msg  string `json:"msg"`
+			Data struct {
+				HasMore   bool   `json:"has_more"`
+				PageToken string `json:"page_token"`
+				Items     []struct {
+					TableID string `json:"table_id"`
+					Name    string `json:"name"`
+				} `json:"items"`
+			} `json:"data"`
+		}
+		if err := s.client.Do(ctx, appID, appSecret, http.MethodGet, path, nil, &response); err != nil {
+			return nil, err
+		}
+
+		for _, item := range response.Data.Items {
+			tables = append(tables, SheetTab{
+				SheetID: item.TableID,
+				Title:   item.Name,
+				Index:   len(tables),
+			})
+		}
+		if !response.Data.HasMore || response.Data.PageToken == "" {
+			return tables, nil
+		}
+		pageToken = response.Data.PageToken
+	}
+}
+
+// bitableRecord 多维表格中的一条记录
+type bitableRecord struct {
+	RecordID string                 `json:"record_id"`
+	Fields   map[string]interface{} `json:"fields"`
+}
+
+// saveBitableTableData 分页读取数据表中的记录，每读到一页就交给协程处理
+func (s *feiShuService) saveBitableTableData(ctx context.Context, appID string, appSecret string, appToken string, table SheetTab, mapping model.ColumnMapping, seenRecords map[string]struct{}, created *createdLinks) error {
+	var wg sync.WaitGroup
+	concurrencyLimit := 5
+	sem := make(chan struct{}, concurrencyLimit)
+	defer wg.Wait()
+
+	pageSize := s.readWindow()
+	if pageSize > bitablePageSize {
+		pageSize = bitablePageSize
+	}
+
+	sortNumber := 0
+	pageToken := ""
+	for {
+		path := fmt.Sprintf("/open-apis/bitable/v1/apps/%s/tables/%s/records?page_size=%d&page_token=%s",
+			appToken, table.SheetID, pageSize, url.QueryEscape(pageToken))
+
+		var response struct {
+			Code int    `json:"code"`
+			Msg  string `json:"msg"`
+			Data struct {
+				HasMore   bool            `json:"has_more"`
+				PageToken string          `json:"page_token"`
+				Items     []bitableRecord `json:"items"`
+			} `json:"data"`
+		}
+		// 翻页依赖上一页返回的 page_token，某一页失败时整个数据表视为失败
+		if err := s.client.Do(ctx, appID, appSecret, http.MethodGet, path, nil, &response); err != nil {
+			return err
+		}
+
+		for _, record := range response.Data.Items {
+			rowData, ok := bitableRow(record, mapping, s.timezone())
+			if !ok {
+				continue
+			}
+			rowData.SortNumber = sortNumber
+			sortNumber++
+			seenRecords[record.RecordID] = struct{}{}
+
+			sem <- struct{}{} // 将空结构体放入通道以限制并发
+			wg.Add(1)
+
+			go func(rowData sheetRow) {
+				defer wg.Done()
+				defer func() { <-sem }() // 从通道中移除空结构体以释放资源
+
+				if s.saveSheetRow(ctx, appID, appSecret, appToken, table, rowData) {
+					created.add(rowData.Link)
+				}
+			}(rowData)
+		}
+
+		if !response.Data.HasMore || response.Data.PageToken == "" {
+			return nil
+		}
+		pageToken = response.Data.PageToken
+	}
+}
+
+// bitableRow 按字段映射将记录转换为文章行，缺少标题或链接的记录被忽略
+func bitableRow(record bitableRecord, mapping model.ColumnMapping, loc *time.Location) (sheetRow, bool) {
+	var text, link string
+	if mapping.Link != "" {
+		text = strings.TrimSpace(bitableFieldText(record.Fields[mapping.Title]))
+		_, link = bitableFieldLink(record.Fields[mapping.Link])
+	} else {
+		text, link = bitableFieldLink(record.Fields[mapping.Title])
+	}
+	if record.RecordID == "" || text == "" || link == "" {
+		return sheetRow{}, false
+	}
+
+	rowData := sheetRow{
+		Text:     text,
+		Link:     link,
+		RecordID: record.RecordID,
+	}
+	if mapping.Date != "" {
+		rowData.Date = bitableFieldDate(record.Fields[mapping.Date], loc)
+	}
+	if len(mapping.Extra) > 0 {
+		rowData.Extra = make(map[string]string, len(mapping.Extra))
+		for name, field := range mapping.Extra {
+			rowData.Extra[name] = bitableFieldText(record.Fields[field])
+		}
+	}
+	return rowData, true
+}
+
+// bitableFieldText 将字段值转换为文本：多行文本按片段拼接，多选、人员等多值字段以逗号分隔
+func bitableFieldText(value interface{}) string {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, key := range []string{"text", "name", "link"} {
+			if text, ok := v[key].(string); ok {
+				return text
+			}
+		}
+		return ""
+	case []interface{}:
+		var segments []string
+		richText := true
+		for _, item := range v {
+			m, ok := item.(map[string]interface{})
+			if !ok || m["type"] == nil {
+				richText = false
+			}
+			if text := bitableFieldText(item); text != "" {
+				segments = append(segments, text)
+			}
+		}
+		if richText {
+			return strings.Join(segments, "")
+		}
+		return strings.Join(segments, ",")
+	default:
+		return cellText(value)
+	}
+}
+
+// bitableFieldLink 从超链接字段或带链接的文本字段中取出文本和链接
+func bitableFieldLink(value interface{}) (string, string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		link, _ := v["link"].(string)
+		text, _ := v["text"].(string)
+		if text == "" {
+			text = link
+		}
+		return strings.TrimSpace(text), link
+	case []interface{}, string:
+		return cellLink(v)
+	}
+	return "", ""
+}
+
+// bitableFieldDate 日期字段为毫秒时间戳，按 loc 时区格式化为日期，文本字段原样返回
+func bitableFieldDate(value interface{}, loc *time.Location) string {
+	if ms, ok := value.(float64); ok {
+		return time.UnixMilli(int64(ms)).In(loc).Format("2006-01-02")
+	}
+	return bitableFieldText(value)
+}
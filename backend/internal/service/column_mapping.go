@@ -0,0 +1,208 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"shengcai/internal/model"
+	"strconv"
+	"strings"
+)
+
+var columnLetterRegexp = regexp.MustCompile(`^[A-Za-z]{1,3}$`)
+
+// sheetColumns 列映射解析后的列序号，A 列为 0，-1 表示未配置
+type sheetColumns struct {
+	Title int
+	Link  int
+	Date  int
+	Extra map[string]int
+	// Abstract、Keyword 回写的列，同步时一并读取以判断单元格是否可以写入
+	Abstract int
+	Keyword  int
+	First    int
+	Last     int
+}
+
+// isColumnLetter 判断映射值是否为工作表范围内的列字母，columnCount 未知时不限制范围
+func isColumnLetter(value string, columnCount int) bool {
+	if !columnLetterRegexp.MatchString(value) {
+		return false
+	}
+	return columnCount <= 0 || columnIndex(value) < columnCount
+}
+
+// columnIndex 将列字母转换为列序号，如 A -> 0，AA -> 26
+func columnIndex(letter string) int {
+	index := 0
+	for _, r := range strings.ToUpper(letter) {
+		index = index*26 + int(r-'A'+1)
+	}
+	return index - 1
+}
+
+// columnLetter 将列序号转换为列字母，如 0 -> A，26 -> AA
+func columnLetter(index int) string {
+	letter := ""
+	for index >= 0 {
+		letter = string(rune('A'+index%26)) + letter
+		index = index/26 - 1
+	}
+	return letter
+}
+
+// mappingValues 返回列映射中配置的所有值
+func mappingValues(mapping model.ColumnMapping) []string {
+	values := []string{mapping.Title, mapping.Link, mapping.Date, mapping.Abstract, mapping.Keyword}
+	for _, value := range mapping.Extra {
+		values = append(values, value)
+	}
+	return values
+}
+
+// mappingNeedsHeader 判断列映射是否需要读取表头。
+// 只有单个列字母不会被当作表头名称，"URL"、"Tag" 这类多个字母的值需要先在表头中查找
+func mappingNeedsHeader(mapping model.ColumnMapping, columnCount int) bool {
+	for _, value := range mappingValues(mapping) {
+		if value == "" {
+			continue
+		}
+		if len(value) > 1 || !isColumnLetter(value, columnCount) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveColumnMapping 根据第一行的表头将列映射解析为列序号，header 从 A 列开始。
+// 表头名称优先，表头中没有时再按工作表范围内的列字母解析
+func resolveColumnMapping(mapping model.ColumnMapping, header []interface{}, columnCount int) (*sheetColumns, error) {
+	resolve := func(value string) (int, error) {
+		if value == "" {
+			return -1, nil
+		}
+		for index, cell := range header {
+			if strings.TrimSpace(cellText(cell)) == strings.TrimSpace(value) {
+				return index, nil
+			}
+		}
+		if isColumnLetter(value, columnCount) {
+			return columnIndex(value), nil
+		}
+		return -1, fmt.Errorf("column %q not found in header row", value)
+	}
+
+	columns := &sheetColumns{
+		Extra: make(map[string]int, len(mapping.Extra)),
+	}
+
+	var err error
+	if columns.Title, err = resolve(mapping.Title); err != nil {
+		return nil, err
+	}
+	if columns.Title < 0 {
+		return nil, fmt.Errorf("title column is not configured")
+	}
+	if columns.Link, err = resolve(mapping.Link); err != nil {
+		return nil, err
+	}
+	if columns.Date, err = resolve(mapping.Date); err != nil {
+		return nil, err
+	}
+	if columns.Abstract, err = resolve(mapping.Abstract); err != nil {
+		return nil, err
+	}
+	if columns.Keyword, err = resolve(mapping.Keyword); err != nil {
+		return nil, err
+	}
+	for name, value := range mapping.Extra {
+		if columns.Extra[name], err = resolve(value); err != nil {
+			return nil, err
+		}
+	}
+
+	columns.First, columns.Last = columns.Title, columns.Title
+	for _, index := range columns.indexes() {
+		if index < 0 {
+			continue
+		}
+		if index < columns.First {
+			columns.First = index
+		}
+		if index > columns.Last {
+			columns.Last = index
+		}
+	}
+	return columns, nil
+}
+
+func (c *sheetColumns) indexes() []int {
+	indexes := []int{c.Title, c.Link, c.Date, c.Abstract, c.Keyword}
+	for _, index := range c.Extra {
+		indexes = append(indexes, index)
+	}
+	return indexes
+}
+
+// cell 从读取到的行中取出指定列的单元格，row 从 First 列开始
+func (c *sheetColumns) cell(row []interface{}, index int) interface{} {
+	offset := index - c.First
+	if index < 0 || offset >= len(row) {
+		return nil
+	}
+	return row[offset]
+}
+
+// cellText 将单元格的值转换为文本，富文本单元格会拼接所有片段
+func cellText(cell interface{}) string {
+	switch v := cell.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case []interface{}:
+		var builder strings.Builder
+		for _, segment := range v {
+			builder.WriteString(cellText(segment))
+		}
+		return builder.String()
+	case map[string]interface{}:
+		if text, ok := v["text"].(string); ok {
+			return text
+		}
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// rowLink 从一行中取出标题和链接，配置了链接列时标题取标题列的文本
+func (c *sheetColumns) rowLink(row []interface{}) (string, string) {
+	if c.Link < 0 {
+		return cellLink(c.cell(row, c.Title))
+	}
+	_, link := cellLink(c.cell(row, c.Link))
+	return strings.TrimSpace(cellText(c.cell(row, c.Title))), link
+}
+
+// cellLink 从单元格中取出标题和链接，支持超链接单元格和纯文本链接
+func cellLink(cell interface{}) (string, string) {
+	switch v := cell.(type) {
+	case string:
+		if strings.HasPrefix(v, "http://") || strings.HasPrefix(v, "https://") {
+			return v, v
+		}
+	case []interface{}:
+		link := ""
+		for _, segment := range v {
+			if m, ok := segment.(map[string]interface{}); ok && link == "" {
+				link, _ = m["link"].(string)
+			}
+		}
+		return strings.TrimSpace(cellText(v)), link
+	}
+	return "", ""
+}
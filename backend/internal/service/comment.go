@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"shengcai/internal/model"
+	"strings"
+	"time"
+)
+
+// commentFileTypes 支持获取评论的文档类型
+var commentFileTypes = map[string]bool{
+	linkTypeDocx: true,
+	linkTypeDoc:  true,
+}
+
+// commentsEnabled 判断是否需要获取该类文档的评论
+func (s *feiShuService) commentsEnabled(document *Document) bool {
+	return s.conf.GetBool("feishu.comments") && document.Token != "" && commentFileTypes[document.LinkType]
+}
+
+// getDocumentComments 通过云文档评论接口分页获取文档的全部评论，每条回复保存为一行
+func (s *feiShuService) getDocumentComments(ctx context.Context, appID string, appSecret string, document *Document) ([]*model.Comment, error) {
+	var comments []*model.Comment
+	pageToken := ""
+	for {
+		path := fmt.Sprintf("/open-apis/drive/v1/files/%s/comments?file_type=%s&page_size=100&page_token=%s",
+			document.Token, document.LinkType, url.QueryEscape(pageToken))
+
+		var response struct {
+			Code int    `json:"code"`
+			Msg  string `json:"msg"`
+			Data struct {
+				HasMore   bool   `json:"has_more"`
+				PageToken string `json:"page_token"`
+				Items     []struct {
+					CommentID string `json:"comment_id"`
+					IsSolved  bool   `json:"is_solved"`
+					Quote     string `json:"quote"`
+					ReplyList struct {
+						Replies []struct {
+							ReplyID    string `json:"reply_id"`
+							UserID     string `json:"user_id"`
+							CreateTime int64  `json:"create_time"`
+							Content    struct {
+								Elements []commentElement `json:"elements"`
+							} `json:"content"`
+						} `json:"replies"`
+					} `json:"reply_list"`
+				} `json:"items"`
+			} `json:"data"`
+		}
+		if err := s.client.Do(ctx, appID, appSecret, http.MethodGet, path, nil, &response); err != nil {
+			return nil, err
+		}
+
+		for _, item := range response.Data.Items {
+			for _, reply := range item.ReplyList.Replies {
+				comment := &model.Comment{
+					CommentID: item.CommentID,
+					ReplyID:   reply.ReplyID,
+					UserID:    reply.UserID,
+					Content:   commentText(reply.Content.Elements),
+					Quote:     truncate(item.Quote, 1000),
+					Solved:    item.IsSolved,
+				}
+				if reply.CreateTime > 0 {
+					replyTime := time.Unix(reply.CreateTime, 0)
+					comment.ReplyTime = &replyTime
+				}
+				comments = append(comments, comment)
+			}
+		}
+		if !response.Data.HasMore || response.Data.PageToken == "" {
+			return comments, nil
+		}
+		pageToken = response.Data.PageToken
+	}
+}
+
+// commentElement 评论回复内容中的一个元素
+type commentElement struct {
+	Type    string `json:"type"`
+	TextRun *struct {
+		Text string `json:"text"`
+	} `json:"text_run"`
+	DocsLink *struct {
+		URL string `json:"url"`
+	} `json:"docs_link"`
+	Person *struct {
+		UserID string `json:"user_id"`
+	} `json:"person"`
+}
+
+// commentText 将回复内容拼接为纯文本，提及的用户以 @user_id 表示
+func commentText(elements []commentElement) string {
+	var builder strings.Builder
+	for _, element := range elements {
+		switch {
+		case element.TextRun != nil:
+			builder.WriteString(element.TextRun.Text)
+		case element.DocsLink != nil:
+			builder.WriteString(element.DocsLink.URL)
+		case element.Person != nil:
+			builder.WriteString("@" + element.Person.UserID)
+		}
+	}
+	return strings.TrimSpace(builder.String())
+}
+
+// commentDigest 生成供摘要使用的评论摘录，最多取 limit 条，每条截断到 200 字
+func commentDigest(comments []*model.Comment, limit int) string {
+	var lines []string
+	for _, comment := range comments {
+		if len(lines) >= limit {
+			break
+		}
+		if comment.Content == "" {
+			continue
+		}
+		lines = append(lines, "- "+truncate(comment.Content, 200))
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return "读者评论：\n" + strings.Join(lines, "\n")
+}
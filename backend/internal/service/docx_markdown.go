@@ -0,0 +1,349 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// 新版文档的块类型，见 https://open.feishu.cn/document/server-docs/docs/docs/docx-v1/document/block-data-structure
+const (
+	docxBlockPage           = 1
+	docxBlockText           = 2
+	docxBlockHeading1       = 3
+	docxBlockHeading9       = 11
+	docxBlockBullet         = 12
+	docxBlockOrdered        = 13
+	docxBlockCode           = 14
+	docxBlockQuote          = 15
+	docxBlockTodo           = 17
+	docxBlockDivider        = 22
+	docxBlockFile           = 23
+	docxBlockImage          = 27
+	docxBlockTable          = 31
+	docxBlockTableCell      = 32
+	docxBlockQuoteContainer = 34
+)
+
+// docxMediaScheme Markdown 中图片和附件的引用前缀，后面跟素材 token
+const docxMediaScheme = "feishu-media://"
+
+type docxTextElement struct {
+	TextRun *struct {
+		Content          string `json:"content"`
+		TextElementStyle struct {
+			Bold          bool `json:"bold"`
+			Italic        bool `json:"italic"`
+			Strikethrough bool `json:"strikethrough"`
+			InlineCode    bool `json:"inline_code"`
+			Link          *struct {
+				URL string `json:"url"`
+			} `json:"link"`
+		} `json:"text_element_style"`
+	} `json:"text_run"`
+	MentionDoc *struct {
+		Title string `json:"title"`
+		URL   string `json:"url"`
+	} `json:"mention_doc"`
+	Equation *struct {
+		Content string `json:"content"`
+	} `json:"equation"`
+}
+
+type docxText struct {
+	Elements []docxTextElement `json:"elements"`
+	Style    struct {
+		Done bool `json:"done"`
+	} `json:"style"`
+}
+
+type docxBlock struct {
+	BlockID   string    `json:"block_id"`
+	ParentID  string    `json:"parent_id"`
+	Children  []string  `json:"children"`
+	BlockType int       `json:"block_type"`
+	Text      *docxText `json:"text"`
+	Heading1  *docxText `json:"heading1"`
+	Heading2  *docxText `json:"heading2"`
+	Heading3  *docxText `json:"heading3"`
+	Heading4  *docxText `json:"heading4"`
+	Heading5  *docxText `json:"heading5"`
+	Heading6  *docxText `json:"heading6"`
+	Heading7  *docxText `json:"heading7"`
+	Heading8  *docxText `json:"heading8"`
+	Heading9  *docxText `json:"heading9"`
+	Bullet    *docxText `json:"bullet"`
+	Ordered   *docxText `json:"ordered"`
+	Code      *docxText `json:"code"`
+	Quote     *docxText `json:"quote"`
+	Todo      *docxText `json:"todo"`
+	Image     *struct {
+		Token string `json:"token"`
+	} `json:"image"`
+	File *struct {
+		Token string `json:"token"`
+		Name  string `json:"name"`
+	} `json:"file"`
+	Table *struct {
+		Cells    []string `json:"cells"`
+		Property struct {
+			ColumnSize int `json:"column_size"`
+		} `json:"property"`
+	} `json:"table"`
+}
+
+// textOf 返回文本类块中的文本内容
+func (b *docxBlock) textOf() *docxText {
+	for _, text := range []*docxText{
+		b.Text, b.Heading1, b.Heading2, b.Heading3, b.Heading4, b.Heading5, b.Heading6, b.Heading7, b.Heading8, b.Heading9,
+		b.Bullet, b.Ordered, b.Code, b.Quote, b.Todo,
+	} {
+		if text != nil {
+			return text
+		}
+	}
+	return nil
+}
+
+func (b *docxBlock) isListItem() bool {
+	return b.BlockType == docxBlockBullet || b.BlockType == docxBlockOrdered || b.BlockType == docxBlockTodo
+}
+
+// getDocxBlocks 分页获取文档的所有块
+func (s *feiShuService) getDocxBlocks(ctx context.Context, appID string, appSecret string, documentID string) ([]*docxBlock, error) {
+	var blocks []*docxBlock
+	pageToken := ""
+	for {
+		path := fmt.Sprintf("/open-apis/docx/v1/documents/%s/blocks?page_size=500&page_token=%s", documentID, pageToken)
+
+		var response struct {
+			Code int    `json:"code"`
+			Msg  string `json:"msg"`
+			Data struct {
+				Items     []*docxBlock `json:"items"`
+				HasMore   bool         `json:"has_more"`
+				PageToken string       `json:"page_token"`
+			} `json:"data"`
+		}
+		if err := s.client.Do(ctx, appID, appSecret, http.MethodGet, path, nil, &response); err != nil {
+			return nil, err
+		}
+
+		blocks = append(blocks, response.Data.Items...)
+		if !response.Data.HasMore || response.Data.PageToken == "" {
+			return blocks, nil
+		}
+		pageToken = response.Data.PageToken
+	}
+}
+
+// docxMarkdown 将文档块转换为 Markdown
+func docxMarkdown(blocks []*docxBlock) string {
+	c := &docxConverter{blocks: make(map[string]*docxBlock, len(blocks))}
+	var root *docxBlock
+	for _, block := range blocks {
+		c.blocks[block.BlockID] = block
+		if block.BlockType == docxBlockPage && root == nil {
+			root = block
+		}
+	}
+	if root == nil {
+		return ""
+	}
+	return c.renderChildren(root.Children, "")
+}
+
+type docxConverter struct {
+	blocks map[string]*docxBlock
+}
+
+// renderChildren 渲染一组同级块，相邻的列表项之间只换一行，其余块之间空一行
+func (c *docxConverter) renderChildren(ids []string, indent string) string {
+	var sb strings.Builder
+	prevList := false
+	ordered := 0
+	for _, id := range ids {
+		block, ok := c.blocks[id]
+		if !ok {
+			continue
+		}
+		if block.BlockType == docxBlockOrdered {
+			ordered++
+		} else {
+			ordered = 0
+		}
+
+		out := c.renderBlock(block, indent, ordered)
+		if out == "" {
+			continue
+		}
+		if sb.Len() > 0 {
+			if prevList && block.isListItem() {
+				sb.WriteString("\n")
+			} else {
+				sb.WriteString("\n\n")
+			}
+		}
+		sb.WriteString(out)
+		prevList = block.isListItem()
+	}
+	return sb.String()
+}
+
+func (c *docxConverter) renderBlock(block *docxBlock, indent string, ordered int) string {
+	switch {
+	case block.BlockType == docxBlockText:
+		return prefixLines(renderDocxText(block.Text), indent)
+	case block.BlockType >= docxBlockHeading1 && block.BlockType <= docxBlockHeading9:
+		level := block.BlockType - docxBlockHeading1 + 1
+		if level > 6 {
+			level = 6
+		}
+		return indent + strings.Repeat("#", level) + " " + renderDocxText(block.textOf())
+	case block.isListItem():
+		marker := "- "
+		switch block.BlockType {
+		case docxBlockOrdered:
+			marker = fmt.Sprintf("%d. ", ordered)
+		case docxBlockTodo:
+			marker = "- [ ] "
+			if block.Todo != nil && block.Todo.Style.Done {
+				marker = "- [x] "
+			}
+		}
+		out := indent + marker + renderDocxText(block.textOf())
+		if children := c.renderChildren(block.Children, indent+strings.Repeat(" ", len(marker))); children != "" {
+			out += "\n" + children
+		}
+		return out
+	case block.BlockType == docxBlockCode:
+		return prefixLines("```\n"+plainDocxText(block.Code)+"\n```", indent)
+	case block.BlockType == docxBlockQuote:
+		return prefixLines(renderDocxText(block.Quote), indent+"> ")
+	case block.BlockType == docxBlockQuoteContainer:
+		return prefixLines(c.renderChildren(block.Children, ""), indent+"> ")
+	case block.BlockType == docxBlockDivider:
+		return indent + "---"
+	case block.BlockType == docxBlockImage && block.Image != nil:
+		return indent + "![](" + docxMediaScheme + block.Image.Token + ")"
+	case block.BlockType == docxBlockFile && block.File != nil:
+		return indent + "[" + block.File.Name + "](" + docxMediaScheme + block.File.Token + ")"
+	case block.BlockType == docxBlockTable && block.Table != nil:
+		return prefixLines(c.renderTable(block), indent)
+	default:
+		// 分栏、高亮块等容器只输出其中的内容
+		return c.renderChildren(block.Children, indent)
+	}
+}
+
+// renderTable 将表格渲染为 Markdown 表格，第一行作为表头
+func (c *docxConverter) renderTable(block *docxBlock) string {
+	columns := block.Table.Property.ColumnSize
+	if columns <= 0 || len(block.Table.Cells) == 0 {
+		return ""
+	}
+
+	var rows []string
+	for start := 0; start < len(block.Table.Cells); start += columns {
+		end := start + columns
+		if end > len(block.Table.Cells) {
+			end = len(block.Table.Cells)
+		}
+
+		cells := make([]string, 0, columns)
+		for _, id := range block.Table.Cells[start:end] {
+			text := ""
+			if cell, ok := c.blocks[id]; ok {
+				text = c.renderChildren(cell.Children, "")
+			}
+			text = strings.ReplaceAll(text, "|", "\\|")
+			text = strings.ReplaceAll(text, "\n\n", "<br>")
+			text = strings.ReplaceAll(text, "\n", "<br>")
+			cells = append(cells, text)
+		}
+		rows = append(rows, "| "+strings.Join(cells, " | ")+" |")
+
+		if start == 0 {
+			rows = append(rows, "|"+strings.Repeat(" --- |", columns))
+		}
+	}
+	return strings.Join(rows, "\n")
+}
+
+// renderDocxText 渲染带样式的行内文本
+func renderDocxText(text *docxText) string {
+	if text == nil {
+		return ""
+	}
+
+	var sb strings.Builder
+	for _, element := range text.Elements {
+		switch {
+		case element.TextRun != nil:
+			content := element.TextRun.Content
+			style := element.TextRun.TextElementStyle
+			if strings.TrimSpace(content) == "" {
+				sb.WriteString(content)
+				continue
+			}
+			if style.InlineCode {
+				content = "`" + content + "`"
+			}
+			if style.Bold {
+				content = "**" + content + "**"
+			}
+			if style.Italic {
+				content = "*" + content + "*"
+			}
+			if style.Strikethrough {
+				content = "~~" + content + "~~"
+			}
+			if style.Link != nil && style.Link.URL != "" {
+				link, err := url.QueryUnescape(style.Link.URL)
+				if err != nil {
+					link = style.Link.URL
+				}
+				content = "[" + content + "](" + link + ")"
+			}
+			sb.WriteString(content)
+		case element.MentionDoc != nil:
+			link, err := url.QueryUnescape(element.MentionDoc.URL)
+			if err != nil {
+				link = element.MentionDoc.URL
+			}
+			sb.WriteString("[" + element.MentionDoc.Title + "](" + link + ")")
+		case element.Equation != nil:
+			sb.WriteString("$" + strings.TrimSpace(element.Equation.Content) + "$")
+		}
+	}
+	return sb.String()
+}
+
+// plainDocxText 返回不带样式的文本，用于代码块
+func plainDocxText(text *docxText) string {
+	if text == nil {
+		return ""
+	}
+
+	var sb strings.Builder
+	for _, element := range text.Elements {
+		if element.TextRun != nil {
+			sb.WriteString(element.TextRun.Content)
+		}
+	}
+	return sb.String()
+}
+
+// prefixLines 为每一行加上前缀，用于缩进和引用
+func prefixLines(text string, prefix string) string {
+	if text == "" || prefix == "" {
+		return text
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = prefix + line
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go.uber.org/zap"
+	v1 "shengcai/api/v1"
+	"shengcai/internal/model"
+	"shengcai/internal/repository"
+	"shengcai/pkg/feishu"
+	"sync"
+	"time"
+)
+
+// 触发同步的飞书事件类型
+var syncEventTypes = map[string]bool{
+	"drive.file.edit_v1":                   true,
+	"drive.file.bitable_record_changed_v1": true,
+	"drive.file.bitable_field_changed_v1":  true,
+}
+
+// EventRequest 飞书事件订阅推送的原始请求
+type EventRequest struct {
+	Timestamp string
+	Nonce     string
+	Signature string
+	Body      []byte
+}
+
+type EventService interface {
+	Handle(ctx context.Context, req *EventRequest) (*v1.FeiShuEventResponse, error)
+	ScheduleSync(ctx context.Context, sheetId string) error
+}
+
+func NewEventService(
+	service *Service,
+	shengCaiService ShengCaiService,
+	sheetInfoRepo repository.SheetInfoRepository,
+) EventService {
+	return &eventService{
+		Service:         service,
+		shengCaiService: shengCaiService,
+		sheetInfoRepo:   sheetInfoRepo,
+		timers:          make(map[string]*time.Timer),
+	}
+}
+
+type eventService struct {
+	*Service
+	shengCaiService ShengCaiService
+	sheetInfoRepo   repository.SheetInfoRepository
+
+	// 每个表格等待触发的同步，在防抖时间内的多次修改只同步一次
+	mu     sync.Mutex
+	timers map[string]*time.Timer
+}
+
+// feiShuEvent 事件推送的请求体，兼容 URL 校验请求和 2.0 版本的事件
+type feiShuEvent struct {
+	Encrypt   string `json:"encrypt"`
+	Challenge string `json:"challenge"`
+	Token     string `json:"token"`
+	Type      string `json:"type"`
+	Header    struct {
+		EventID   string `json:"event_id"`
+		EventType string `json:"event_type"`
+		Token     string `json:"token"`
+	} `json:"header"`
+	Event struct {
+		FileToken string `json:"file_token"`
+		FileType  string `json:"file_type"`
+	} `json:"event"`
+}
+
+// Handle 处理飞书事件推送：解密、校验 URL 校验请求和签名，表格修改事件会触发该表格的同步。
+// verification_token 和 encrypt_key 都未配置时无法验证推送来源，拒绝所有事件
+func (s *eventService) Handle(ctx context.Context, req *EventRequest) (*v1.FeiShuEventResponse, error) {
+	encryptKey := s.conf.GetString("feishu.event.encrypt_key")
+	verificationToken := s.conf.GetString("feishu.event.verification_token")
+	if encryptKey == "" && verificationToken == "" {
+		s.logger.WithContext(ctx).Warn("feishu.event is not configured, events are rejected")
+		return nil, v1.ErrServiceUnavailable
+	}
+
+	var event feiShuEvent
+	if err := json.Unmarshal(req.Body, &event); err != nil {
+		return nil, v1.ErrBadRequest
+	}
+	if event.Encrypt != "" {
+		if encryptKey == "" {
+			return nil, v1.ErrBadRequest
+		}
+		plain, err := feishu.DecryptEvent(encryptKey, event.Encrypt)
+		if err != nil {
+			s.logger.WithContext(ctx).Error("eventService.Handle DecryptEvent error", zap.Error(err))
+			return nil, v1.ErrBadRequest
+		}
+		event = feiShuEvent{}
+		if err = json.Unmarshal(plain, &event); err != nil {
+			return nil, v1.ErrBadRequest
+		}
+	}
+
+	token := event.Token
+	if token == "" {
+		token = event.Header.Token
+	}
+	if verificationToken != "" && token != verificationToken {
+		return nil, v1.ErrUnauthorized
+	}
+
+	// 配置订阅地址时飞书会发送 URL 校验请求，原样返回 challenge
+	if event.Type == "url_verification" {
+		return &v1.FeiShuEventResponse{Challenge: event.Challenge}, nil
+	}
+
+	// 配置了 Encrypt Key 时飞书会对事件推送签名
+	if encryptKey != "" && !feishu.VerifyEventSignature(req.Timestamp, req.Nonce, encryptKey, req.Body, req.Signature) {
+		return nil, v1.ErrUnauthorized
+	}
+
+	if syncEventTypes[event.Header.EventType] && event.Event.FileToken != "" {
+		s.logger.WithContext(ctx).Info("feishu file changed",
+			zap.String("event_id", event.Header.EventID), zap.String("event_type", event.Header.EventType),
+			zap.String("file_token", event.Event.FileToken))
+		if err := s.ScheduleSync(ctx, event.Event.FileToken); err != nil {
+			return nil, err
+		}
+	}
+
+	return &v1.FeiShuEventResponse{}, nil
+}
+
+// ScheduleSync 已登记且启用的表格在防抖时间后同步，其他文件直接忽略
+func (s *eventService) ScheduleSync(ctx context.Context, sheetId string) error {
+	sheetInfo, err := s.sheetInfoRepo.GetBySheetID(ctx, sheetId)
+	if errors.Is(err, v1.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if sheetInfo.Deleted || sheetInfo.Status != model.SheetStatusActive {
+		return nil
+	}
+
+	s.debounceSync(sheetId)
+	return nil
+}
+
+func (s *eventService) debounceSync(sheetId string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if timer, ok := s.timers[sheetId]; ok {
+		timer.Reset(s.debounce())
+		return
+	}
+	s.timers[sheetId] = time.AfterFunc(s.debounce(), func() {
+		s.runSync(sheetId)
+	})
+}
+
+func (s *eventService) runSync(sheetId string) {
+	s.mu.Lock()
+	delete(s.timers, sheetId)
+	s.mu.Unlock()
+
+	ctx := context.Background()
+	err := s.shengCaiService.SyncSheet(ctx, sheetId)
+	if errors.Is(err, v1.ErrSheetSyncing) {
+		// 正在进行的同步可能已经读过修改前的数据，结束后再同步一次
+		s.debounceSync(sheetId)
+		return
+	}
+	if err != nil {
+		s.logger.WithContext(ctx).Error("eventService.runSync SyncSheet error", zap.String("sheet_id", sheetId), zap.Error(err))
+	}
+}
+
+// debounce 事件触发同步的防抖时间，默认 5 秒
+func (s *eventService) debounce() time.Duration {
+	if seconds := s.conf.GetInt("feishu.event.debounce"); seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return 5 * time.Second
+}
@@ -1,17 +1,14 @@
 package service
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
-	lark "github.com/larksuite/oapi-sdk-go/v3"
-	larkdrive "github.com/larksuite/oapi-sdk-go/v3/service/drive/v1"
-	larksheets "github.com/larksuite/oapi-sdk-go/v3/service/sheets/v3"
-	"io/ioutil"
+	"go.uber.org/zap"
 	"net/http"
 	"shengcai/internal/model"
 	"shengcai/internal/repository"
+	"shengcai/pkg/dateparse"
+	"shengcai/pkg/feishu"
 	"strconv"
 	"strings"
 	"sync"
@@ -20,429 +17,511 @@ import (
 
 type FeiShuService interface {
 	GetTenantAccessToken(ctx context.Context, appID string, appSecret string) (string, error)
-	GetFirstSheetIDAndRowCount(ctx context.Context, appID string, appSecret string, spreadsheetToken string) (string, int, error)
-	SaveTableData(ctx context.Context, appID string, appSecret string, spreadsheetToken string) error
-	GetDocumentData(ctx context.Context, appID string, appSecret string, documentMetaData map[string]string) (string, error)
+	GetSheetTabs(ctx context.Context, appID string, appSecret string, spreadsheetToken string) ([]SheetTab, error)
+	SaveTableData(ctx context.Context, appID string, appSecret string, sheetInfo *model.SheetInfo) (*model.SyncResult, error)
+	SaveBitableData(ctx context.Context, appID string, appSecret string, sheetInfo *model.SheetInfo) (*model.SyncResult, error)
+	GetDocumentData(ctx context.Context, appID string, appSecret string, documentMetaData map[string]string) (*Document, error)
 	GetSheetLatestModifyTime(ctx context.Context, appID string, appSecret string, spreadsheetToken string) (string, error)
 }
 
 func NewFeiShuService(
 	service *Service,
+	client *feishu.Client,
 	sheetInfoRepo repository.SheetInfoRepository,
 	cellDataRepo repository.CellDataRepository,
+	commentRepo repository.CommentRepository,
+	mediaService MediaService,
 ) FeiShuService {
-	return &feiShuService{
+	s := &feiShuService{
 		Service:       service,
+		client:        client,
 		sheetInfoRepo: sheetInfoRepo,
 		cellDataRepo:  cellDataRepo,
+		commentRepo:   commentRepo,
+		mediaService:  mediaService,
 	}
+	s.fetchers = s.documentFetchers()
+	return s
 }
 
 type feiShuService struct {
 	*Service
+	client        *feishu.Client
 	sheetInfoRepo repository.SheetInfoRepository
 	cellDataRepo  repository.CellDataRepository
+	commentRepo   repository.CommentRepository
+	// 下载文档中的图片和附件
+	mediaService MediaService
+
+	// 按链接类型获取文档内容
+	fetchers map[string]documentFetcher
+	// 知识库节点 token 到实际文档的缓存
+	wikiNodes sync.Map
 }
 
+// GetTenantAccessToken 从凭证缓存中获取 tenant_access_token，同一应用的所有调用共享一个凭证
 func (s *feiShuService) GetTenantAccessToken(ctx context.Context, appID string, appSecret string) (string, error) {
-	url := "https://open.feishu.cn/open-apis/auth/v3/tenant_access_token/internal"
+	return s.client.TenantAccessToken(ctx, appID, appSecret)
+}
 
-	// 请求体数据
-	data := map[string]string{
-		"app_id":     appID,
-		"app_secret": appSecret,
-	}
+// SheetTab 电子表格中的一个工作表（标签页）
+type SheetTab struct {
+	SheetID     string
+	Title       string
+	Index       int
+	RowCount    int
+	ColumnCount int
+}
 
-	// 将数据编码为 JSON 格式
-	jsonData, err := json.Marshal(data)
-	if err != nil {
-		return "", fmt.Errorf("error encoding JSON: %w", err)
-	}
+func (s *feiShuService) GetSheetTabs(ctx context.Context, appID string, appSecret string, spreadsheetToken string) ([]SheetTab, error) {
+	path := fmt.Sprintf("/open-apis/sheets/v3/spreadsheets/%s/sheets/query", spreadsheetToken)
 
-	// 创建 HTTP 请求
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return "", fmt.Errorf("error creating request: %w", err)
+	var response struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+		Data struct {
+			Sheets []struct {
+				SheetID        string `json:"sheet_id"`
+				Title          string `json:"title"`
+				Index          int    `json:"index"`
+				ResourceType   string `json:"resource_type"`
+				GridProperties *struct {
+					RowCount    int `json:"row_count"`
+					ColumnCount int `json:"column_count"`
+				} `json:"grid_properties"`
+			} `json:"sheets"`
+		} `json:"data"`
 	}
 
-	client := &http.Client{
-		Timeout: 10 * time.Second,
+	if err := s.client.Do(ctx, appID, appSecret, http.MethodGet, path, nil, &response); err != nil {
+		return nil, err
 	}
 
-	// 设置请求头
-	req.Header.Set("Content-Type", "application/json")
+	var tabs []SheetTab
+	for _, sheet := range response.Data.Sheets {
+		// 内嵌的多维表格等其他类型的工作表无法通过 values 接口读取
+		if sheet.ResourceType != "" && sheet.ResourceType != "sheet" {
+			continue
+		}
+		if sheet.SheetID == "" || sheet.GridProperties == nil {
+			continue
+		}
 
-	// 发送请求
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("error making request: %w", err)
+		tab := SheetTab{
+			SheetID:     sheet.SheetID,
+			Title:       sheet.Title,
+			Index:       sheet.Index,
+			RowCount:    sheet.GridProperties.RowCount,
+			ColumnCount: sheet.GridProperties.ColumnCount,
+		}
+		tabs = append(tabs, tab)
 	}
-	defer resp.Body.Close()
 
-	// 检查响应状态码
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("received non-200 response: %s", resp.Status)
-	}
+	return tabs, nil
+}
 
-	// 读取响应体
-	body, err := ioutil.ReadAll(resp.Body)
+func (s *feiShuService) SaveTableData(ctx context.Context, appID string, appSecret string, sheetInfo *model.SheetInfo) (*model.SyncResult, error) {
+	spreadsheetToken := sheetInfo.SheetID
+	lastModifyTime, err := s.GetSheetLatestModifyTime(ctx, appID, appSecret, spreadsheetToken)
 	if err != nil {
-		return "", fmt.Errorf("error reading response body: %w", err)
+		return nil, err
 	}
 
-	// 定义响应体的结构
-	var response struct {
-		Code              int    `json:"code"`
-		Msg               string `json:"msg"`
-		TenantAccessToken string `json:"tenant_access_token"`
-		Expire            int    `json:"expire"`
+	updateLog := s.formatUpdateLog(ctx, lastModifyTime)
+	if err = s.sheetInfoRepo.Updates(ctx, spreadsheetToken, map[string]interface{}{
+		"update_log": updateLog,
+	}); err != nil {
+		return nil, err
 	}
 
-	// 解析 JSON 响应体
-	err = json.Unmarshal(body, &response)
+	tabs, err := s.GetSheetTabs(ctx, appID, appSecret, spreadsheetToken)
 	if err != nil {
-		return "", fmt.Errorf("error decoding JSON: %w", err)
-	}
-
-	// 判断请求是否成功
-	if response.Code == 0 && response.Msg == "ok" {
-		return response.TenantAccessToken, nil
-	} else {
-		return "", fmt.Errorf("request failed with code: %d, message: %s", response.Code, response.Msg)
+		return nil, err
 	}
-}
-
-func (s *feiShuService) GetFirstSheetIDAndRowCount(ctx context.Context, appID string, appSecret string, spreadsheetToken string) (string, int, error) {
-	// 创建 Client
-	client := lark.NewClient(appID, appSecret)
 
-	// 创建请求对象
-	req := larksheets.NewQuerySpreadsheetSheetReqBuilder().
-		SpreadsheetToken(spreadsheetToken).
-		Build()
-
-	// 发起请求
-	resp, err := client.Sheets.SpreadsheetSheet.Query(ctx, req)
-	// 处理错误
+	revision, err := s.getSheetRevision(ctx, appID, appSecret, spreadsheetToken, tabs)
 	if err != nil {
-		return "", 0, err
-	}
-	// 服务端错误处理
-	if !resp.Success() {
-		return "", 0, fmt.Errorf("request failed with code: %d, message: %s", resp.Code, resp.Msg)
-		//return "", 0, fmt.Errorf("code:"+strconv.Itoa(resp.Code), "msg:"+resp.Msg, "request_id:"+resp.RequestId())
+		return nil, err
 	}
 
-	sheetID := resp.Data.Sheets[0].SheetId
-	rowCount := resp.Data.Sheets[0].GridProperties.RowCount
-
-	return *sheetID, *rowCount, nil
-}
-func (s *feiShuService) SaveTableData(ctx context.Context, appID string, appSecret string, spreadsheetToken string) error {
-	lastModifyTime, err := s.GetSheetLatestModifyTime(ctx, appID, appSecret, spreadsheetToken)
-	if err != nil {
-		return err
+	// 最后修改时间和版本号都没有变化时跳过本次同步，节省飞书接口配额
+	if sheetInfo.LatestModifyTime != "" && sheetInfo.LatestModifyTime == lastModifyTime &&
+		sheetInfo.Revision != 0 && sheetInfo.Revision == revision {
+		s.logger.WithContext(ctx).Info("spreadsheet unchanged, skip sync",
+			zap.String("sheet_id", spreadsheetToken), zap.String("latest_modify_time", lastModifyTime), zap.Int("revision", revision))
+		return &model.SyncResult{Skipped: true}, nil
 	}
 
-	updateLog := ""
-	// 将时间戳字符串转换为 int64 类型的时间戳
-	timestamp, err := strconv.ParseInt(lastModifyTime, 10, 64)
-	if err == nil {
-		// 将时间戳转换为 time.Time 类型
-		t := time.Unix(timestamp, 0)
+	mapping := sheetInfo.GetColumnMapping()
 
-		// 格式化时间为 yyyy-mm-dd hh:mm:ss
-		updateLog = t.Format("2006-01-02 15:04:05")
-	} else {
-		fmt.Println("Error parsing timestamp:", err)
+	// 记录本次同步中出现过的链接，用于识别被删除的行
+	seenLinks := make(map[string]struct{})
+	// 开启回写时记录每篇文章所在的行和回写列的当前值
+	var writeBackCells map[string]writeBackCell
+	if writeBackEnabled(sheetInfo) {
+		writeBackCells = make(map[string]writeBackCell)
 	}
+	tabColumns := make(map[string]*sheetColumns, len(tabs))
+	var created createdLinks
 
-	err = s.tm.Transaction(ctx, func(ctx context.Context) error {
-		if err = s.sheetInfoRepo.Create(ctx, &model.SheetInfo{
-			SheetID:      spreadsheetToken,
-			SheetName:    "",
-			UpdateLog:    updateLog,
-			RuntimeState: "",
-		}); err != nil {
-			return err
+	// 逐个同步工作表，单个工作表失败不影响其他工作表
+	var failedTabs []string
+	for _, tab := range tabs {
+		s.logger.WithContext(ctx).Debug("feiShuService.SaveTableData sync tab",
+			zap.String("sheet_id", spreadsheetToken), zap.String("tab_id", tab.SheetID), zap.Int("row_count", tab.RowCount))
+
+		columns, err := s.saveSheetTabData(ctx, appID, appSecret, spreadsheetToken, tab, mapping, seenLinks, writeBackCells, &created)
+		tabColumns[tab.SheetID] = columns
+		if err != nil {
+			s.logger.WithContext(ctx).Error("feiShuService.saveSheetTabData error",
+				zap.String("sheet_id", spreadsheetToken), zap.String("tab_id", tab.SheetID), zap.Error(err))
+			failedTabs = append(failedTabs, fmt.Sprintf("%s(%s): %v", tab.Title, tab.SheetID, err))
 		}
-		return nil
-	})
-	if err != nil {
-		return err
 	}
-
-	sheetID, rowCount, err := s.GetFirstSheetIDAndRowCount(ctx, appID, appSecret, spreadsheetToken)
-	if err != nil {
-		return err
+	result := &model.SyncResult{Rows: len(seenLinks), Created: created.list()}
+	if len(failedTabs) > 0 {
+		return result, fmt.Errorf("%d of %d tabs failed: %s", len(failedTabs), len(tabs), strings.Join(failedTabs, "; "))
 	}
-	fmt.Println("sheetID ==>", sheetID)
-	fmt.Println("rowCount ==>", rowCount)
 
-	tenantAccessToken, err := s.GetTenantAccessToken(ctx, appID, appSecret)
-	if err != nil {
-		return err
+	// 只有完整读取了所有工作表，才能确定哪些文章已经从表格中删除
+	if len(tabs) > 0 {
+		if result.Deleted, err = s.cellDataRepo.MarkDeleted(ctx, spreadsheetToken, seenLinks); err != nil {
+			return result, err
+		}
+		if len(result.Deleted) > 0 {
+			s.logger.WithContext(ctx).Info("rows removed from spreadsheet",
+				zap.String("sheet_id", spreadsheetToken), zap.Strings("links", result.Deleted))
+		}
 	}
-	fmt.Println("tenantAccessToken ==>", tenantAccessToken)
 
-	var tableData []map[string]string
+	if writeBackCells != nil {
+		if result.Written, err = s.writeBack(ctx, appID, appSecret, spreadsheetToken, tabColumns, writeBackCells); err != nil {
+			return result, err
+		}
+		// 回写会修改表格，重新获取修改时间和版本号，避免下次同步把自己的写入当作表格变化
+		if result.Written > 0 {
+			if lastModifyTime, err = s.GetSheetLatestModifyTime(ctx, appID, appSecret, spreadsheetToken); err != nil {
+				return result, err
+			}
+			if revision, err = s.getSheetRevision(ctx, appID, appSecret, spreadsheetToken, tabs); err != nil {
+				return result, err
+			}
+		}
+	}
 
-	// 生成请求URL，范围从B{startRow}到D{endRow}
-	url := fmt.Sprintf("https://open.feishu.cn/open-apis/sheets/v2/spreadsheets/%s/values/%s!B%d:D%d?dateTimeRenderOption=FormattedString", spreadsheetToken, sheetID, 2, rowCount)
+	// 只有全部工作表同步成功后才记录本次的修改时间和版本号，失败的同步会在下次重试
+	return result, s.sheetInfoRepo.Updates(ctx, spreadsheetToken, map[string]interface{}{
+		"latest_modify_time": lastModifyTime,
+		"revision":           revision,
+	})
+}
 
-	// 创建 HTTP 请求
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+// getSheetRevision 读取一个单元格来获取电子表格当前的版本号
+func (s *feiShuService) getSheetRevision(ctx context.Context, appID string, appSecret string, spreadsheetToken string, tabs []SheetTab) (int, error) {
+	if len(tabs) == 0 {
+		return 0, nil
+	}
+	_, revision, err := s.getSheetValues(ctx, appID, appSecret, spreadsheetToken, fmt.Sprintf("%s!A1:A1", tabs[0].SheetID))
 	if err != nil {
-		return fmt.Errorf("error creating request: %w", err)
+		return 0, err
 	}
+	return revision, nil
+}
 
-	client := &http.Client{
-		Timeout: 10 * time.Second,
+// saveSheetTabData 同步一个工作表，返回解析后的列映射供回写使用
+func (s *feiShuService) saveSheetTabData(ctx context.Context, appID string, appSecret string, spreadsheetToken string, tab SheetTab, mapping model.ColumnMapping, seenLinks map[string]struct{}, writeBackCells map[string]writeBackCell, created *createdLinks) (*sheetColumns, error) {
+	if tab.RowCount < 2 {
+		return nil, nil
 	}
 
-	// 设置请求头
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+tenantAccessToken)
+	// 可能是表头名称的列需要先读取第一行
+	var header []interface{}
+	if mappingNeedsHeader(mapping, tab.ColumnCount) && tab.ColumnCount > 0 {
+		values, _, err := s.getSheetValues(ctx, appID, appSecret, spreadsheetToken,
+			fmt.Sprintf("%s!A1:%s1", tab.SheetID, columnLetter(tab.ColumnCount-1)))
+		if err != nil {
+			return nil, err
+		}
+		if len(values) > 0 {
+			header = values[0]
+		}
+	}
 
-	// 发送请求
-	resp, err := client.Do(req)
+	columns, err := resolveColumnMapping(mapping, header, tab.ColumnCount)
 	if err != nil {
-		return fmt.Errorf("error making request: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	// 检查响应状态码
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("received non-200 response: %s", resp.Status)
-	}
+	var wg sync.WaitGroup
+	concurrencyLimit := 5
+	sem := make(chan struct{}, concurrencyLimit)
 
-	// 读取响应体
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("error reading response body: %w", err)
-	}
+	// 按行分批读取，每读到一批就交给协程处理，避免大表格一次读取超时或占用过多内存
+	window := s.readWindow()
+	var failedWindows []string
+	for startRow := 2; startRow <= tab.RowCount; startRow += window {
+		endRow := startRow + window - 1
+		if endRow > tab.RowCount {
+			endRow = tab.RowCount
+		}
 
-	// 解析 JSON 响应体
-	var response struct {
-		Code int    `json:"code"`
-		Msg  string `json:"msg"`
-		Data struct {
-			Revision         int    `json:"revision"`
-			SpreadsheetToken string `json:"spreadsheetToken"`
-			ValueRange       struct {
-				MajorDimension string          `json:"majorDimension"`
-				Range          string          `json:"range"`
-				Revision       int             `json:"revision"`
-				Values         [][]interface{} `json:"values"`
-			} `json:"valueRange"`
-		} `json:"data"`
-	}
+		// 读取范围从映射中最左侧的列到最右侧的列
+		valueRange := fmt.Sprintf("%s!%s%d:%s%d", tab.SheetID, columnLetter(columns.First), startRow, columnLetter(columns.Last), endRow)
+		// 飞书客户端会对这一批的临时性失败单独重试
+		values, _, err := s.getSheetValues(ctx, appID, appSecret, spreadsheetToken, valueRange)
+		if err != nil {
+			s.logger.WithContext(ctx).Error("feiShuService.getSheetValues error",
+				zap.String("sheet_id", spreadsheetToken), zap.String("range", valueRange), zap.Error(err))
+			failedWindows = append(failedWindows, fmt.Sprintf("%d-%d", startRow, endRow))
+			continue
+		}
 
-	err = json.Unmarshal(body, &response)
-	if err != nil {
-		return fmt.Errorf("error decoding JSON: %w", err)
-	}
-
-	// 判断请求是否成功
-	if response.Code == 0 && response.Msg == "success" {
-		for sortNumber, row := range response.Data.ValueRange.Values {
-			rowData := make(map[string]string)
-			for index, cell := range row {
-				switch v := cell.(type) {
-				case []interface{}:
-					if index == 0 && len(v) > 0 {
-						text, textOk := v[0].(map[string]interface{})["text"].(string)
-						link, linkOk := v[0].(map[string]interface{})["link"].(string)
-
-						// 确保 text 和 link 不为空字符串
-						if textOk && text != "" && linkOk && link != "" {
-							rowData["text"] = text
-							rowData["link"] = link
-							rowData["sortNumber"] = strconv.Itoa(sortNumber)
-						}
-					}
-				case string:
-					if index == 2 {
-						// 处理单元格内容为字符串的情况
-						rowData["date"] = v
-					}
-				}
+		for offset, row := range values {
+			text, link := columns.rowLink(row)
+			// 确保 text 和 link 不为空字符串
+			if text == "" || link == "" {
+				continue
 			}
-			// 检查 text 和 link 是否都存在
-			if _, textExists := rowData["text"]; textExists {
-				if _, linkExists := rowData["link"]; linkExists {
-					// 检查 rowData 是否包含 date，如果没有则默认设置为空字符串
-					if _, dateExists := rowData["date"]; !dateExists {
-						rowData["date"] = ""
-					}
-					tableData = append(tableData, rowData)
-				}
-			}
-		}
 
-		// 输出 tableData 的内容
-		fmt.Println("tableData[0] ==>", tableData[0])
-		fmt.Println("tableData[len(tableData)-1] ==>", tableData[len(tableData)-1])
+			seenLinks[link] = struct{}{}
+			columns.collectWriteBackCell(writeBackCells, tab, link, row, startRow+offset)
 
-		var wg sync.WaitGroup
-		concurrencyLimit := 5
-		sem := make(chan struct{}, concurrencyLimit)
+			rowData := sheetRow{
+				Text:       text,
+				Link:       link,
+				Date:       cellText(columns.cell(row, columns.Date)),
+				SortNumber: startRow - 2 + offset,
+			}
+			if len(columns.Extra) > 0 {
+				rowData.Extra = make(map[string]string, len(columns.Extra))
+				for name, index := range columns.Extra {
+					rowData.Extra[name] = cellText(columns.cell(row, index))
+				}
+			}
 
-		for _, rowData := range tableData {
 			sem <- struct{}{} // 将空结构体放入通道以限制并发
 			wg.Add(1)
 
-			go func(rowData map[string]string) {
+			go func(rowData sheetRow) {
 				defer wg.Done()
 				defer func() { <-sem }() // 从通道中移除空结构体以释放资源
 
-				// 调用 GetDocumentData 方法
-				content, err := s.GetDocumentData(ctx, appID, appSecret, rowData)
-				if err != nil {
-					content = fmt.Sprintf("Error processing link %s: %v\n", rowData["link"], err)
+				if s.saveSheetRow(ctx, appID, appSecret, spreadsheetToken, tab, rowData) {
+					created.add(rowData.Link)
 				}
-				fmt.Println("text ==>", rowData["text"])
-				fmt.Println("link ==>", rowData["link"])
-				fmt.Println("date ==>", rowData["date"])
-				fmt.Println("sortNumber ==>", rowData["sortNumber"])
-
-				sortNumber, err := strconv.Atoi(rowData["sortNumber"])
-				if err != nil {
-					fmt.Printf("error converting index to int: %v", err)
-					sortNumber = 9999
-				}
-
-				err = s.tm.Transaction(ctx, func(ctx context.Context) error {
-					if err = s.cellDataRepo.Create(ctx, &model.CellData{
-						Title:        rowData["text"],
-						Link:         rowData["link"],
-						Content:      content,
-						Abstract:     "",
-						RuntimeState: "",
-						SheetID:      spreadsheetToken,
-						ReleaseDate:  rowData["date"],
-						Keyword:      "",
-						SortNumber:   sortNumber,
-					}); err != nil {
-						return err
-					}
-					return nil
-				})
-				if err != nil {
-					fmt.Printf("cell_data write failed: Title='%s', Link='%s', ReleaseDate='%s', Error: %v\n",
-						rowData["text"], rowData["link"], rowData["date"], err)
-				}
-
-				// 每次调用后 sleep 1 秒
-				time.Sleep(1000 * time.Millisecond)
 			}(rowData)
 		}
+	}
 
-		// 等待所有协程完成
-		wg.Wait()
+	// 等待所有协程完成
+	wg.Wait()
 
-		return nil
-	} else {
-		return fmt.Errorf("request failed with code: %d, message: %s", response.Code, response.Msg)
+	if len(failedWindows) > 0 {
+		return columns, fmt.Errorf("failed to read rows %s", strings.Join(failedWindows, ", "))
 	}
+	return columns, nil
 }
 
-func (s *feiShuService) GetDocumentData(ctx context.Context, appID string, appSecret string, documentMetaData map[string]string) (string, error) {
-	//text := documentMetaData["text"]
-	link := documentMetaData["link"]
-	documentID := strings.Split(link, "/")[len(strings.Split(link, "/"))-1]
-	//date := documentMetaData["date"]
-
-	tenantAccessToken, err := s.GetTenantAccessToken(ctx, appID, appSecret)
+// saveSheetRow 获取一行对应的文档内容并写入 cell_data，返回是否新增了文章
+func (s *feiShuService) saveSheetRow(ctx context.Context, appID string, appSecret string, spreadsheetToken string, tab SheetTab, rowData sheetRow) bool {
+	// 调用 GetDocumentData 方法
+	document, err := s.GetDocumentData(ctx, appID, appSecret, map[string]string{
+		"text": rowData.Text,
+		"link": rowData.Link,
+		"date": rowData.Date,
+	})
+	content := document.Content
 	if err != nil {
-		return "", err
+		content = fmt.Sprintf("Error processing link %s: %v\n", rowData.Link, err)
+	}
+	s.logger.WithContext(ctx).Debug("feiShuService.saveSheetRow",
+		zap.String("title", rowData.Text), zap.String("link", rowData.Link),
+		zap.String("date", rowData.Date), zap.Int("sort_number", rowData.SortNumber))
+
+	// 评论获取失败时保留已保存的评论，不影响文章本身的同步
+	var comments []*model.Comment
+	commentsFetched := false
+	if err == nil && s.commentsEnabled(document) {
+		var commentErr error
+		if comments, commentErr = s.getDocumentComments(ctx, appID, appSecret, document); commentErr != nil {
+			s.logger.WithContext(ctx).Error("feiShuService.getDocumentComments error", zap.String("link", rowData.Link), zap.Error(commentErr))
+		} else {
+			commentsFetched = true
+		}
 	}
 
-	// 生成请求URL，范围从B{startRow}到D{endRow}
-	url := fmt.Sprintf("https://open.feishu.cn/open-apis/docx/v1/documents/%s/raw_content", documentID)
-
-	// 创建 HTTP 请求
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return "", fmt.Errorf("error creating request: %w", err)
+	cellData := &model.CellData{
+		Title:           rowData.Text,
+		Link:            rowData.Link,
+		LinkType:        document.LinkType,
+		Content:         content,
+		ContentMarkdown: document.Markdown,
+		Abstract:        "",
+		RuntimeState:    "",
+		SheetID:         spreadsheetToken,
+		TabID:           tab.SheetID,
+		TabTitle:        tab.Title,
+		TabIndex:        tab.Index,
+		ReleaseDate:     rowData.Date,
+		PublishedAt:     s.publishedAt(rowData.Date),
+		Keyword:         "",
+		SortNumber:      rowData.SortNumber,
+		Extra:           rowData.Extra,
+		RecordID:        rowData.RecordID,
+	}
+	if err == nil {
+		// 按素材地址改写前的纯文本判断正文是否变化
+		cellData.ContentHash = repository.ContentHash(document.Text)
+	}
+	if commentsFetched && s.conf.GetBool("ai.comment_digest") {
+		cellData.CommentDigest = commentDigest(comments, 20)
 	}
 
-	client := &http.Client{
-		Timeout: 10 * time.Second,
+	created := false
+	err = s.tm.Transaction(ctx, func(ctx context.Context) error {
+		if created, err = s.cellDataRepo.Create(ctx, cellData); err != nil {
+			return err
+		}
+		if commentsFetched {
+			return s.commentRepo.Replace(ctx, cellData.ID, comments)
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.WithContext(ctx).Error("feiShuService.saveSheetRow write cell_data error",
+			zap.String("title", rowData.Text), zap.String("link", rowData.Link),
+			zap.String("date", rowData.Date), zap.Error(err))
+	} else if cellData.AIState == model.AIStatePending {
+		// 摘要在事务提交后生成，失败的文章保持 pending，由 SummarizePending 补齐
+		if summarizeErr := s.cellDataRepo.Summarize(ctx, cellData.ID, cellData.CommentDigest); summarizeErr != nil {
+			s.logger.WithContext(ctx).Error("feiShuService.Summarize error", zap.String("link", rowData.Link), zap.Error(summarizeErr))
+		}
 	}
 
-	// 设置请求头
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+tenantAccessToken)
+	// 每次调用后 sleep 1 秒
+	time.Sleep(1000 * time.Millisecond)
+	return created && err == nil
+}
 
-	// 发送请求
-	resp, err := client.Do(req)
+// publishedAt 按配置的时区解析发布日期，无法识别的日期只保留原始文本
+func (s *feiShuService) publishedAt(releaseDate string) *time.Time {
+	if releaseDate == "" {
+		return nil
+	}
+	t, err := dateparse.Parse(releaseDate, s.timezone(), time.Now())
 	if err != nil {
-		return "", fmt.Errorf("error making request: %w", err)
+		s.logger.Warn("unrecognized release date", zap.String("release_date", releaseDate))
+		return nil
 	}
-	defer resp.Body.Close()
+	return &t
+}
 
-	// 检查响应状态码
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("received non-200 response: %s", resp.Status)
+// readWindow 每次读取的行数，默认 500 行
+func (s *feiShuService) readWindow() int {
+	if window := s.conf.GetInt("feishu.read_window"); window > 0 {
+		return window
 	}
+	return 500
+}
 
-	// 读取响应体
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("error reading response body: %w", err)
-	}
+// createdLinks 同步过程中新增的文章链接，由多个协程并发写入
+type createdLinks struct {
+	mu    sync.Mutex
+	links []string
+}
+
+func (c *createdLinks) add(link string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.links = append(c.links, link)
+}
+
+func (c *createdLinks) list() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.links...)
+}
+
+// sheetRow 电子表格中的一行文章数据
+type sheetRow struct {
+	Text       string
+	Link       string
+	Date       string
+	SortNumber int
+	Extra      map[string]string
+	// RecordID 多维表格的记录 ID，电子表格为空
+	RecordID string
+}
+
+// getSheetValues 读取电子表格指定范围的值，同时返回表格的版本号
+func (s *feiShuService) getSheetValues(ctx context.Context, appID string, appSecret string, spreadsheetToken string, valueRange string) ([][]interface{}, int, error) {
+	path := fmt.Sprintf("/open-apis/sheets/v2/spreadsheets/%s/values/%s?dateTimeRenderOption=FormattedString", spreadsheetToken, valueRange)
 
 	// 解析 JSON 响应体
 	var response struct {
 		Code int    `json:"code"`
 		Msg  string `json:"msg"`
 		Data struct {
-			Content string `json:"content"`
+			Revision         int    `json:"revision"`
+			SpreadsheetToken string `json:"spreadsheetToken"`
+			ValueRange       struct {
+				MajorDimension string          `json:"majorDimension"`
+				Range          string          `json:"range"`
+				Revision       int             `json:"revision"`
+				Values         [][]interface{} `json:"values"`
+			} `json:"valueRange"`
 		} `json:"data"`
 	}
 
-	err = json.Unmarshal(body, &response)
-	if err != nil {
-		return "", fmt.Errorf("error decoding JSON: %w", err)
+	if err := s.client.Do(ctx, appID, appSecret, http.MethodGet, path, nil, &response); err != nil {
+		return nil, 0, err
 	}
 
-	// 判断请求是否成功
-	if response.Code == 0 && response.Msg == "success" {
-		return response.Data.Content, nil
-	} else {
-		return "", fmt.Errorf("request failed with code: %d, message: %s", response.Code, response.Msg)
-	}
+	return response.Data.ValueRange.Values, response.Data.Revision, nil
 }
 
 func (s *feiShuService) GetSheetLatestModifyTime(ctx context.Context, appID string, appSecret string, spreadsheetToken string) (string, error) {
-	// 创建 Client
-	client := lark.NewClient(appID, appSecret)
-	// 创建请求对象
-	req := larkdrive.NewBatchQueryMetaReqBuilder().
-		MetaRequest(larkdrive.NewMetaRequestBuilder().
-			RequestDocs([]*larkdrive.RequestDoc{
-				larkdrive.NewRequestDocBuilder().
-					DocToken(spreadsheetToken).
-					DocType(`sheet`).
-					Build(),
-			}).
-			WithUrl(false).
-			Build()).
-		Build()
-
-	// 发起请求
-	resp, err := client.Drive.Meta.BatchQuery(ctx, req)
-
-	// 处理错误
-	if err != nil {
-		return "", err
+	return s.getLatestModifyTime(ctx, appID, appSecret, spreadsheetToken, "sheet")
+}
+
+// getLatestModifyTime 通过云文档元数据接口获取文件的最后修改时间（秒级时间戳）
+func (s *feiShuService) getLatestModifyTime(ctx context.Context, appID string, appSecret string, fileToken string, docType string) (string, error) {
+	body := map[string]interface{}{
+		"request_docs": []map[string]string{
+			{"doc_token": fileToken, "doc_type": docType},
+		},
+		"with_url": false,
+	}
+
+	var response struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+		Data struct {
+			Metas []struct {
+				DocToken         string `json:"doc_token"`
+				LatestModifyTime string `json:"latest_modify_time"`
+			} `json:"metas"`
+		} `json:"data"`
 	}
 
-	// 服务端错误处理
-	if !resp.Success() {
-		return "", fmt.Errorf("request failed with code: %d, message: %s", resp.Code, resp.Msg)
+	if err := s.client.Do(ctx, appID, appSecret, http.MethodPost, "/open-apis/drive/v1/metas/batch_query", body, &response); err != nil {
+		return "", err
+	}
+	if len(response.Data.Metas) == 0 || response.Data.Metas[0].LatestModifyTime == "" {
+		return "", fmt.Errorf("no meta found for %s: %s", docType, fileToken)
 	}
 
-	return *resp.Data.Metas[0].LatestModifyTime, nil
+	return response.Data.Metas[0].LatestModifyTime, nil
+}
+
+// formatUpdateLog 将秒级时间戳格式化为 yyyy-mm-dd hh:mm:ss，用于展示表格的更新时间
+func (s *feiShuService) formatUpdateLog(ctx context.Context, modifyTime string) string {
+	// 将时间戳字符串转换为 int64 类型的时间戳
+	timestamp, err := strconv.ParseInt(modifyTime, 10, 64)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("feiShuService.formatUpdateLog parse timestamp error",
+			zap.String("modify_time", modifyTime), zap.Error(err))
+		return ""
+	}
+	return time.Unix(timestamp, 0).Format("2006-01-02 15:04:05")
 }
@@ -0,0 +1,416 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"go.uber.org/zap"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"shengcai/pkg/readability"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// 链接对应的文档类型，同时记录在 cell_data.link_type 中
+const (
+	linkTypeDocx     = "docx"
+	linkTypeWiki     = "wiki"
+	linkTypeDoc      = "doc"
+	linkTypeSheet    = "sheet"
+	linkTypeBitable  = "bitable"
+	linkTypeMinutes  = "minutes"
+	linkTypeExternal = "external"
+)
+
+// feiShuLinkPaths 链接路径中的类型标识与文档类型的对应关系
+var feiShuLinkPaths = map[string]string{
+	"docx":    linkTypeDocx,
+	"wiki":    linkTypeWiki,
+	"docs":    linkTypeDoc,
+	"doc":     linkTypeDoc,
+	"sheets":  linkTypeSheet,
+	"base":    linkTypeBitable,
+	"minutes": linkTypeMinutes,
+}
+
+// feiShuHosts 飞书文档所在的域名后缀
+var feiShuHosts = []string{"feishu.cn", "larksuite.com", "larkoffice.com"}
+
+// Document 链接对应的文档内容
+type Document struct {
+	// LinkType 实际处理该链接的文档类型，知识库链接为解析后的类型
+	LinkType string
+	// Token 飞书文档的 token，知识库链接为解析后的文档 token，外部链接为空
+	Token   string
+	Content string
+	// Text 素材地址改写前的纯文本，用于判断正文是否变化
+	Text string
+	// Markdown 保留标题、列表、链接、表格和图片的结构化内容，目前只有新版文档支持
+	Markdown string
+}
+
+// documentLink 解析后的链接
+type documentLink struct {
+	Type  string
+	Token string
+	URL   *url.URL
+}
+
+// documentFetcher 获取某一类链接的文档内容并填充到 document 中
+type documentFetcher func(ctx context.Context, appID string, appSecret string, link documentLink, document *Document) error
+
+// parseDocumentLink 解析链接的文档类型和 token，如 https://xxx.feishu.cn/wiki/<token>?from=xxx；
+// 非飞书域名的 http 链接视为外部链接
+func parseDocumentLink(link string) documentLink {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return documentLink{}
+	}
+
+	if !isFeiShuHost(u.Hostname()) {
+		return documentLink{Type: linkTypeExternal, URL: u}
+	}
+
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for i := 0; i+1 < len(segments); i++ {
+		if linkType, ok := feiShuLinkPaths[segments[i]]; ok {
+			return documentLink{Type: linkType, Token: segments[i+1], URL: u}
+		}
+	}
+	return documentLink{URL: u}
+}
+
+func isFeiShuHost(host string) bool {
+	for _, suffix := range feiShuHosts {
+		if host == suffix || strings.HasSuffix(host, "."+suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// documentFetchers 各类链接对应的内容获取方法
+func (s *feiShuService) documentFetchers() map[string]documentFetcher {
+	return map[string]documentFetcher{
+		linkTypeDocx:     s.fetchDocx,
+		linkTypeDoc:      s.fetchDoc,
+		linkTypeSheet:    s.fetchSheet,
+		linkTypeBitable:  s.fetchBitable,
+		linkTypeExternal: s.fetchExternal,
+		// 妙记是音视频的转写，不作为文章处理，只记录链接类型
+		linkTypeMinutes: skipDocument,
+	}
+}
+
+// skipDocument 不获取内容的链接类型，正文为空，也不会生成摘要
+func skipDocument(ctx context.Context, appID string, appSecret string, link documentLink, document *Document) error {
+	return nil
+}
+
+// GetDocumentData 获取链接对应文档的内容，知识库链接会先解析为实际的文档
+func (s *feiShuService) GetDocumentData(ctx context.Context, appID string, appSecret string, documentMetaData map[string]string) (*Document, error) {
+	link := parseDocumentLink(documentMetaData["link"])
+
+	if link.Type == linkTypeWiki {
+		node, err := s.resolveWikiNode(ctx, appID, appSecret, link.Token)
+		if err != nil {
+			return &Document{LinkType: linkTypeWiki}, err
+		}
+		link.Type, link.Token = node.ObjType, node.ObjToken
+	}
+
+	document := &Document{LinkType: link.Type, Token: link.Token}
+	fetcher, ok := s.fetchers[link.Type]
+	if !ok {
+		return document, fmt.Errorf("unsupported document type %q for link: %s", link.Type, documentMetaData["link"])
+	}
+
+	err := fetcher(ctx, appID, appSecret, link, document)
+	if document.Text == "" {
+		document.Text = document.Content
+	}
+	return document, err
+}
+
+// wikiNode 知识库节点对应的实际文档
+type wikiNode struct {
+	ObjToken string
+	ObjType  string
+}
+
+// resolveWikiNode 通过知识库 get_node 接口获取节点对应的文档 token 和类型，结果会被缓存
+func (s *feiShuService) resolveWikiNode(ctx context.Context, appID string, appSecret string, nodeToken string) (*wikiNode, error) {
+	if cached, ok := s.wikiNodes.Load(nodeToken); ok {
+		return cached.(*wikiNode), nil
+	}
+
+	path := fmt.Sprintf("/open-apis/wiki/v2/spaces/get_node?token=%s", url.QueryEscape(nodeToken))
+
+	var response struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+		Data struct {
+			Node struct {
+				ObjToken string `json:"obj_token"`
+				ObjType  string `json:"obj_type"`
+				Title    string `json:"title"`
+			} `json:"node"`
+		} `json:"data"`
+	}
+
+	if err := s.client.Do(ctx, appID, appSecret, http.MethodGet, path, nil, &response); err != nil {
+		return nil, err
+	}
+	if response.Data.Node.ObjToken == "" {
+		return nil, fmt.Errorf("wiki node %s has no obj_token", nodeToken)
+	}
+
+	node := &wikiNode{
+		ObjToken: response.Data.Node.ObjToken,
+		ObjType:  response.Data.Node.ObjType,
+	}
+	s.wikiNodes.Store(nodeToken, node)
+	return node, nil
+}
+
+// fetchDocx 获取新版文档的纯文本内容，开启 feishu.docx_markdown 时同时遍历文档块生成 Markdown
+func (s *feiShuService) fetchDocx(ctx context.Context, appID string, appSecret string, link documentLink, document *Document) error {
+	path := fmt.Sprintf("/open-apis/docx/v1/documents/%s/raw_content", link.Token)
+
+	// 解析 JSON 响应体
+	var response struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+		Data struct {
+			Content string `json:"content"`
+		} `json:"data"`
+	}
+
+	if err := s.client.Do(ctx, appID, appSecret, http.MethodGet, path, nil, &response); err != nil {
+		return err
+	}
+
+	document.Content = response.Data.Content
+	document.Text = response.Data.Content
+
+	if s.conf.GetBool("feishu.docx_markdown") {
+		// Markdown 获取失败时仍保留纯文本内容
+		blocks, err := s.getDocxBlocks(ctx, appID, appSecret, link.Token)
+		if err != nil {
+			s.logger.WithContext(ctx).Error("feiShuService.fetchDocx getDocxBlocks error", zap.String("document_id", link.Token), zap.Error(err))
+			return nil
+		}
+		document.Markdown = docxMarkdown(blocks)
+
+		// 素材下载失败时保留原来的引用，不影响文档内容的保存
+		if err = s.mediaService.Mirror(ctx, appID, appSecret, document); err != nil {
+			s.logger.WithContext(ctx).Error("feiShuService.fetchDocx Mirror error", zap.String("document_id", link.Token), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// fetchDoc 获取旧版文档的纯文本内容
+func (s *feiShuService) fetchDoc(ctx context.Context, appID string, appSecret string, link documentLink, document *Document) error {
+	path := fmt.Sprintf("/open-apis/doc/v2/%s/raw_content", link.Token)
+
+	var response struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+		Data struct {
+			Content string `json:"content"`
+		} `json:"data"`
+	}
+
+	if err := s.client.Do(ctx, appID, appSecret, http.MethodGet, path, nil, &response); err != nil {
+		return err
+	}
+
+	document.Content = response.Data.Content
+	return nil
+}
+
+// fetchSheet 读取链接指向的工作表（默认第一个），每行的单元格以制表符分隔
+func (s *feiShuService) fetchSheet(ctx context.Context, appID string, appSecret string, link documentLink, document *Document) error {
+	tabs, err := s.GetSheetTabs(ctx, appID, appSecret, link.Token)
+	if err != nil {
+		return err
+	}
+	if len(tabs) == 0 {
+		return fmt.Errorf("spreadsheet %s has no readable sheet", link.Token)
+	}
+
+	tab := tabs[0]
+	if sheetID := link.URL.Query().Get("sheet"); sheetID != "" {
+		for _, t := range tabs {
+			if t.SheetID == sheetID {
+				tab = t
+				break
+			}
+		}
+	}
+
+	rowCount := tab.RowCount
+	if window := s.readWindow(); rowCount > window {
+		rowCount = window
+	}
+	if rowCount <= 0 || tab.ColumnCount <= 0 {
+		return nil
+	}
+
+	valueRange := fmt.Sprintf("%s!A1:%s%d", tab.SheetID, columnLetter(tab.ColumnCount-1), rowCount)
+	values, _, err := s.getSheetValues(ctx, appID, appSecret, link.Token, valueRange)
+	if err != nil {
+		return err
+	}
+
+	lines := make([]string, 0, len(values))
+	for _, row := range values {
+		cells := make([]string, len(row))
+		empty := true
+		for i, cell := range row {
+			cells[i] = cellText(cell)
+			if cells[i] != "" {
+				empty = false
+			}
+		}
+		if !empty {
+			lines = append(lines, strings.TrimRight(strings.Join(cells, "\t"), "\t"))
+		}
+	}
+	document.Content = strings.Join(lines, "\n")
+	return nil
+}
+
+// fetchBitable 读取多维表格中链接指向的数据表（默认第一个），每条记录输出为“字段: 值”
+func (s *feiShuService) fetchBitable(ctx context.Context, appID string, appSecret string, link documentLink, document *Document) error {
+	tableID := link.URL.Query().Get("table")
+	if tableID == "" {
+		path := fmt.Sprintf("/open-apis/bitable/v1/apps/%s/tables?page_size=1", link.Token)
+
+		var response struct {
+			Code int    `json:"code"`
+			Msg  string `json:"msg"`
+			Data struct {
+				Items []struct {
+					TableID string `json:"table_id"`
+				} `json:"items"`
+			} `json:"data"`
+		}
+		if err := s.client.Do(ctx, appID, appSecret, http.MethodGet, path, nil, &response); err != nil {
+			return err
+		}
+		if len(response.Data.Items) == 0 {
+			return fmt.Errorf("bitable %s has no table", link.Token)
+		}
+		tableID = response.Data.Items[0].TableID
+	}
+
+	path := fmt.Sprintf("/open-apis/bitable/v1/apps/%s/tables/%s/records?page_size=%d", link.Token, tableID, s.readWindow())
+
+	var response struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+		Data struct {
+			Items []struct {
+				Fields map[string]interface{} `json:"fields"`
+			} `json:"items"`
+		} `json:"data"`
+	}
+	if err := s.client.Do(ctx, appID, appSecret, http.MethodGet, path, nil, &response); err != nil {
+		return err
+	}
+
+	records := make([]string, 0, len(response.Data.Items))
+	for _, item := range response.Data.Items {
+		fields := make([]string, 0, len(item.Fields))
+		for name, value := range item.Fields {
+			if text := cellText(value); text != "" {
+				fields = append(fields, name+": "+text)
+			}
+		}
+		if len(fields) > 0 {
+			records = append(records, strings.Join(fields, "\n"))
+		}
+	}
+	document.Content = strings.Join(records, "\n\n")
+	return nil
+}
+
+// fetchExternal 下载外部网页（如公众号文章、博客）并提取正文
+func (s *feiShuService) fetchExternal(ctx context.Context, appID string, appSecret string, link documentLink, document *Document) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", link.URL.String(), nil)
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; ShengCaiBot/1.0)")
+
+	resp, err := externalClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d for %s", resp.StatusCode, link.URL)
+	}
+
+	article, err := readability.Extract(io.LimitReader(resp.Body, maxExternalPageSize))
+	if err != nil {
+		return fmt.Errorf("error parsing html: %w", err)
+	}
+	if article.Text == "" {
+		return fmt.Errorf("no article text found in %s", link.URL)
+	}
+	document.Content = article.Text
+	return nil
+}
+
+// maxExternalPageSize 外部网页最多读取的字节数，超出的部分不参与正文提取
+const maxExternalPageSize = 5 << 20
+
+// externalClient 下载外部网页使用的客户端，链接来自表格，不能访问内网地址，
+// 每次建立连接时按解析后的 IP 检查，跳转后的地址同样会被检查
+var externalClient = &http.Client{
+	Timeout: 15 * time.Second,
+	Transport: &http.Transport{
+		// 不使用环境变量中的代理，否则检查的是代理的地址
+		Proxy: nil,
+		DialContext: (&net.Dialer{
+			Timeout: 5 * time.Second,
+			Control: func(network string, address string, c syscall.RawConn) error {
+				host, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return err
+				}
+				if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+					return fmt.Errorf("address %s is not allowed", address)
+				}
+				return nil
+			},
+		}).DialContext,
+		TLSHandshakeTimeout: 5 * time.Second,
+		MaxIdleConnsPerHost: 2,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 5 {
+			return fmt.Errorf("stopped after %d redirects", len(via))
+		}
+		if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+			return fmt.Errorf("redirect to unsupported scheme %q", req.URL.Scheme)
+		}
+		return nil
+	},
+}
+
+// sharedAddressSpace 运营商级 NAT 使用的共享地址段，云厂商内部服务也会使用
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// isPublicIP 判断是否为公网地址，排除回环、内网、共享地址、链路本地、组播和未指定地址
+func isPublicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || sharedAddressSpace.Contains(ip) || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified())
+}
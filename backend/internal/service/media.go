@@ -0,0 +1,170 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	v1 "shengcai/api/v1"
+	"shengcai/internal/model"
+	"shengcai/internal/repository"
+	"shengcai/pkg/blob"
+	"shengcai/pkg/feishu"
+	"strconv"
+)
+
+// mediaReferenceRegexp 匹配 Markdown 中 feishu-media://<token> 形式的素材引用
+var mediaReferenceRegexp = regexp.MustCompile(regexp.QuoteMeta(docxMediaScheme) + `([A-Za-z0-9_-]+)`)
+
+// mediaFileNameRegexp 匹配纯文本中可能是素材 token 的单词，扩展名可选
+var mediaFileNameRegexp = regexp.MustCompile(`([A-Za-z0-9_-]+)(\.[A-Za-z0-9]+)?`)
+
+// MediaService 将文档中的图片和附件下载到本地存储，并把引用改写为本服务的地址
+type MediaService interface {
+	Mirror(ctx context.Context, appID string, appSecret string, document *Document) error
+	Open(ctx context.Context, id int) (*model.Media, io.ReadCloser, error)
+}
+
+func NewMediaService(
+	service *Service,
+	client *feishu.Client,
+	mediaRepo repository.MediaRepository,
+	store blob.Store,
+) MediaService {
+	return &mediaService{
+		Service:   service,
+		client:    client,
+		mediaRepo: mediaRepo,
+		store:     store,
+	}
+}
+
+type mediaService struct {
+	*Service
+	client    *feishu.Client
+	mediaRepo repository.MediaRepository
+	store     blob.Store
+}
+
+// Mirror 下载文档 Markdown 中引用的素材，并改写 Markdown 和纯文本中的引用。
+// 单个素材下载失败时保留原来的引用，下次同步时重试
+func (s *mediaService) Mirror(ctx context.Context, appID string, appSecret string, document *Document) error {
+	if !s.conf.GetBool("media.enabled") || document.Markdown == "" {
+		return nil
+	}
+
+	urls := make(map[string]string)
+	for _, match := range mediaReferenceRegexp.FindAllStringSubmatch(document.Markdown, -1) {
+		token := match[1]
+		if _, ok := urls[token]; ok {
+			continue
+		}
+		media, err := s.mirror(ctx, appID, appSecret, token)
+		if err != nil {
+			s.logger.WithContext(ctx).Error("mediaService.mirror error", zap.String("token", token), zap.Error(err))
+			continue
+		}
+		urls[token] = s.mediaURL(media.ID)
+	}
+	if len(urls) == 0 {
+		return nil
+	}
+
+	document.Markdown = mediaReferenceRegexp.ReplaceAllStringFunc(document.Markdown, func(reference string) string {
+		if url, ok := urls[reference[len(docxMediaScheme):]]; ok {
+			return url
+		}
+		return reference
+	})
+	// 纯文本中的图片以 <token>.<扩展名> 的文件名出现，按完整的单词匹配，避免替换以 token 开头的其他单词
+	document.Content = mediaFileNameRegexp.ReplaceAllStringFunc(document.Content, func(word string) string {
+		if url, ok := urls[mediaFileNameRegexp.FindStringSubmatch(word)[1]]; ok {
+			return url
+		}
+		return word
+	})
+	return nil
+}
+
+// mirror 下载一个素材，已下载过的 token 直接返回，内容相同的文件只保存一份
+func (s *mediaService) mirror(ctx context.Context, appID string, appSecret string, token string) (*model.Media, error) {
+	media, err := s.mediaRepo.GetByToken(ctx, token)
+	if err == nil {
+		return media, nil
+	}
+	if !errors.Is(err, v1.ErrNotFound) {
+		return nil, err
+	}
+
+	path := fmt.Sprintf("/open-apis/drive/v1/medias/%s/download", token)
+	data, header, err := s.client.Download(ctx, appID, appSecret, path, s.conf.GetInt64("media.max_size"))
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	exists, err := s.store.Exists(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		if err = s.store.Put(ctx, hash, bytes.NewReader(data)); err != nil {
+			return nil, err
+		}
+	}
+
+	media = &model.Media{
+		Token:       token,
+		Hash:        hash,
+		Size:        int64(len(data)),
+		ContentType: header.Get("Content-Type"),
+		StorageKey:  hash,
+	}
+	if media.ContentType == "" {
+		media.ContentType = http.DetectContentType(data)
+	}
+	if _, params, err := mime.ParseMediaType(header.Get("Content-Disposition")); err == nil {
+		media.FileName = params["filename"]
+	}
+	if err = s.mediaRepo.Create(ctx, media); err != nil {
+		// 多个协程同时下载同一个素材时，以先写入的记录为准
+		if existing, getErr := s.mediaRepo.GetByToken(ctx, token); getErr == nil {
+			return existing, nil
+		}
+		return nil, err
+	}
+	return media, nil
+}
+
+// Open 打开素材的内容，调用方负责关闭
+func (s *mediaService) Open(ctx context.Context, id int) (*model.Media, io.ReadCloser, error) {
+	media, err := s.mediaRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	reader, err := s.store.Open(ctx, media.StorageKey)
+	if errors.Is(err, blob.ErrNotFound) {
+		return nil, nil, v1.ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return media, reader, nil
+}
+
+// mediaURL 素材对外的访问地址，默认为 /v1/media/<id>
+func (s *mediaService) mediaURL(id int) string {
+	prefix := s.conf.GetString("media.url_prefix")
+	if prefix == "" {
+		prefix = "/v1/media/"
+	}
+	return prefix + strconv.Itoa(id)
+}
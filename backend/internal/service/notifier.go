@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"go.uber.org/zap"
+	"net/url"
+	"shengcai/internal/model"
+	"shengcai/internal/repository"
+	"shengcai/pkg/feishu"
+	"strings"
+)
+
+// NotifierService 将同步中新增的文章推送到飞书群自定义机器人
+type NotifierService interface {
+	NotifyCreated(ctx context.Context, sheetInfo *model.SheetInfo, links []string) error
+}
+
+func NewNotifierService(
+	service *Service,
+	client *feishu.Client,
+	cellDataRepo repository.CellDataRepository,
+) NotifierService {
+	return &notifierService{
+		Service:      service,
+		client:       client,
+		cellDataRepo: cellDataRepo,
+	}
+}
+
+type notifierService struct {
+	*Service
+	client       *feishu.Client
+	cellDataRepo repository.CellDataRepository
+}
+
+// botWebhook 配置中的一个群自定义机器人
+type botWebhook struct {
+	Name string `mapstructure:"name"`
+	URL  string `mapstructure:"url"`
+	// Secret 机器人开启签名校验时的密钥
+	Secret string `mapstructure:"secret"`
+	// Sheets 接收通知的表格，为空时接收所有表格的通知
+	Sheets []string `mapstructure:"sheets"`
+}
+
+// routes 判断机器人是否接收该表格的通知
+func (w botWebhook) routes(sheetId string) bool {
+	if len(w.Sheets) == 0 {
+		return true
+	}
+	for _, id := range w.Sheets {
+		if id == sheetId {
+			return true
+		}
+	}
+	return false
+}
+
+// NotifyCreated 一次同步只发送一张卡片，卡片中最多展示 notify.max_articles 篇文章
+func (s *notifierService) NotifyCreated(ctx context.Context, sheetInfo *model.SheetInfo, links []string) error {
+	if len(links) == 0 || sheetInfo.NotifyMuted {
+		return nil
+	}
+
+	var webhooks []botWebhook
+	if err := s.conf.UnmarshalKey("notify.webhooks", &webhooks); err != nil {
+		return fmt.Errorf("error decoding notify.webhooks: %w", err)
+	}
+	var targets []botWebhook
+	for _, webhook := range webhooks {
+		if webhook.URL != "" && (webhook.routes(sheetInfo.SheetID) || sheetInfo.IsMirror() && webhook.routes(sheetInfo.OriginSheetID)) {
+			targets = append(targets, webhook)
+		}
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+
+	maxArticles := s.conf.GetInt("notify.max_articles")
+	if maxArticles <= 0 {
+		maxArticles = 10
+	}
+	var articles []*model.CellData
+	for _, link := range links {
+		if len(articles) >= maxArticles {
+			break
+		}
+		article, err := s.cellDataRepo.GetByLink(ctx, sheetInfo.SheetID, link)
+		if err != nil {
+			s.logger.WithContext(ctx).Error("notifierService.GetByLink error", zap.String("link", link), zap.Error(err))
+			continue
+		}
+		articles = append(articles, article)
+	}
+	if len(articles) == 0 {
+		return nil
+	}
+
+	card := createdCard(sheetInfo, articles, len(links))
+
+	// 单个机器人发送失败不影响其他机器人
+	var failed []string
+	for _, webhook := range targets {
+		if err := s.client.SendBotMessage(ctx, webhook.URL, webhook.Secret, card); err != nil {
+			s.logger.WithContext(ctx).Error("notifierService.SendBotMessage error",
+				zap.String("webhook", webhook.Name), zap.String("sheet_id", sheetInfo.SheetID), zap.Error(err))
+			failed = append(failed, fmt.Sprintf("%s: %v", webhook.Name, err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d webhooks failed: %s", len(failed), len(targets), strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// createdCard 生成新增文章的消息卡片，total 为本次新增的文章总数
+func createdCard(sheetInfo *model.SheetInfo, articles []*model.CellData, total int) map[string]interface{} {
+	sheetName := sheetInfo.DisplayName()
+
+	elements := make([]interface{}, 0, len(articles)*2+1)
+	for i, article := range articles {
+		if i > 0 {
+			elements = append(elements, map[string]interface{}{"tag": "hr"})
+		}
+		// 标题、链接来自表格，摘要和关键字由模型根据正文生成，都需要转义后才能放进 lark_md
+		title := escapeLarkMarkdown(strings.Join(strings.Fields(article.Title), " "))
+		line := fmt.Sprintf("**%s**", title)
+		if link := larkMarkdownLink(article.Link); link != "" {
+			line = fmt.Sprintf("**[%s](%s)**", title, link)
+		}
+		lines := []string{line}
+		if article.Abstract != "" {
+			lines = append(lines, escapeLarkMarkdown(article.Abstract))
+		}
+		if article.Keyword != "" {
+			lines = append(lines, "关键字："+escapeLarkMarkdown(article.Keyword))
+		}
+		elements = append(elements, map[string]interface{}{
+			"tag":  "div",
+			"text": map[string]interface{}{"tag": "lark_md", "content": strings.Join(lines, "\n")},
+		})
+	}
+	if total > len(articles) {
+		elements = append(elements, map[string]interface{}{
+			"tag": "note",
+			"elements": []interface{}{
+				map[string]interface{}{"tag": "plain_text", "content": fmt.Sprintf("还有 %d 篇文章未展示", total-len(articles))},
+			},
+		})
+	}
+
+	return map[string]interface{}{
+		"msg_type": "interactive",
+		"card": map[string]interface{}{
+			"config": map[string]interface{}{"wide_screen_mode": true},
+			"header": map[string]interface{}{
+				"template": "blue",
+				"title": map[string]interface{}{
+					"tag":     "plain_text",
+					"content": fmt.Sprintf("%s 新增 %d 篇文章", sheetName, total),
+				},
+			},
+			"elements": elements,
+		},
+	}
+}
+
+// larkMarkdownEscaper 将 lark_md 中有特殊含义的字符替换为 HTML 实体，按原样显示
+var larkMarkdownEscaper = strings.NewReplacer(
+	"&", "&amp;", "<", "&lt;", ">", "&gt;",
+	"\\", "&#92;", "*", "&#42;", "_", "&#95;", "~", "&#126;", "`", "&#96;",
+	"[", "&#91;", "]", "&#93;", "(", "&#40;", ")", "&#41;", "#", "&#35;",
+)
+
+// escapeLarkMarkdown 转义文本中的 Markdown 字符
+func escapeLarkMarkdown(text string) string {
+	return larkMarkdownEscaper.Replace(text)
+}
+
+// larkMarkdownLink 返回可以放在 lark_md 链接中的地址，只接受 http、https 链接，
+// 括号和空白按百分号编码，避免提前结束链接
+func larkMarkdownLink(link string) string {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ""
+	}
+	return strings.NewReplacer("(", "%28", ")", "%29", " ", "%20").Replace(u.String())
+}
@@ -2,10 +2,12 @@ package service
 
 import (
 	"github.com/spf13/viper"
+	"go.uber.org/zap"
 	"shengcai/internal/repository"
 	"shengcai/pkg/jwt"
 	"shengcai/pkg/log"
 	"shengcai/pkg/sid"
+	"time"
 )
 
 type Service struct {
@@ -31,3 +33,25 @@ func NewService(
 		conf:   conf,
 	}
 }
+
+// defaultSyncInterval 表格未单独设置同步间隔时使用的默认值，单位为秒
+func (s *Service) defaultSyncInterval() int {
+	if interval := s.conf.GetInt("feishu.sync_interval"); interval > 0 {
+		return interval
+	}
+	return 300
+}
+
+// timezone 解析和展示发布日期使用的时区，未配置或无法识别时使用服务器的本地时区
+func (s *Service) timezone() *time.Location {
+	name := s.conf.GetString("feishu.timezone")
+	if name == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		s.logger.Error("unknown feishu.timezone", zap.String("timezone", name), zap.Error(err))
+		return time.Local
+	}
+	return loc
+}
@@ -0,0 +1,275 @@
+package service
+
+import (
+	"context"
+	"errors"
+	v1 "shengcai/api/v1"
+	"shengcai/internal/model"
+	"shengcai/internal/repository"
+	"time"
+)
+
+type SheetInfoService interface {
+	Register(ctx context.Context, req *v1.SheetRegisterRequest) error
+	UpdateStatus(ctx context.Context, req *v1.SheetUpdateStatusRequest) error
+	Mute(ctx context.Context, req *v1.SheetMuteRequest) error
+	ReportMirror(ctx context.Context, req *v1.SheetMirrorReportRequest) (*v1.SheetMirrorReportResponse, error)
+	Remove(ctx context.Context, req *v1.SheetRemoveRequest) error
+	List(ctx context.Context) (*v1.SheetListResponse, error)
+}
+
+func NewSheetInfoService(
+	service *Service,
+	sheetInfoRepo repository.SheetInfoRepository,
+	eventService EventService,
+) SheetInfoService {
+	return &sheetInfoService{
+		Service:       service,
+		sheetInfoRepo: sheetInfoRepo,
+		eventService:  eventService,
+	}
+}
+
+type sheetInfoService struct {
+	*Service
+	sheetInfoRepo repository.SheetInfoRepository
+	// 镜像表格拷贝完成后通过事件的防抖队列触发同步
+	eventService EventService
+}
+
+// Register 登记一个需要同步的电子表格，已登记或已移除的表格会被重新启用
+func (s *sheetInfoService) Register(ctx context.Context, req *v1.SheetRegisterRequest) error {
+	syncInterval := req.SyncInterval
+	if syncInterval == 0 {
+		syncInterval = s.defaultSyncInterval()
+	}
+	sourceType := req.SourceType
+	if sourceType == "" {
+		sourceType = model.SourceTypeSheet
+	}
+	// 多维表格没有默认布局，必须配置字段映射，且不支持回写
+	if sourceType == model.SourceTypeBitable &&
+		(req.ColumnMapping == nil || req.ColumnMapping.Abstract != "" || req.ColumnMapping.Keyword != "") {
+		return v1.ErrBadRequest
+	}
+	if err := s.checkOrigin(ctx, req.SpreadsheetToken, req.OriginSheetID); err != nil {
+		return err
+	}
+
+	sheetInfo, err := s.sheetInfoRepo.GetBySheetID(ctx, req.SpreadsheetToken)
+	if err != nil && !errors.Is(err, v1.ErrNotFound) {
+		return err
+	}
+	if sheetInfo == nil {
+		sheetInfo = &model.SheetInfo{
+			SheetID:         req.SpreadsheetToken,
+			SheetName:       req.SheetName,
+			SourceType:      sourceType,
+			TableID:         req.TableID,
+			OriginSheetID:   req.OriginSheetID,
+			OriginSheetName: req.OriginSheetName,
+			Status:          model.SheetStatusActive,
+			SyncInterval:    syncInterval,
+		}
+		if req.ColumnMapping != nil {
+			sheetInfo.ColumnMapping = columnMappingFromRequest(req.ColumnMapping)
+		}
+		return s.sheetInfoRepo.Create(ctx, sheetInfo)
+	}
+
+	if req.SheetName != "" {
+		sheetInfo.SheetName = req.SheetName
+	}
+	if req.ColumnMapping != nil {
+		sheetInfo.ColumnMapping = columnMappingFromRequest(req.ColumnMapping)
+	}
+	if req.OriginSheetID != "" {
+		sheetInfo.OriginSheetID = req.OriginSheetID
+		sheetInfo.OriginSheetName = req.OriginSheetName
+	}
+	sheetInfo.SourceType = sourceType
+	sheetInfo.TableID = req.TableID
+	sheetInfo.SyncInterval = syncInterval
+	sheetInfo.Status = model.SheetStatusActive
+	sheetInfo.Deleted = false
+	// 重新登记后强制完整同步一次，使新的列映射立即生效
+	sheetInfo.LatestModifyTime = ""
+	sheetInfo.Revision = 0
+	return s.sheetInfoRepo.Save(ctx, sheetInfo)
+}
+
+func (s *sheetInfoService) UpdateStatus(ctx context.Context, req *v1.SheetUpdateStatusRequest) error {
+	sheetInfo, err := s.sheetInfoRepo.GetBySheetID(ctx, req.SpreadsheetToken)
+	if err != nil {
+		return err
+	}
+	if sheetInfo.Deleted {
+		return v1.ErrNotFound
+	}
+	return s.sheetInfoRepo.Updates(ctx, sheetInfo.SheetID, map[string]interface{}{
+		"status": req.Status,
+	})
+}
+
+// Mute 设置表格是否发送新增文章的通知，关闭后同步照常进行
+func (s *sheetInfoService) Mute(ctx context.Context, req *v1.SheetMuteRequest) error {
+	sheetInfo, err := s.sheetInfoRepo.GetBySheetID(ctx, req.SpreadsheetToken)
+	if err != nil {
+		return err
+	}
+	if sheetInfo.Deleted {
+		return v1.ErrNotFound
+	}
+	return s.sheetInfoRepo.Updates(ctx, sheetInfo.SheetID, map[string]interface{}{
+		"notify_muted": req.Muted,
+	})
+}
+
+// ReportMirror 记录 RPA 拷贝加密表格的结果，拷贝内容有变化时触发镜像表格的同步。
+// 尚未关联原始表格的镜像表格以第一次上报的原始表格为准
+func (s *sheetInfoService) ReportMirror(ctx context.Context, req *v1.SheetMirrorReportRequest) (*v1.SheetMirrorReportResponse, error) {
+	sheetInfo, err := s.sheetInfoRepo.GetBySheetID(ctx, req.SpreadsheetToken)
+	if err != nil {
+		return nil, err
+	}
+	if sheetInfo.Deleted {
+		return nil, v1.ErrNotFound
+	}
+
+	values := map[string]interface{}{
+		"mirror_row_count":   req.RowCount,
+		"mirror_checksum":    req.Checksum,
+		"mirror_reported_at": time.Now(),
+	}
+	switch {
+	case !sheetInfo.IsMirror() && req.OriginSheetID == "":
+		// 不是镜像表格，RPA 也没有说明拷贝的来源
+		return nil, v1.ErrBadRequest
+	case !sheetInfo.IsMirror():
+		if err = s.checkOrigin(ctx, sheetInfo.SheetID, req.OriginSheetID); err != nil {
+			return nil, err
+		}
+		values["origin_sheet_id"] = req.OriginSheetID
+	case req.OriginSheetID != "" && req.OriginSheetID != sheetInfo.OriginSheetID:
+		// 拷贝到了其他原始表格的镜像中，需要先重新登记
+		return nil, v1.ErrOriginMirrored
+	}
+	if req.OriginSheetName != "" {
+		values["origin_sheet_name"] = req.OriginSheetName
+	}
+	if err = s.sheetInfoRepo.Updates(ctx, sheetInfo.SheetID, values); err != nil {
+		return nil, err
+	}
+
+	result := &v1.SheetMirrorReportResponse{}
+	changed := req.Checksum != sheetInfo.MirrorChecksum || req.RowCount != sheetInfo.MirrorRowCount
+	if changed && sheetInfo.Status == model.SheetStatusActive {
+		if err = s.eventService.ScheduleSync(ctx, sheetInfo.SheetID); err != nil {
+			return nil, err
+		}
+		result.SyncScheduled = true
+	}
+	return result, nil
+}
+
+// checkOrigin 一个原始表格只能有一个镜像表格，原始表格本身也不能是镜像
+func (s *sheetInfoService) checkOrigin(ctx context.Context, sheetId string, originSheetId string) error {
+	if originSheetId == "" {
+		return nil
+	}
+	if originSheetId == sheetId {
+		return v1.ErrBadRequest
+	}
+	mirror, err := s.sheetInfoRepo.GetByOriginSheetID(ctx, originSheetId)
+	if errors.Is(err, v1.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if mirror.SheetID != sheetId {
+		return v1.ErrOriginMirrored
+	}
+	return nil
+}
+
+// Remove 将表格从登记表中移除，已采集的文章保留
+func (s *sheetInfoService) Remove(ctx context.Context, req *v1.SheetRemoveRequest) error {
+	sheetInfo, err := s.sheetInfoRepo.GetBySheetID(ctx, req.SpreadsheetToken)
+	if err != nil {
+		return err
+	}
+	return s.sheetInfoRepo.Updates(ctx, sheetInfo.SheetID, map[string]interface{}{
+		"status":  model.SheetStatusPaused,
+		"deleted": true,
+	})
+}
+
+func (s *sheetInfoService) List(ctx context.Context) (*v1.SheetListResponse, error) {
+	list, err := s.sheetInfoRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	staleAfter := s.mirrorStaleAfter()
+	result := &v1.SheetListResponse{
+		List: make([]v1.SheetInfoItem, len(list)),
+	}
+	for i, item := range list {
+		mapping := item.GetColumnMapping()
+		result.List[i] = v1.SheetInfoItem{
+			SheetID:          item.SheetID,
+			SheetName:        item.SheetName,
+			SourceType:       item.SourceType,
+			TableID:          item.TableID,
+			OriginSheetID:    item.OriginSheetID,
+			OriginSheetName:  item.OriginSheetName,
+			MirrorRowCount:   item.MirrorRowCount,
+			MirrorReportedAt: item.MirrorReportedAt,
+			MirrorStale:      item.MirrorStale(now, staleAfter),
+			Status:           item.Status,
+			SyncInterval:     item.SyncInterval,
+			NotifyMuted:      item.NotifyMuted,
+			UpdateLog:        item.UpdateLog,
+			LastSyncAt:       item.LastSyncAt,
+			RuntimeState:     item.RuntimeState,
+			ColumnMapping: v1.ColumnMapping{
+				Title:    mapping.Title,
+				Link:     mapping.Link,
+				Date:     mapping.Date,
+				Extra:    mapping.Extra,
+				Abstract: mapping.Abstract,
+				Keyword:  mapping.Keyword,
+			},
+		}
+		if item.LastSyncResult != nil {
+			result.List[i].LastSyncResult = &v1.SheetSyncResult{
+				Skipped: item.LastSyncResult.Skipped,
+				Rows:    item.LastSyncResult.Rows,
+				Deleted: item.LastSyncResult.Deleted,
+				Written: item.LastSyncResult.Written,
+			}
+		}
+	}
+	return result, nil
+}
+
+// mirrorStaleAfter 镜像表格超过该时间没有收到 RPA 的上报即视为过期，默认 24 小时
+func (s *sheetInfoService) mirrorStaleAfter() time.Duration {
+	if seconds := s.conf.GetInt("feishu.mirror_stale_after"); seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return 24 * time.Hour
+}
+
+func columnMappingFromRequest(mapping *v1.ColumnMapping) model.ColumnMapping {
+	return model.ColumnMapping{
+		Title:    mapping.Title,
+		Link:     mapping.Link,
+		Date:     mapping.Date,
+		Extra:    mapping.Extra,
+		Abstract: mapping.Abstract,
+		Keyword:  mapping.Keyword,
+	}
+}
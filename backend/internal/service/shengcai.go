@@ -2,26 +2,42 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
 	"os"
 	v1 "shengcai/api/v1"
+	"shengcai/internal/model"
 	"shengcai/internal/repository"
+	"sync"
+	"time"
 )
 
 type ShengCaiService interface {
 	List(ctx context.Context, req *v1.ShengCaiListRequest) (*v1.ShengCaiListResponse, error)
 	GetMetaData(ctx context.Context, req *v1.ShengCaiGetMetaDataRequest) (*v1.ShengCaiGetMetaDataResponse, error)
+	Detail(ctx context.Context, req *v1.ShengCaiDetailRequest) (*v1.ShengCaiDetailResponse, error)
+	Comments(ctx context.Context, req *v1.ShengCaiCommentsRequest) (*v1.ShengCaiCommentsResponse, error)
 	CreateData(ctx context.Context) error
+	RegisterConfiguredSheet(ctx context.Context) error
+	SyncSheet(ctx context.Context, sheetId string) error
 }
 
 func NewShengCaiService(
 	service *Service,
 	feiShuService FeiShuService,
 	cellDataRepo repository.CellDataRepository,
+	sheetInfoRepo repository.SheetInfoRepository,
+	commentRepo repository.CommentRepository,
+	notifierService NotifierService,
 ) ShengCaiService {
 	return &shengCaiService{
-		Service:       service,
-		FeiShuService: feiShuService,
-		CellDataRepo:  cellDataRepo,
+		Service:         service,
+		FeiShuService:   feiShuService,
+		CellDataRepo:    cellDataRepo,
+		SheetInfoRepo:   sheetInfoRepo,
+		CommentRepo:     commentRepo,
+		NotifierService: notifierService,
 	}
 }
 
@@ -29,27 +45,258 @@ type shengCaiService struct {
 	*Service
 	FeiShuService FeiShuService
 	CellDataRepo  repository.CellDataRepository
+	SheetInfoRepo repository.SheetInfoRepository
+	CommentRepo   repository.CommentRepository
+	// 新增文章时发送群机器人通知
+	NotifierService NotifierService
+
+	// 正在同步中的表格，避免同一个表格被并发同步
+	syncing sync.Map
 }
 
 func (s *shengCaiService) List(ctx context.Context, req *v1.ShengCaiListRequest) (*v1.ShengCaiListResponse, error) {
-	if list, err := s.CellDataRepo.List(ctx, struct {
-		SheetID string `json:"sheet_id" validate:"required"`
-	}(struct{ SheetID string }{SheetID: req.SpreadsheetToken}), req.Page); err != nil {
+	sheetInfo, err := s.resolveSheet(ctx, req.SpreadsheetToken)
+	if err != nil {
+		return nil, err
+	}
+	filter := repository.CellDataFilter{SheetID: sheetInfo.SheetID, TabID: req.TabID, Sort: req.Sort}
+	// 日期按配置的时区解释，截止日期包含当天
+	if req.DateFrom != "" {
+		from, err := time.ParseInLocation("2006-01-02", req.DateFrom, s.timezone())
+		if err != nil {
+			return nil, v1.ErrBadRequest
+		}
+		filter.PublishedFrom = &from
+	}
+	if req.DateTo != "" {
+		to, err := time.ParseInLocation("2006-01-02", req.DateTo, s.timezone())
+		if err != nil {
+			return nil, v1.ErrBadRequest
+		}
+		to = to.AddDate(0, 0, 1)
+		filter.PublishedTo = &to
+	}
+	if list, err := s.CellDataRepo.List(ctx, filter, req.Page); err != nil {
 		return nil, err
 	} else {
+		for i := range list.List {
+			list.List[i].SheetID = sheetInfo.PublicSheetID()
+		}
 		return list, nil
 	}
 }
 
 func (s *shengCaiService) GetMetaData(ctx context.Context, req *v1.ShengCaiGetMetaDataRequest) (*v1.ShengCaiGetMetaDataResponse, error) {
-	if list, err := s.CellDataRepo.GetMetaData(ctx, req.SpreadsheetToken); err != nil {
+	sheetInfo, err := s.resolveSheet(ctx, req.SpreadsheetToken)
+	if err != nil {
+		return nil, err
+	}
+	if list, err := s.CellDataRepo.GetMetaData(ctx, sheetInfo.SheetID); err != nil {
 		return nil, err
 	} else {
+		if sheetInfo.IsMirror() {
+			list.SheetName = sheetInfo.DisplayName()
+		}
 		return list, nil
 	}
 }
 
+// Detail 返回文章的完整内容，包括纯文本和 Markdown
+func (s *shengCaiService) Detail(ctx context.Context, req *v1.ShengCaiDetailRequest) (*v1.ShengCaiDetailResponse, error) {
+	sheetInfo, err := s.resolveSheet(ctx, req.SpreadsheetToken)
+	if err != nil {
+		return nil, err
+	}
+	cellData, err := s.CellDataRepo.GetByLink(ctx, sheetInfo.SheetID, req.Link)
+	if err != nil {
+		return nil, err
+	}
+
+	return &v1.ShengCaiDetailResponse{
+		SheetID:         sheetInfo.PublicSheetID(),
+		TabID:           cellData.TabID,
+		TabTitle:        cellData.TabTitle,
+		Title:           cellData.Title,
+		Link:            cellData.Link,
+		LinkType:        cellData.LinkType,
+		ReleaseDate:     cellData.ReleaseDate,
+		PublishedAt:     cellData.PublishedAt,
+		Abstract:        cellData.Abstract,
+		Keyword:         cellData.Keyword,
+		AIExtra:         cellData.AIExtra,
+		AIState:         cellData.AIState,
+		Extra:           cellData.Extra,
+		Content:         cellData.Content,
+		ContentMarkdown: cellData.ContentMarkdown,
+	}, nil
+}
+
+// Comments 返回文章的评论，同一条评论的回复按时间顺序归在一起
+func (s *shengCaiService) Comments(ctx context.Context, req *v1.ShengCaiCommentsRequest) (*v1.ShengCaiCommentsResponse, error) {
+	sheetInfo, err := s.resolveSheet(ctx, req.SpreadsheetToken)
+	if err != nil {
+		return nil, err
+	}
+	cellData, err := s.CellDataRepo.GetByLink(ctx, sheetInfo.SheetID, req.Link)
+	if err != nil {
+		return nil, err
+	}
+	comments, err := s.CommentRepo.ListByCellData(ctx, cellData.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &v1.ShengCaiCommentsResponse{List: make([]v1.ShengCaiComment, 0)}
+	index := make(map[string]int)
+	for _, comment := range comments {
+		i, ok := index[comment.CommentID]
+		if !ok {
+			i = len(result.List)
+			index[comment.CommentID] = i
+			result.List = append(result.List, v1.ShengCaiComment{
+				CommentID: comment.CommentID,
+				Quote:     comment.Quote,
+				Solved:    comment.Solved,
+			})
+		}
+		result.List[i].Replies = append(result.List[i].Replies, v1.ShengCaiCommentReply{
+			ReplyID:   comment.ReplyID,
+			UserID:    comment.UserID,
+			Content:   comment.Content,
+			ReplyTime: comment.ReplyTime,
+		})
+	}
+	return result, nil
+}
+
+// resolveSheet 查询接口可以使用原始加密表格的 token，返回实际同步的镜像表格。
+// 未登记的表格按原样查询，与登记表出现之前的行为保持一致
+func (s *shengCaiService) resolveSheet(ctx context.Context, sheetId string) (*model.SheetInfo, error) {
+	sheetInfo, err := s.SheetInfoRepo.GetBySheetID(ctx, sheetId)
+	if err == nil {
+		return sheetInfo, nil
+	}
+	if !errors.Is(err, v1.ErrNotFound) {
+		return nil, err
+	}
+	sheetInfo, err = s.SheetInfoRepo.GetByOriginSheetID(ctx, sheetId)
+	if errors.Is(err, v1.ErrNotFound) {
+		return &model.SheetInfo{SheetID: sheetId}, nil
+	}
+	return sheetInfo, err
+}
+
+// RegisterConfiguredSheet 兼容旧配置：启动时登记环境变量或配置文件中的表格，已登记的表格保持不变
+func (s *shengCaiService) RegisterConfiguredSheet(ctx context.Context) error {
+	spreadsheetToken := os.Getenv("spreadsheet_token")
+	if spreadsheetToken == "" {
+		spreadsheetToken = s.conf.GetString("feishu.spreadsheet_token")
+	}
+	if spreadsheetToken == "" {
+		return nil
+	}
+	return s.SheetInfoRepo.Create(ctx, &model.SheetInfo{
+		SheetID:      spreadsheetToken,
+		Status:       model.SheetStatusActive,
+		SyncInterval: s.defaultSyncInterval(),
+	})
+}
+
+// CreateData 遍历登记表中所有启用的表格，对到期的表格各自启动一次同步
 func (s *shengCaiService) CreateData(ctx context.Context) error {
+	sheets, err := s.SheetInfoRepo.ListActive(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, sheet := range sheets {
+		if !sheet.IsDue(now) {
+			continue
+		}
+		// 同步耗时超过定时任务的间隔时，上一次同步还未结束，等它结束后按同步间隔再同步
+		if _, ok := s.syncing.Load(sheet.SheetID); ok {
+			s.logger.WithContext(ctx).Debug("shengCaiService.CreateData sheet is syncing, skipped", zap.String("sheet_id", sheet.SheetID))
+			continue
+		}
+		go func(sheetId string) {
+			err := s.SyncSheet(ctx, sheetId)
+			if errors.Is(err, v1.ErrSheetSyncing) {
+				// 事件触发或手动触发的同步刚好开始
+				s.logger.WithContext(ctx).Debug("shengCaiService.CreateData sheet is syncing, skipped", zap.String("sheet_id", sheetId))
+				return
+			}
+			if err != nil {
+				s.logger.WithContext(ctx).Error("shengCaiService.SyncSheet error", zap.String("sheet_id", sheetId), zap.Error(err))
+			}
+		}(sheet.SheetID)
+	}
+	return nil
+}
+
+// summarizePendingBatch 每次同步最多补齐的摘要数，避免单次同步占用过长时间
+const summarizePendingBatch = 50
+
+// SyncSheet 立即同步指定的表格，同一个表格同一时间只会有一个同步任务
+func (s *shengCaiService) SyncSheet(ctx context.Context, sheetId string) error {
+	if _, loaded := s.syncing.LoadOrStore(sheetId, struct{}{}); loaded {
+		return v1.ErrSheetSyncing
+	}
+	defer s.syncing.Delete(sheetId)
+
+	sheetInfo, err := s.SheetInfoRepo.GetBySheetID(ctx, sheetId)
+	if err != nil {
+		return err
+	}
+
+	if err = s.SheetInfoRepo.Updates(ctx, sheetId, map[string]interface{}{
+		"runtime_state": "syncing",
+	}); err != nil {
+		return err
+	}
+
+	appID, appSecret := s.credentials()
+	var result *model.SyncResult
+	var syncErr error
+	if sheetInfo.IsBitable() {
+		result, syncErr = s.FeiShuService.SaveBitableData(ctx, appID, appSecret, sheetInfo)
+	} else {
+		result, syncErr = s.FeiShuService.SaveTableData(ctx, appID, appSecret, sheetInfo)
+	}
+
+	runtimeState := "ok"
+	if syncErr != nil {
+		runtimeState = truncate(fmt.Sprintf("failed: %v", syncErr), 255)
+	}
+	values := map[string]interface{}{
+		"runtime_state": runtimeState,
+		"last_sync_at":  time.Now(),
+	}
+	if result != nil {
+		values["last_sync_result"] = result
+	}
+	if err = s.SheetInfoRepo.Updates(ctx, sheetId, values); err != nil {
+		return err
+	}
+
+	// 等待生成的摘要（超出 token 预算而暂停的）在之后的同步中补齐，表格没有变化时同样处理
+	if summarized, err := s.CellDataRepo.SummarizePending(ctx, sheetId, summarizePendingBatch); err != nil {
+		s.logger.WithContext(ctx).Error("shengCaiService.SummarizePending error", zap.String("sheet_id", sheetId), zap.Error(err))
+	} else if summarized > 0 {
+		s.logger.WithContext(ctx).Info("pending summaries generated", zap.String("sheet_id", sheetId), zap.Int("count", summarized))
+	}
+
+	// 部分工作表失败时已新增的文章同样需要通知，通知失败不影响同步结果。
+	// 表格第一次同步时所有文章都是新增的，不发送通知
+	if result != nil && len(result.Created) > 0 && sheetInfo.LastSyncAt != nil {
+		if err = s.NotifierService.NotifyCreated(ctx, sheetInfo, result.Created); err != nil {
+			s.logger.WithContext(ctx).Error("shengCaiService.NotifyCreated error", zap.String("sheet_id", sheetId), zap.Error(err))
+		}
+	}
+	return syncErr
+}
+
+func (s *shengCaiService) credentials() (string, string) {
 	appID := os.Getenv("app_id")
 	if appID == "" {
 		appID = s.conf.GetString("open.app_id")
@@ -59,16 +306,14 @@ func (s *shengCaiService) CreateData(ctx context.Context) error {
 	if appSecret == "" {
 		appSecret = s.conf.GetString("open.app_secret")
 	}
+	return appID, appSecret
+}
 
-	apiKey := os.Getenv("api_key")
-	if apiKey == "" {
-		apiKey = s.conf.GetString("open.api_key")
-	}
-
-	spreadsheetToken := os.Getenv("spreadsheet_token")
-	if spreadsheetToken == "" {
-		spreadsheetToken = s.conf.GetString("feishu.spreadsheet_token")
+// truncate 按字符截断字符串，避免超出数据库字段长度
+func truncate(str string, limit int) string {
+	runes := []rune(str)
+	if len(runes) <= limit {
+		return str
 	}
-
-	return s.FeiShuService.SaveTableData(ctx, appID, appSecret, spreadsheetToken)
+	return string(runes[:limit])
 }
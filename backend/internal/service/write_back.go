@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"go.uber.org/zap"
+	"net/http"
+	"shengcai/internal/model"
+	"time"
+)
+
+// writeBackCell 同步时读到的一行文章在回写列中的当前值
+type writeBackCell struct {
+	TabID     string
+	RowNumber int
+	// Abstract、Keyword 单元格当前的文本，未配置对应的列时为空
+	Abstract string
+	Keyword  string
+}
+
+// writeBackRange 一个待写入的单元格
+type writeBackRange struct {
+	ID     int
+	Column string
+	Range  string
+	Value  string
+}
+
+// collectWriteBackCell 记录一行中回写列的当前值，未开启回写时 cells 为 nil
+func (c *sheetColumns) collectWriteBackCell(cells map[string]writeBackCell, tab SheetTab, link string, row []interface{}, rowNumber int) {
+	if cells == nil {
+		return
+	}
+	cell := writeBackCell{TabID: tab.SheetID, RowNumber: rowNumber}
+	if c.Abstract >= 0 {
+		cell.Abstract = cellText(c.cell(row, c.Abstract))
+	}
+	if c.Keyword >= 0 {
+		cell.Keyword = cellText(c.cell(row, c.Keyword))
+	}
+	cells[link] = cell
+}
+
+// writeBack 将 AI 生成的摘要和关键字回写到电子表格中。
+// 只写入空白单元格或者上次由我们写入且未被修改过的单元格，人工编辑过的内容不会被覆盖。
+func (s *feiShuService) writeBack(ctx context.Context, appID string, appSecret string, spreadsheetToken string, columns map[string]*sheetColumns, cells map[string]writeBackCell) (int, error) {
+	list, err := s.cellDataRepo.ListWriteBack(ctx, spreadsheetToken)
+	if err != nil {
+		return 0, err
+	}
+
+	var ranges []writeBackRange
+	for _, item := range list {
+		cell, ok := cells[item.Link]
+		if !ok {
+			continue
+		}
+		tabColumns := columns[cell.TabID]
+		if tabColumns == nil {
+			continue
+		}
+		if tabColumns.Abstract >= 0 && writable(cell.Abstract, item.Abstract, item.WrittenAbstract) {
+			ranges = append(ranges, writeBackRange{
+				ID:     item.ID,
+				Column: "written_abstract",
+				Range:  cellRange(cell.TabID, tabColumns.Abstract, cell.RowNumber),
+				Value:  item.Abstract,
+			})
+		}
+		if tabColumns.Keyword >= 0 && writable(cell.Keyword, item.Keyword, item.WrittenKeyword) {
+			ranges = append(ranges, writeBackRange{
+				ID:     item.ID,
+				Column: "written_keyword",
+				Range:  cellRange(cell.TabID, tabColumns.Keyword, cell.RowNumber),
+				Value:  item.Keyword,
+			})
+		}
+	}
+
+	// 分批写入，每批只调用一次写入接口，节省飞书接口配额
+	batchSize := s.writeBackBatchSize()
+	written := 0
+	for start := 0; start < len(ranges); start += batchSize {
+		end := start + batchSize
+		if end > len(ranges) {
+			end = len(ranges)
+		}
+		batch := ranges[start:end]
+		if err = s.batchUpdateValues(ctx, appID, appSecret, spreadsheetToken, batch); err != nil {
+			return written, err
+		}
+
+		// 记录写入的内容，下次同步时据此判断单元格是否被人工修改过
+		now := time.Now()
+		for _, r := range batch {
+			if err = s.cellDataRepo.Updates(ctx, r.ID, map[string]interface{}{
+				r.Column:     r.Value,
+				"written_at": now,
+			}); err != nil {
+				return written, err
+			}
+			written++
+		}
+		s.logger.WithContext(ctx).Info("wrote back to spreadsheet",
+			zap.String("sheet_id", spreadsheetToken), zap.Int("cells", len(batch)))
+	}
+	return written, nil
+}
+
+// writable 判断单元格是否需要写入：内容有变化，且单元格为空或者仍是上次写入的内容
+func writable(current string, value string, written string) bool {
+	if value == "" || current == value {
+		return false
+	}
+	return current == "" || current == written
+}
+
+// cellRange 生成单个单元格的范围，如 tab001!E3:E3
+func cellRange(tabID string, column int, rowNumber int) string {
+	letter := columnLetter(column)
+	return fmt.Sprintf("%s!%s%d:%s%d", tabID, letter, rowNumber, letter, rowNumber)
+}
+
+// batchUpdateValues 调用向多个范围写入数据的接口
+func (s *feiShuService) batchUpdateValues(ctx context.Context, appID string, appSecret string, spreadsheetToken string, ranges []writeBackRange) error {
+	valueRanges := make([]map[string]interface{}, len(ranges))
+	for i, r := range ranges {
+		valueRanges[i] = map[string]interface{}{
+			"range":  r.Range,
+			"values": [][]interface{}{{r.Value}},
+		}
+	}
+
+	var response struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+	}
+	path := fmt.Sprintf("/open-apis/sheets/v2/spreadsheets/%s/values_batch_update", spreadsheetToken)
+	return s.client.Do(ctx, appID, appSecret, http.MethodPost, path, map[string]interface{}{
+		"valueRanges": valueRanges,
+	}, &response)
+}
+
+// writeBackBatchSize 每次写入的单元格数，默认 100 个
+func (s *feiShuService) writeBackBatchSize() int {
+	if size := s.conf.GetInt("feishu.write_back_batch_size"); size > 0 {
+		return size
+	}
+	return 100
+}
+
+// writeBackEnabled 判断表格是否开启了回写
+func writeBackEnabled(sheetInfo *model.SheetInfo) bool {
+	return !sheetInfo.IsBitable() && sheetInfo.ColumnMapping.WriteBack()
+}
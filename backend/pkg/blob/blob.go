@@ -0,0 +1,34 @@
+// Package blob 保存图片、附件等二进制对象，按存储类型选择实现，目前支持本地文件系统
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/spf13/viper"
+)
+
+// ErrNotFound 对象不存在
+var ErrNotFound = errors.New("blob: not found")
+
+// Store 以 key 存取对象，key 由调用方生成，只包含字母、数字、下划线和短横线
+type Store interface {
+	Put(ctx context.Context, key string, r io.Reader) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Exists(ctx context.Context, key string) (bool, error)
+}
+
+// NewStore 根据 media.store.type 创建存储，未配置时使用本地文件系统
+func NewStore(conf *viper.Viper) Store {
+	switch conf.GetString("media.store.type") {
+	case "", "local":
+		dir := conf.GetString("media.store.dir")
+		if dir == "" {
+			dir = "storage/media"
+		}
+		return NewLocalStore(dir)
+	default:
+		panic("unknown media store type")
+	}
+}
@@ -0,0 +1,120 @@
+// Package dateparse 解析表格中手工填写的日期，兼容中文日期、斜杠和点分隔、Excel 序列号以及省略年份的写法
+package dateparse
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalid 无法识别的日期
+var ErrInvalid = errors.New("dateparse: invalid date")
+
+var (
+	// dateRegexp 年-月-日，可带时间，年份可以是两位
+	dateRegexp = regexp.MustCompile(`^(\d{4}|\d{2})-(\d{1,2})-(\d{1,2})(?:[ T]+(\d{1,2}):(\d{1,2})(?::(\d{1,2}))?)?$`)
+	// monthRegexp 只有年和月，取当月第一天
+	monthRegexp = regexp.MustCompile(`^(\d{4})-(\d{1,2})$`)
+	// monthDayRegexp 省略年份的月-日
+	monthDayRegexp = regexp.MustCompile(`^(\d{1,2})-(\d{1,2})$`)
+	// numberRegexp Excel 序列号、时间戳或 20230911 形式的日期
+	numberRegexp = regexp.MustCompile(`^\d+(\.\d+)?$`)
+	// weekdayRegexp 日期后面附带的星期，如 (周一)、星期二
+	weekdayRegexp = regexp.MustCompile(`[（(]?(周|星期)[一二三四五六日天][)）]?$`)
+)
+
+// replacer 统一分隔符：年、月、斜杠和点替换为横线，去掉日、号
+var replacer = strings.NewReplacer(
+	"年", "-", "月", "-", "日", "", "号", "",
+	"/", "-", ".", "-", "／", "-",
+	"０", "0", "１", "1", "２", "2", "３", "3", "４", "4",
+	"５", "5", "６", "6", "７", "7", "８", "8", "９", "9",
+)
+
+// excelEpoch Excel 序列号的起点，兼容 Excel 把 1900 年当作闰年的错误
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// Parse 按 loc 时区解析日期。省略年份时取 now 所在的年份，如果因此落在 now 之后则取上一年
+func Parse(raw string, loc *time.Location, now time.Time) (time.Time, error) {
+	value := strings.TrimSpace(raw)
+	if value == "" {
+		return time.Time{}, ErrInvalid
+	}
+	if loc == nil {
+		loc = time.Local
+	}
+
+	if numberRegexp.MatchString(value) {
+		return parseNumber(value, loc)
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.In(loc), nil
+	}
+
+	value = strings.TrimSpace(weekdayRegexp.ReplaceAllString(value, ""))
+	value = strings.TrimSuffix(replacer.Replace(value), "-")
+
+	if match := dateRegexp.FindStringSubmatch(value); match != nil {
+		year := atoi(match[1])
+		if len(match[1]) == 2 {
+			year += 2000
+		}
+		return date(year, atoi(match[2]), atoi(match[3]), atoi(match[4]), atoi(match[5]), atoi(match[6]), loc)
+	}
+	if match := monthRegexp.FindStringSubmatch(value); match != nil {
+		return date(atoi(match[1]), atoi(match[2]), 1, 0, 0, 0, loc)
+	}
+	if match := monthDayRegexp.FindStringSubmatch(value); match != nil {
+		now = now.In(loc)
+		t, err := date(now.Year(), atoi(match[1]), atoi(match[2]), 0, 0, 0, loc)
+		if err != nil || !t.After(now) {
+			return t, err
+		}
+		return date(now.Year()-1, atoi(match[1]), atoi(match[2]), 0, 0, 0, loc)
+	}
+	return time.Time{}, ErrInvalid
+}
+
+// parseNumber 8 位整数按 yyyymmdd 解析，10 位和 13 位整数分别为秒和毫秒时间戳，其余为 Excel 序列号
+func parseNumber(value string, loc *time.Location) (time.Time, error) {
+	if len(value) == 8 {
+		if t, err := date(atoi(value[:4]), atoi(value[4:6]), atoi(value[6:]), 0, 0, 0, loc); err == nil {
+			return t, nil
+		}
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return time.Time{}, ErrInvalid
+	}
+	switch {
+	case len(value) == 13:
+		return time.UnixMilli(int64(number)).In(loc), nil
+	case len(value) == 10:
+		return time.Unix(int64(number), 0).In(loc), nil
+	// 2958465 为 9999-12-31
+	case number >= 1 && number <= 2958465:
+		days := int(number)
+		seconds := int((number - float64(days)) * 86400)
+		t := excelEpoch.AddDate(0, 0, days).Add(time.Duration(seconds) * time.Second)
+		// 序列号表示的是表格所在时区的日期
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, loc), nil
+	}
+	return time.Time{}, ErrInvalid
+}
+
+// date 构造时间并检查各部分是否越界，避免 2 月 30 日被顺延到 3 月
+func date(year, month, day, hour, minute, second int, loc *time.Location) (time.Time, error) {
+	t := time.Date(year, time.Month(month), day, hour, minute, second, 0, loc)
+	if t.Year() != year || int(t.Month()) != month || t.Day() != day ||
+		t.Hour() != hour || t.Minute() != minute || t.Second() != second {
+		return time.Time{}, ErrInvalid
+	}
+	return t, nil
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
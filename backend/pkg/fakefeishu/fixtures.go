@@ -0,0 +1,224 @@
+package fakefeishu
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Fixtures 模拟服务返回的数据，目录结构为：
+//
+//	spreadsheets/<spreadsheet_token>.json  电子表格，见 Spreadsheet
+//	bitables/<app_token>.json              多维表格，见 Bitable
+//	docx/<document_id>.txt                 新版文档的纯文本内容
+//	docx/<document_id>.json                可选，新版文档的块列表，格式与块接口返回的 items 一致
+//	wiki/<node_token>.json                 可选，知识库节点对应的文档，见 WikiNode
+//	comments/<file_token>.json             可选，文档的评论列表，格式与评论接口返回的 items 一致
+//	media/<file_token>.<扩展名>             可选，文档中的图片和附件，按扩展名返回 Content-Type
+//	faults.json                            可选，启动时注入的故障列表，见 Fault
+type Fixtures struct {
+	Spreadsheets map[string]*Spreadsheet
+	Bitables     map[string]*Bitable
+	Documents    map[string]string
+	Blocks       map[string][]map[string]interface{}
+	WikiNodes    map[string]*WikiNode
+	Comments     map[string][]map[string]interface{}
+	Media        map[string]*Media
+	Faults       []Fault
+}
+
+// Spreadsheet 电子表格的元数据和各工作表的单元格
+type Spreadsheet struct {
+	LatestModifyTime string   `json:"latest_modify_time"`
+	Revision         int      `json:"revision"`
+	Sheets           []*Sheet `json:"sheets"`
+}
+
+// Sheet 一个工作表，Values 按行保存单元格，格式与 values 接口返回的一致
+type Sheet struct {
+	SheetID      string          `json:"sheet_id"`
+	Title        string          `json:"title"`
+	Index        int             `json:"index"`
+	ResourceType string          `json:"resource_type"`
+	RowCount     int             `json:"row_count"`
+	ColumnCount  int             `json:"column_count"`
+	Values       [][]interface{} `json:"values"`
+}
+
+// rowCount 未配置行数时使用数据的行数
+func (s *Sheet) rowCount() int {
+	if s.RowCount > 0 {
+		return s.RowCount
+	}
+	return len(s.Values)
+}
+
+// columnCount 未配置列数时使用数据中最宽一行的列数
+func (s *Sheet) columnCount() int {
+	if s.ColumnCount > 0 {
+		return s.ColumnCount
+	}
+	count := 0
+	for _, row := range s.Values {
+		if len(row) > count {
+			count = len(row)
+		}
+	}
+	return count
+}
+
+// Bitable 多维表格的元数据和各数据表的记录
+type Bitable struct {
+	LatestModifyTime string          `json:"latest_modify_time"`
+	Tables           []*BitableTable `json:"tables"`
+}
+
+// BitableTable 一个数据表，Records 的格式与 records 接口返回的一致
+type BitableTable struct {
+	TableID string           `json:"table_id"`
+	Name    string           `json:"name"`
+	Records []*BitableRecord `json:"records"`
+}
+
+// BitableRecord 一条记录，Fields 以字段名称为键
+type BitableRecord struct {
+	RecordID string                 `json:"record_id"`
+	Fields   map[string]interface{} `json:"fields"`
+}
+
+// WikiNode 知识库节点，ObjType 为节点对应的文档类型，如 docx、sheet、bitable
+type WikiNode struct {
+	ObjToken string `json:"obj_token"`
+	ObjType  string `json:"obj_type"`
+	Title    string `json:"title"`
+}
+
+// Media 一个素材文件
+type Media struct {
+	FileName    string
+	ContentType string
+	Data        []byte
+}
+
+func NewFixtures() *Fixtures {
+	return &Fixtures{
+		Spreadsheets: make(map[string]*Spreadsheet),
+		Bitables:     make(map[string]*Bitable),
+		Documents:    make(map[string]string),
+		Blocks:       make(map[string][]map[string]interface{}),
+		WikiNodes:    make(map[string]*WikiNode),
+		Comments:     make(map[string][]map[string]interface{}),
+		Media:        make(map[string]*Media),
+	}
+}
+
+// LoadFixtures 从目录中加载数据，缺少的子目录视为空
+func LoadFixtures(dir string) (*Fixtures, error) {
+	fixtures := NewFixtures()
+
+	files, err := filepath.Glob(filepath.Join(dir, "spreadsheets", "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		var spreadsheet Spreadsheet
+		if err = readJSON(file, &spreadsheet); err != nil {
+			return nil, err
+		}
+		fixtures.Spreadsheets[strings.TrimSuffix(filepath.Base(file), ".json")] = &spreadsheet
+	}
+
+	if files, err = filepath.Glob(filepath.Join(dir, "bitables", "*.json")); err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		var bitable Bitable
+		if err = readJSON(file, &bitable); err != nil {
+			return nil, err
+		}
+		fixtures.Bitables[strings.TrimSuffix(filepath.Base(file), ".json")] = &bitable
+	}
+
+	if files, err = filepath.Glob(filepath.Join(dir, "docx", "*.txt")); err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		fixtures.Documents[strings.TrimSuffix(filepath.Base(file), ".txt")] = string(content)
+	}
+
+	if files, err = filepath.Glob(filepath.Join(dir, "docx", "*.json")); err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		var blocks []map[string]interface{}
+		if err = readJSON(file, &blocks); err != nil {
+			return nil, err
+		}
+		fixtures.Blocks[strings.TrimSuffix(filepath.Base(file), ".json")] = blocks
+	}
+
+	if files, err = filepath.Glob(filepath.Join(dir, "wiki", "*.json")); err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		var node WikiNode
+		if err = readJSON(file, &node); err != nil {
+			return nil, err
+		}
+		fixtures.WikiNodes[strings.TrimSuffix(filepath.Base(file), ".json")] = &node
+	}
+
+	if files, err = filepath.Glob(filepath.Join(dir, "comments", "*.json")); err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		var comments []map[string]interface{}
+		if err = readJSON(file, &comments); err != nil {
+			return nil, err
+		}
+		fixtures.Comments[strings.TrimSuffix(filepath.Base(file), ".json")] = comments
+	}
+
+	if files, err = filepath.Glob(filepath.Join(dir, "media", "*")); err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		name := filepath.Base(file)
+		fixtures.Media[strings.TrimSuffix(name, filepath.Ext(name))] = &Media{
+			FileName:    name,
+			ContentType: mime.TypeByExtension(filepath.Ext(name)),
+			Data:        data,
+		}
+	}
+
+	faultsFile := filepath.Join(dir, "faults.json")
+	if _, err = os.Stat(faultsFile); err == nil {
+		if err = readJSON(faultsFile, &fixtures.Faults); err != nil {
+			return nil, err
+		}
+	}
+
+	return fixtures, nil
+}
+
+func readJSON(file string, out interface{}) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("error decoding %s: %w", file, err)
+	}
+	return nil
+}
@@ -0,0 +1,735 @@
+// Package fakefeishu 模拟飞书开放平台的部分接口，用于在没有飞书凭证的情况下运行同步流程。
+// 既可以通过 cmd/fakefeishu 独立启动，也可以在测试中配合 httptest 使用。
+package fakefeishu
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Fault 注入的故障，匹配路径前缀的请求会返回指定的状态码和错误码，或者延迟响应
+type Fault struct {
+	// PathPrefix 匹配的接口路径前缀，如 /open-apis/docx
+	PathPrefix string `json:"path_prefix"`
+	Status     int    `json:"status"`
+	Code       int    `json:"code"`
+	Msg        string `json:"msg"`
+	// RateLimitReset 设置后返回 x-ogw-ratelimit-reset 响应头，单位为秒
+	RateLimitReset int `json:"rate_limit_reset"`
+	// DelayMs 响应前等待的毫秒数，只设置延迟时请求会在等待后正常处理
+	DelayMs int `json:"delay_ms"`
+	// Times 故障生效的次数，0 表示一直生效
+	Times int `json:"times"`
+}
+
+// RateLimited 返回限流故障
+func RateLimited(pathPrefix string, times int) Fault {
+	return Fault{PathPrefix: pathPrefix, Status: http.StatusTooManyRequests, Code: 99991400, Msg: "request trigger frequency limit", Times: times}
+}
+
+// Forbidden 返回无权限故障
+func Forbidden(pathPrefix string, times int) Fault {
+	return Fault{PathPrefix: pathPrefix, Status: http.StatusForbidden, Code: 1770032, Msg: "forBidden", Times: times}
+}
+
+// Slow 返回慢响应故障
+func Slow(pathPrefix string, delay time.Duration, times int) Fault {
+	return Fault{PathPrefix: pathPrefix, DelayMs: int(delay / time.Millisecond), Times: times}
+}
+
+// Server 模拟的飞书开放平台
+type Server struct {
+	mu       sync.Mutex
+	fixtures *Fixtures
+	faults   []*Fault
+	tokens   map[string]bool
+	issued   int
+	requests map[string]int
+}
+
+func NewServer(fixtures *Fixtures) *Server {
+	if fixtures == nil {
+		fixtures = NewFixtures()
+	}
+	s := &Server{
+		fixtures: fixtures,
+		tokens:   make(map[string]bool),
+		requests: make(map[string]int),
+	}
+	for _, fault := range fixtures.Faults {
+		s.AddFault(fault)
+	}
+	return s
+}
+
+// AddFault 注入一个故障，多个故障按注入顺序匹配
+func (s *Server) AddFault(fault Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &fault)
+}
+
+// ClearFaults 清除所有故障
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// SetSpreadsheet 新增或替换电子表格
+func (s *Server) SetSpreadsheet(token string, spreadsheet *Spreadsheet) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fixtures.Spreadsheets[token] = spreadsheet
+}
+
+// SetBitable 新增或替换多维表格
+func (s *Server) SetBitable(appToken string, bitable *Bitable) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fixtures.Bitables[appToken] = bitable
+}
+
+// SetComments 新增或替换文档的评论
+func (s *Server) SetComments(fileToken string, comments []map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fixtures.Comments[fileToken] = comments
+}
+
+// SetMedia 新增或替换素材文件
+func (s *Server) SetMedia(fileToken string, media *Media) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fixtures.Media[fileToken] = media
+}
+
+// SetDocument 新增或替换新版文档的内容
+func (s *Server) SetDocument(documentID string, content string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fixtures.Documents[documentID] = content
+}
+
+// SetDocumentBlocks 新增或替换新版文档的块列表
+func (s *Server) SetDocumentBlocks(documentID string, blocks []map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fixtures.Blocks[documentID] = blocks
+}
+
+// SetWikiNode 新增或替换知识库节点
+func (s *Server) SetWikiNode(nodeToken string, node *WikiNode) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fixtures.WikiNodes[nodeToken] = node
+}
+
+// ExpireTokens 使已签发的所有凭证失效
+func (s *Server) ExpireTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens = make(map[string]bool)
+}
+
+// Requests 返回路径前缀匹配的请求次数
+func (s *Server) Requests(pathPrefix string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count := 0
+	for path, n := range s.requests {
+		if strings.HasPrefix(path, pathPrefix) {
+			count += n
+		}
+	}
+	return count
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+
+	s.mu.Lock()
+	s.requests[path]++
+	fault := s.matchFault(path)
+	s.mu.Unlock()
+
+	if fault != nil {
+		if fault.DelayMs > 0 {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(time.Duration(fault.DelayMs) * time.Millisecond):
+			}
+		}
+		if fault.Status != 0 || fault.Code != 0 {
+			if fault.RateLimitReset > 0 {
+				w.Header().Set("x-ogw-ratelimit-reset", strconv.Itoa(fault.RateLimitReset))
+			}
+			status := fault.Status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			writeError(w, status, fault.Code, fault.Msg)
+			return
+		}
+	}
+
+	if path == "/open-apis/auth/v3/tenant_access_token/internal" {
+		s.tenantAccessToken(w, r)
+		return
+	}
+
+	if !s.authorized(r) {
+		writeError(w, http.StatusBadRequest, 99991663, "Invalid access token for authorization. Please make a request with token attached.")
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/open-apis/sheets/v3/spreadsheets/") && strings.HasSuffix(path, "/sheets/query"):
+		s.querySheets(w, strings.TrimSuffix(strings.TrimPrefix(path, "/open-apis/sheets/v3/spreadsheets/"), "/sheets/query"))
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/open-apis/sheets/v2/spreadsheets/") && strings.Contains(path, "/values/"):
+		parts := strings.SplitN(strings.TrimPrefix(path, "/open-apis/sheets/v2/spreadsheets/"), "/values/", 2)
+		s.values(w, parts[0], parts[1])
+	case r.Method == http.MethodPost && strings.HasPrefix(path, "/open-apis/sheets/v2/spreadsheets/") && strings.HasSuffix(path, "/values_batch_update"):
+		s.batchUpdateValues(w, r, strings.TrimSuffix(strings.TrimPrefix(path, "/open-apis/sheets/v2/spreadsheets/"), "/values_batch_update"))
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/open-apis/bitable/v1/apps/") && strings.HasSuffix(path, "/records"):
+		parts := strings.Split(strings.TrimPrefix(path, "/open-apis/bitable/v1/apps/"), "/")
+		s.bitableRecords(w, r, parts[0], parts[2])
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/open-apis/bitable/v1/apps/") && strings.HasSuffix(path, "/tables"):
+		s.bitableTables(w, strings.TrimSuffix(strings.TrimPrefix(path, "/open-apis/bitable/v1/apps/"), "/tables"))
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/open-apis/drive/v1/files/") && strings.HasSuffix(path, "/comments"):
+		s.comments(w, r, strings.TrimSuffix(strings.TrimPrefix(path, "/open-apis/drive/v1/files/"), "/comments"))
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/open-apis/drive/v1/medias/") && strings.HasSuffix(path, "/download"):
+		s.downloadMedia(w, strings.TrimSuffix(strings.TrimPrefix(path, "/open-apis/drive/v1/medias/"), "/download"))
+	case r.Method == http.MethodPost && path == "/open-apis/drive/v1/metas/batch_query":
+		s.batchQueryMeta(w, r)
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/open-apis/docx/v1/documents/") && strings.HasSuffix(path, "/raw_content"):
+		s.rawContent(w, strings.TrimSuffix(strings.TrimPrefix(path, "/open-apis/docx/v1/documents/"), "/raw_content"))
+	case r.Method == http.MethodGet && path == "/open-apis/wiki/v2/spaces/get_node":
+		s.wikiNode(w, r.URL.Query().Get("token"))
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/open-apis/docx/v1/documents/") && strings.HasSuffix(path, "/blocks"):
+		s.documentBlocks(w, r, strings.TrimSuffix(strings.TrimPrefix(path, "/open-apis/docx/v1/documents/"), "/blocks"))
+	default:
+		writeError(w, http.StatusNotFound, 404, "404 page not found")
+	}
+}
+
+// matchFault 返回第一个匹配且仍然生效的故障，调用方需持有锁
+func (s *Server) matchFault(path string) *Fault {
+	for i, fault := range s.faults {
+		if !strings.HasPrefix(path, fault.PathPrefix) {
+			continue
+		}
+		if fault.Times > 0 {
+			fault.Times--
+			if fault.Times == 0 {
+				s.faults = append(s.faults[:i:i], s.faults[i+1:]...)
+			}
+		}
+		return fault
+	}
+	return nil
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tokens[token]
+}
+
+func (s *Server) tenantAccessToken(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		AppID     string `json:"app_id"`
+		AppSecret string `json:"app_secret"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.AppID == "" || req.AppSecret == "" {
+		writeError(w, http.StatusBadRequest, 10003, "invalid param")
+		return
+	}
+
+	s.mu.Lock()
+	s.issued++
+	token := fmt.Sprintf("t-fake-%d", s.issued)
+	s.tokens[token] = true
+	s.mu.Unlock()
+
+	writeJSON(w, map[string]interface{}{
+		"code":                0,
+		"msg":                 "ok",
+		"tenant_access_token": token,
+		"expire":              7200,
+	})
+}
+
+func (s *Server) spreadsheet(token string) *Spreadsheet {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fixtures.Spreadsheets[token]
+}
+
+func (s *Server) querySheets(w http.ResponseWriter, token string) {
+	spreadsheet := s.spreadsheet(token)
+	if spreadsheet == nil {
+		writeError(w, http.StatusBadRequest, 1310214, "spreadsheet not found")
+		return
+	}
+
+	sheets := make([]map[string]interface{}, 0, len(spreadsheet.Sheets))
+	for _, sheet := range spreadsheet.Sheets {
+		resourceType := sheet.ResourceType
+		if resourceType == "" {
+			resourceType = "sheet"
+		}
+		sheets = append(sheets, map[string]interface{}{
+			"sheet_id":      sheet.SheetID,
+			"title":         sheet.Title,
+			"index":         sheet.Index,
+			"hidden":        false,
+			"resource_type": resourceType,
+			"grid_properties": map[string]interface{}{
+				"frozen_row_count":    0,
+				"frozen_column_count": 0,
+				"row_count":           sheet.rowCount(),
+				"column_count":        sheet.columnCount(),
+			},
+		})
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"code": 0,
+		"msg":  "success",
+		"data": map[string]interface{}{"sheets": sheets},
+	})
+}
+
+// rangeRegexp 匹配 <sheetId>!A1:C10 形式的范围，单元格部分可以省略
+var rangeRegexp = regexp.MustCompile(`^([^!]+)(?:!([A-Z]+)(\d+)?(?::([A-Z]+)(\d+)?)?)?$`)
+
+func (s *Server) values(w http.ResponseWriter, token string, valueRange string) {
+	spreadsheet := s.spreadsheet(token)
+	if spreadsheet == nil {
+		writeError(w, http.StatusBadRequest, 1310214, "spreadsheet not found")
+		return
+	}
+
+	matches := rangeRegexp.FindStringSubmatch(valueRange)
+	if matches == nil {
+		writeError(w, http.StatusBadRequest, 90202, "wrong range")
+		return
+	}
+
+	var sheet *Sheet
+	for _, sh := range spreadsheet.Sheets {
+		if sh.SheetID == matches[1] {
+			sheet = sh
+		}
+	}
+	if sheet == nil {
+		writeError(w, http.StatusBadRequest, 90215, "sheetId not found")
+		return
+	}
+
+	firstColumn, lastColumn := 0, sheet.columnCount()-1
+	firstRow, lastRow := 1, sheet.rowCount()
+	if matches[2] != "" {
+		firstColumn = columnIndex(matches[2])
+		lastColumn = firstColumn
+		if matches[4] != "" {
+			lastColumn = columnIndex(matches[4])
+		}
+	}
+	if matches[3] != "" {
+		firstRow, _ = strconv.Atoi(matches[3])
+		lastRow = firstRow
+		if matches[5] != "" {
+			lastRow, _ = strconv.Atoi(matches[5])
+		}
+	}
+	if lastRow > sheet.rowCount() {
+		lastRow = sheet.rowCount()
+	}
+
+	values := make([][]interface{}, 0)
+	for rowIndex := firstRow; rowIndex <= lastRow; rowIndex++ {
+		row := make([]interface{}, lastColumn-firstColumn+1)
+		if rowIndex-1 < len(sheet.Values) {
+			source := sheet.Values[rowIndex-1]
+			for column := firstColumn; column <= lastColumn && column < len(source); column++ {
+				row[column-firstColumn] = source[column]
+			}
+		}
+		values = append(values, row)
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"code": 0,
+		"msg":  "success",
+		"data": map[string]interface{}{
+			"revision":         spreadsheet.Revision,
+			"spreadsheetToken": token,
+			"valueRange": map[string]interface{}{
+				"majorDimension": "ROWS",
+				"range":          valueRange,
+				"revision":       spreadsheet.Revision,
+				"values":         values,
+			},
+		},
+	})
+}
+
+// batchUpdateValues 将写入的值保存到工作表中，每次写入后版本号加一并更新修改时间
+func (s *Server) batchUpdateValues(w http.ResponseWriter, r *http.Request, token string) {
+	var req struct {
+		ValueRanges []struct {
+			Range  string          `json:"range"`
+			Values [][]interface{} `json:"values"`
+		} `json:"valueRanges"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, 90201, "wrong request body")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	spreadsheet := s.fixtures.Spreadsheets[token]
+	if spreadsheet == nil {
+		writeError(w, http.StatusBadRequest, 1310214, "spreadsheet not found")
+		return
+	}
+
+	updatedCells := 0
+	for _, valueRange := range req.ValueRanges {
+		matches := rangeRegexp.FindStringSubmatch(valueRange.Range)
+		if matches == nil || matches[2] == "" || matches[3] == "" {
+			writeError(w, http.StatusBadRequest, 90202, "wrong range")
+			return
+		}
+		var sheet *Sheet
+		for _, sh := range spreadsheet.Sheets {
+			if sh.SheetID == matches[1] {
+				sheet = sh
+			}
+		}
+		if sheet == nil {
+			writeError(w, http.StatusBadRequest, 90215, "sheetId not found")
+			return
+		}
+
+		firstColumn := columnIndex(matches[2])
+		firstRow, _ := strconv.Atoi(matches[3])
+		for i, row := range valueRange.Values {
+			rowIndex := firstRow - 1 + i
+			for len(sheet.Values) <= rowIndex {
+				sheet.Values = append(sheet.Values, nil)
+			}
+			for j, value := range row {
+				column := firstColumn + j
+				for len(sheet.Values[rowIndex]) <= column {
+					sheet.Values[rowIndex] = append(sheet.Values[rowIndex], nil)
+				}
+				sheet.Values[rowIndex][column] = value
+				updatedCells++
+			}
+		}
+	}
+
+	spreadsheet.Revision++
+	if modifyTime, err := strconv.ParseInt(spreadsheet.LatestModifyTime, 10, 64); err == nil {
+		spreadsheet.LatestModifyTime = strconv.FormatInt(modifyTime+1, 10)
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"code": 0,
+		"msg":  "success",
+		"data": map[string]interface{}{
+			"revision":          spreadsheet.Revision,
+			"spreadsheetToken":  token,
+			"totalUpdatedCells": updatedCells,
+		},
+	})
+}
+
+func (s *Server) batchQueryMeta(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		RequestDocs []struct {
+			DocToken string `json:"doc_token"`
+			DocType  string `json:"doc_type"`
+		} `json:"request_docs"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, 1061002, "params error")
+		return
+	}
+
+	metas := make([]map[string]interface{}, 0)
+	failed := make([]map[string]interface{}, 0)
+	for _, doc := range req.RequestDocs {
+		latestModifyTime := ""
+		switch doc.DocType {
+		case "sheet":
+			if spreadsheet := s.spreadsheet(doc.DocToken); spreadsheet != nil {
+				latestModifyTime = spreadsheet.LatestModifyTime
+			}
+		case "bitable":
+			if bitable := s.bitable(doc.DocToken); bitable != nil {
+				latestModifyTime = bitable.LatestModifyTime
+			}
+		}
+		if latestModifyTime == "" {
+			failed = append(failed, map[string]interface{}{"token": doc.DocToken, "code": 970005})
+			continue
+		}
+		metas = append(metas, map[string]interface{}{
+			"doc_token":          doc.DocToken,
+			"doc_type":           doc.DocType,
+			"title":              doc.DocToken,
+			"latest_modify_time": latestModifyTime,
+		})
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"code": 0,
+		"msg":  "success",
+		"data": map[string]interface{}{"metas": metas, "failed_list": failed},
+	})
+}
+
+func (s *Server) bitable(appToken string) *Bitable {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fixtures.Bitables[appToken]
+}
+
+func (s *Server) bitableTables(w http.ResponseWriter, appToken string) {
+	bitable := s.bitable(appToken)
+	if bitable == nil {
+		writeError(w, http.StatusNotFound, 1254040, "BaseTokenNotFound")
+		return
+	}
+
+	items := make([]map[string]interface{}, 0, len(bitable.Tables))
+	for _, table := range bitable.Tables {
+		items = append(items, map[string]interface{}{
+			"table_id": table.TableID,
+			"name":     table.Name,
+			"revision": 1,
+		})
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"code": 0,
+		"msg":  "success",
+		"data": map[string]interface{}{"has_more": false, "page_token": "", "total": len(items), "items": items},
+	})
+}
+
+// bitableRecords 按 page_size 分页返回记录，page_token 为下一页起始记录的下标
+func (s *Server) bitableRecords(w http.ResponseWriter, r *http.Request, appToken string, tableID string) {
+	bitable := s.bitable(appToken)
+	if bitable == nil {
+		writeError(w, http.StatusNotFound, 1254040, "BaseTokenNotFound")
+		return
+	}
+
+	var table *BitableTable
+	for _, t := range bitable.Tables {
+		if t.TableID == tableID {
+			table = t
+		}
+	}
+	if table == nil {
+		writeError(w, http.StatusNotFound, 1254041, "TableIdNotFound")
+		return
+	}
+
+	pageSize, _ := strconv.Atoi(r.URL.Query().Get("page_size"))
+	if pageSize <= 0 || pageSize > 500 {
+		pageSize = 20
+	}
+	start, _ := strconv.Atoi(r.URL.Query().Get("page_token"))
+	if start < 0 || start > len(table.Records) {
+		start = len(table.Records)
+	}
+	end := start + pageSize
+	if end > len(table.Records) {
+		end = len(table.Records)
+	}
+
+	pageToken := ""
+	if end < len(table.Records) {
+		pageToken = strconv.Itoa(end)
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"code": 0,
+		"msg":  "success",
+		"data": map[string]interface{}{
+			"has_more":   pageToken != "",
+			"page_token": pageToken,
+			"total":      len(table.Records),
+			"items":      table.Records[start:end],
+		},
+	})
+}
+
+// comments 按 page_size 分页返回文档的评论，page_token 为下一页起始评论的下标，没有评论的文档返回空列表
+func (s *Server) comments(w http.ResponseWriter, r *http.Request, fileToken string) {
+	s.mu.Lock()
+	comments := s.fixtures.Comments[fileToken]
+	s.mu.Unlock()
+
+	pageSize, _ := strconv.Atoi(r.URL.Query().Get("page_size"))
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 50
+	}
+	start, _ := strconv.Atoi(r.URL.Query().Get("page_token"))
+	if start < 0 || start > len(comments) {
+		start = len(comments)
+	}
+	end := start + pageSize
+	if end > len(comments) {
+		end = len(comments)
+	}
+
+	pageToken := ""
+	if end < len(comments) {
+		pageToken = strconv.Itoa(end)
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"code": 0,
+		"msg":  "success",
+		"data": map[string]interface{}{
+			"has_more":   pageToken != "",
+			"page_token": pageToken,
+			"items":      comments[start:end],
+		},
+	})
+}
+
+// downloadMedia 返回素材的原始内容，和真实接口一样通过 Content-Disposition 带上文件名
+func (s *Server) downloadMedia(w http.ResponseWriter, fileToken string) {
+	s.mu.Lock()
+	media, ok := s.fixtures.Media[fileToken]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, 1061003, "not found")
+		return
+	}
+
+	contentType := media.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": media.FileName}))
+	_, _ = w.Write(media.Data)
+}
+
+func (s *Server) rawContent(w http.ResponseWriter, documentID string) {
+	s.mu.Lock()
+	content, ok := s.fixtures.Documents[documentID]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, 1770002, "not found")
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"code": 0,
+		"msg":  "success",
+		"data": map[string]interface{}{"content": content},
+	})
+}
+
+// wikiNode 返回知识库节点对应的文档
+func (s *Server) wikiNode(w http.ResponseWriter, nodeToken string) {
+	s.mu.Lock()
+	node, ok := s.fixtures.WikiNodes[nodeToken]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, 131005, "not found")
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"code": 0,
+		"msg":  "success",
+		"data": map[string]interface{}{
+			"node": map[string]interface{}{
+				"node_token": nodeToken,
+				"obj_token":  node.ObjToken,
+				"obj_type":   node.ObjType,
+				"title":      node.Title,
+			},
+		},
+	})
+}
+
+// documentBlocks 分页返回文档的块，page_token 为下一页的起始位置
+func (s *Server) documentBlocks(w http.ResponseWriter, r *http.Request, documentID string) {
+	s.mu.Lock()
+	blocks, ok := s.fixtures.Blocks[documentID]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, 1770002, "not found")
+		return
+	}
+
+	pageSize, _ := strconv.Atoi(r.URL.Query().Get("page_size"))
+	if pageSize <= 0 || pageSize > 500 {
+		pageSize = 500
+	}
+	start, _ := strconv.Atoi(r.URL.Query().Get("page_token"))
+	if start < 0 || start > len(blocks) {
+		start = len(blocks)
+	}
+	end := start + pageSize
+	if end > len(blocks) {
+		end = len(blocks)
+	}
+
+	pageToken := ""
+	if end < len(blocks) {
+		pageToken = strconv.Itoa(end)
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"code": 0,
+		"msg":  "success",
+		"data": map[string]interface{}{
+			"has_more":   pageToken != "",
+			"page_token": pageToken,
+			"items":      blocks[start:end],
+		},
+	})
+}
+
+// columnIndex 将列字母转换为列序号，如 A -> 0
+func columnIndex(letter string) int {
+	index := 0
+	for _, r := range letter {
+		index = index*26 + int(r-'A'+1)
+	}
+	return index - 1
+}
+
+func writeJSON(w http.ResponseWriter, body interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, code int, msg string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"code": code, "msg": msg})
+}
@@ -0,0 +1,74 @@
+package feishu
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// SignBotMessage 计算自定义机器人开启签名校验时的签名，
+// 以 timestamp + "\n" + secret 为密钥对空字符串做 HmacSHA256 后 Base64 编码
+func SignBotMessage(timestamp int64, secret string) string {
+	h := hmac.New(sha256.New, []byte(strconv.FormatInt(timestamp, 10)+"\n"+secret))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// SendBotMessage 向群自定义机器人的 webhook 发送消息，secret 不为空时附带签名。
+// 机器人不需要访问凭证，webhook 为完整的地址。
+func (c *Client) SendBotMessage(ctx context.Context, webhook string, secret string, message map[string]interface{}) error {
+	body := make(map[string]interface{}, len(message)+2)
+	for key, value := range message {
+		body[key] = value
+	}
+	if secret != "" {
+		timestamp := time.Now().Unix()
+		body["timestamp"] = strconv.FormatInt(timestamp, 10)
+		body["sign"] = SignBotMessage(timestamp, secret)
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("error encoding JSON: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response body: %w", err)
+	}
+
+	// 机器人接口成功时返回 code 为 0，旧版本返回 StatusCode 为 0
+	var response struct {
+		Code       int    `json:"code"`
+		Msg        string `json:"msg"`
+		StatusCode int    `json:"StatusCode"`
+	}
+	if err = json.Unmarshal(respBody, &response); err != nil || resp.StatusCode != http.StatusOK {
+		return NewAPIError(resp.StatusCode, respBody)
+	}
+	if response.Code != 0 {
+		return &APIError{HTTPStatus: resp.StatusCode, Code: response.Code, Msg: response.Msg}
+	}
+	if response.StatusCode != 0 {
+		return &APIError{HTTPStatus: resp.StatusCode, Code: response.StatusCode, Msg: response.Msg}
+	}
+	return nil
+}
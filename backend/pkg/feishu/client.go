@@ -0,0 +1,245 @@
+package feishu
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	// DefaultBaseURL 飞书开放平台的默认地址，可通过 feishu.base_url 覆盖，如私有化部署或本地模拟服务
+	DefaultBaseURL = "https://open.feishu.cn"
+
+	defaultMaxRetries = 3
+	defaultMinBackoff = 500 * time.Millisecond
+	defaultMaxBackoff = 10 * time.Second
+	requestTimeout    = 10 * time.Second
+)
+
+// Client 调用飞书开放平台接口的客户端，统一处理凭证、错误码和重试
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	tokens     *TokenProvider
+
+	maxRetries int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+}
+
+func NewClient(conf *viper.Viper) *Client {
+	baseURL := strings.TrimRight(conf.GetString("feishu.base_url"), "/")
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	maxRetries := defaultMaxRetries
+	if conf.IsSet("feishu.max_retries") {
+		maxRetries = conf.GetInt("feishu.max_retries")
+	}
+
+	httpClient := &http.Client{Timeout: requestTimeout}
+	return &Client{
+		baseURL:    baseURL,
+		httpClient: httpClient,
+		tokens:     NewTokenProvider(httpClient, baseURL),
+		maxRetries: maxRetries,
+		minBackoff: defaultMinBackoff,
+		maxBackoff: defaultMaxBackoff,
+	}
+}
+
+// BaseURL 返回当前使用的开放平台地址
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+// TenantAccessToken 返回应用当前缓存的 tenant_access_token
+func (c *Client) TenantAccessToken(ctx context.Context, appID string, appSecret string) (string, error) {
+	return c.tokens.Token(ctx, appID, appSecret)
+}
+
+// Do 携带 tenant_access_token 调用接口并将响应解析到 out 中。
+// path 为 /open-apis 开头的接口路径，body 不为空时以 JSON 发送。
+// 限流、5xx 和网络错误会按指数退避加随机抖动重试，凭证失效时刷新凭证后重试。
+func (c *Client) Do(ctx context.Context, appID string, appSecret string, method string, path string, body interface{}, out interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("error encoding JSON: %w", err)
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := c.tokens.Do(ctx, appID, appSecret, func(token string) error {
+			return c.do(ctx, token, method, path, payload, out)
+		})
+		if err == nil || attempt >= c.maxRetries || !isRetryable(err) || ctx.Err() != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(c.backoff(attempt, err)):
+		}
+	}
+}
+
+// Download 携带 tenant_access_token 下载二进制内容，如文档中的图片和附件
+// maxSize 大于 0 时限制内容大小，超出返回 ErrTooLarge，不会把超限的内容读入内存
+func (c *Client) Download(ctx context.Context, appID string, appSecret string, path string, maxSize int64) ([]byte, http.Header, error) {
+	var data []byte
+	var header http.Header
+	for attempt := 0; ; attempt++ {
+		err := c.tokens.Do(ctx, appID, appSecret, func(token string) error {
+			resp, err := c.send(ctx, token, http.MethodGet, path, nil)
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				body, err := io.ReadAll(resp.Body)
+				if err != nil {
+					return fmt.Errorf("error reading response body: %w", err)
+				}
+				return newAPIError(resp, body)
+			}
+			if maxSize > 0 && resp.ContentLength > maxSize {
+				return fmt.Errorf("%w: content length %d exceeds %d", ErrTooLarge, resp.ContentLength, maxSize)
+			}
+
+			var reader io.Reader = resp.Body
+			if maxSize > 0 {
+				// 多读一个字节，用来判断内容是否超过上限
+				reader = io.LimitReader(resp.Body, maxSize+1)
+			}
+			body, err := io.ReadAll(reader)
+			if err != nil {
+				return fmt.Errorf("error reading response body: %w", err)
+			}
+			if maxSize > 0 && int64(len(body)) > maxSize {
+				return fmt.Errorf("%w: body exceeds %d", ErrTooLarge, maxSize)
+			}
+			data, header = body, resp.Header
+			return nil
+		})
+		if err == nil || attempt >= c.maxRetries || !isRetryable(err) || ctx.Err() != nil {
+			return data, header, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-time.After(c.backoff(attempt, err)):
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, token string, method string, path string, payload []byte) (*http.Response, error) {
+	var reader io.Reader
+	if payload != nil {
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making request: %w", err)
+	}
+	return resp, nil
+}
+
+func (c *Client) do(ctx context.Context, token string, method string, path string, payload []byte, out interface{}) error {
+	resp, err := c.send(ctx, token, method, path, payload)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response body: %w", err)
+	}
+
+	// 凭证失效、限流等情况飞书会同时返回非 200 状态码和对应的错误码
+	if resp.StatusCode != http.StatusOK {
+		return newAPIError(resp, body)
+	}
+
+	var result struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+	}
+	if err = json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("error decoding JSON: %w", err)
+	}
+	if result.Code != 0 {
+		return newAPIError(resp, body)
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err = json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("error decoding JSON: %w", err)
+	}
+	return nil
+}
+
+// backoff 返回第 attempt 次失败后的等待时间。限流响应带有重置时间时等待到重置之后，
+// 否则按指数退避，并在后一半区间内随机抖动，避免并发请求同时重试
+func (c *Client) backoff(attempt int, err error) time.Duration {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		return apiErr.RetryAfter + time.Duration(rand.Int63n(int64(c.minBackoff)+1))
+	}
+
+	d := c.minBackoff << uint(attempt)
+	if d <= 0 || d > c.maxBackoff {
+		d = c.maxBackoff
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// isRetryable 判断错误是否为临时性错误：限流、服务端错误或网络错误
+func isRetryable(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return errors.Is(err, ErrRateLimited) || apiErr.HTTPStatus >= http.StatusInternalServerError
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+// newAPIError 根据响应构造错误，并解析限流重置时间
+func newAPIError(resp *http.Response, body []byte) error {
+	err := NewAPIError(resp.StatusCode, body)
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		// x-ogw-ratelimit-reset 为距离限流重置的秒数
+		if reset, parseErr := strconv.Atoi(resp.Header.Get("x-ogw-ratelimit-reset")); parseErr == nil && reset > 0 {
+			apiErr.RetryAfter = time.Duration(reset) * time.Second
+		}
+	}
+	return err
+}
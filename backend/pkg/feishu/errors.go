@@ -0,0 +1,100 @@
+package feishu
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// 飞书接口错误的分类，可通过 errors.Is 判断
+var (
+	ErrRateLimited  = errors.New("feishu: rate limited")
+	ErrNoPermission = errors.New("feishu: no permission")
+	ErrNotFound     = errors.New("feishu: not found")
+	ErrTokenInvalid = errors.New("feishu: token invalid")
+	ErrTooLarge     = errors.New("feishu: response too large")
+)
+
+// APIError 飞书开放平台返回的业务错误
+type APIError struct {
+	HTTPStatus int
+	Code       int
+	Msg        string
+	// RetryAfter 限流时距离配额重置的时间
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	if e.HTTPStatus != 0 && e.HTTPStatus != 200 {
+		return fmt.Sprintf("request failed with status: %d, code: %d, message: %s", e.HTTPStatus, e.Code, e.Msg)
+	}
+	return fmt.Sprintf("request failed with code: %d, message: %s", e.Code, e.Msg)
+}
+
+// Unwrap 返回错误所属的分类，未归类的错误返回 nil
+func (e *APIError) Unwrap() error {
+	switch {
+	case tokenInvalidCodes[e.Code]:
+		return ErrTokenInvalid
+	case rateLimitedCodes[e.Code] || e.HTTPStatus == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case noPermissionCodes[e.Code] || e.HTTPStatus == http.StatusForbidden:
+		return ErrNoPermission
+	case notFoundCodes[e.Code] || e.HTTPStatus == http.StatusNotFound:
+		return ErrNotFound
+	}
+	return nil
+}
+
+// NewAPIError 根据响应状态码和响应体构造错误，响应体无法解析时只保留状态码
+func NewAPIError(httpStatus int, body []byte) error {
+	var response struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return &APIError{HTTPStatus: httpStatus, Msg: string(body)}
+	}
+	return &APIError{HTTPStatus: httpStatus, Code: response.Code, Msg: response.Msg}
+}
+
+// tokenInvalidCodes 访问凭证无效或过期的错误码
+var tokenInvalidCodes = map[int]bool{
+	99991661: true, // 缺少 access token
+	99991663: true, // tenant access token 无效
+	99991664: true, // app access token 无效
+	99991671: true, // token 格式错误
+}
+
+// rateLimitedCodes 触发频率限制的错误码
+var rateLimitedCodes = map[int]bool{
+	99991400: true, // 应用频率限制
+	1254290:  true, // 电子表格请求过于频繁
+	9499:     true, // 自定义机器人发送频率超限
+}
+
+// noPermissionCodes 应用缺少权限或无权访问文档的错误码
+var noPermissionCodes = map[int]bool{
+	99991672: true, // 应用未开通接口权限
+	99991679: true, // 用户未授权
+	1254302:  true, // 电子表格无访问权限
+	1770032:  true, // 新版文档无访问权限
+	131006:   true, // 知识库无访问权限
+	91403:    true, // 多维表格无访问权限
+	1061004:  true, // 云空间无访问权限
+}
+
+// notFoundCodes 文档或资源不存在的错误码
+var notFoundCodes = map[int]bool{
+	1770002: true, // 新版文档不存在
+	131005:  true, // 知识库节点不存在
+	91402:   true, // 多维表格不存在
+	1061003: true, // 云空间文件不存在
+}
+
+// IsTokenInvalid 判断错误是否由访问凭证失效引起
+func IsTokenInvalid(err error) bool {
+	return errors.Is(err, ErrTokenInvalid)
+}
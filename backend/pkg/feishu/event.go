@@ -0,0 +1,54 @@
+package feishu
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// DecryptEvent 解密事件订阅推送的 encrypt 字段。
+// 使用 AES-256-CBC，密钥为 Encrypt Key 的 SHA256，密文的前 16 字节为 IV
+func DecryptEvent(encryptKey string, encrypted string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding encrypted event: %w", err)
+	}
+	if len(data) < aes.BlockSize || len(data)%aes.BlockSize != 0 {
+		return nil, errors.New("encrypted event has invalid length")
+	}
+
+	key := sha256.Sum256([]byte(encryptKey))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+
+	iv, data := data[:aes.BlockSize], data[aes.BlockSize:]
+	if len(data) == 0 {
+		return nil, errors.New("encrypted event is empty")
+	}
+	plain := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain, data)
+
+	// 去除 PKCS7 填充
+	padding := int(plain[len(plain)-1])
+	if padding == 0 || padding > aes.BlockSize || padding > len(plain) {
+		return nil, errors.New("encrypted event has invalid padding")
+	}
+	return plain[:len(plain)-padding], nil
+}
+
+// VerifyEventSignature 校验事件请求头中的 X-Lark-Signature，
+// 签名为 sha256(timestamp + nonce + encryptKey + body) 的十六进制
+func VerifyEventSignature(timestamp string, nonce string, encryptKey string, body []byte, signature string) bool {
+	h := sha256.New()
+	h.Write([]byte(timestamp + nonce + encryptKey))
+	h.Write(body)
+	expected := hex.EncodeToString(h.Sum(nil))
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(signature)) == 1
+}
@@ -0,0 +1,163 @@
+package feishu
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	tenantAccessTokenPath = "/open-apis/auth/v3/tenant_access_token/internal"
+	// tokenRefreshMargin 距离过期不足该时间时提前刷新，避免请求途中过期
+	tokenRefreshMargin = 5 * time.Minute
+)
+
+// TokenProvider 按应用缓存 tenant_access_token，在即将过期前刷新。
+// 并发请求同一个应用的凭证时只会向飞书发起一次请求。
+type TokenProvider struct {
+	client  *http.Client
+	baseURL string
+	now     func() time.Time
+
+	mu       sync.Mutex
+	tokens   map[string]*cachedToken
+	inflight map[string]*tokenCall
+}
+
+type cachedToken struct {
+	token    string
+	expireAt time.Time
+}
+
+type tokenCall struct {
+	done  chan struct{}
+	token string
+	err   error
+}
+
+func NewTokenProvider(client *http.Client, baseURL string) *TokenProvider {
+	return &TokenProvider{
+		client:   client,
+		baseURL:  baseURL,
+		now:      time.Now,
+		tokens:   make(map[string]*cachedToken),
+		inflight: make(map[string]*tokenCall),
+	}
+}
+
+// Token 返回应用可用的 tenant_access_token，缓存未命中或即将过期时重新获取
+func (p *TokenProvider) Token(ctx context.Context, appID string, appSecret string) (string, error) {
+	p.mu.Lock()
+	if cached, ok := p.tokens[appID]; ok && p.now().Add(tokenRefreshMargin).Before(cached.expireAt) {
+		p.mu.Unlock()
+		return cached.token, nil
+	}
+	if call, ok := p.inflight[appID]; ok {
+		p.mu.Unlock()
+		select {
+		case <-call.done:
+			return call.token, call.err
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+	call := &tokenCall{done: make(chan struct{})}
+	p.inflight[appID] = call
+	p.mu.Unlock()
+
+	// 使用独立的 context，避免发起者取消后其他等待者拿到取消错误
+	fetchCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	token, expire, err := p.fetch(fetchCtx, appID, appSecret)
+	cancel()
+
+	p.mu.Lock()
+	if err == nil {
+		p.tokens[appID] = &cachedToken{
+			token:    token,
+			expireAt: p.now().Add(time.Duration(expire) * time.Second),
+		}
+	}
+	delete(p.inflight, appID)
+	p.mu.Unlock()
+
+	call.token, call.err = token, err
+	close(call.done)
+	return token, err
+}
+
+// Invalidate 丢弃缓存的凭证，只有缓存中仍是该凭证时才会丢弃，避免覆盖其他协程刚刷新的凭证
+func (p *TokenProvider) Invalidate(appID string, token string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if cached, ok := p.tokens[appID]; ok && cached.token == token {
+		delete(p.tokens, appID)
+	}
+}
+
+// Do 使用凭证调用 fn，如果飞书返回凭证无效的错误码，则刷新凭证后重试一次
+func (p *TokenProvider) Do(ctx context.Context, appID string, appSecret string, fn func(token string) error) error {
+	token, err := p.Token(ctx, appID, appSecret)
+	if err != nil {
+		return err
+	}
+	err = fn(token)
+	if !IsTokenInvalid(err) {
+		return err
+	}
+
+	p.Invalidate(appID, token)
+	if token, err = p.Token(ctx, appID, appSecret); err != nil {
+		return err
+	}
+	return fn(token)
+}
+
+func (p *TokenProvider) fetch(ctx context.Context, appID string, appSecret string) (string, int, error) {
+	// 请求体数据
+	jsonData, err := json.Marshal(map[string]string{
+		"app_id":     appID,
+		"app_secret": appSecret,
+	})
+	if err != nil {
+		return "", 0, fmt.Errorf("error encoding JSON: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+tenantAccessTokenPath, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", 0, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", 0, fmt.Errorf("error making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", 0, fmt.Errorf("error reading response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, NewAPIError(resp.StatusCode, body)
+	}
+
+	var response struct {
+		Code              int    `json:"code"`
+		Msg               string `json:"msg"`
+		TenantAccessToken string `json:"tenant_access_token"`
+		Expire            int    `json:"expire"`
+	}
+	if err = json.Unmarshal(body, &response); err != nil {
+		return "", 0, fmt.Errorf("error decoding JSON: %w", err)
+	}
+	if response.Code != 0 {
+		return "", 0, &APIError{HTTPStatus: resp.StatusCode, Code: response.Code, Msg: response.Msg}
+	}
+	return response.TenantAccessToken, response.Expire, nil
+}
@@ -0,0 +1,122 @@
+package llm
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// sentenceEnds 超长段落按这些标点切分为句子
+const sentenceEnds = "。！？；!?;"
+
+// EstimateTokens 估算文本的 token 数，不依赖具体模型的分词器：
+// 中日韩文字和全角标点按每个字一个 token，其余字符按每 4 个字节一个 token
+func EstimateTokens(text string) int {
+	wide, other := 0, 0
+	for _, r := range text {
+		if isWide(r) {
+			wide++
+		} else {
+			other += utf8.RuneLen(r)
+		}
+	}
+	return wide + (other+3)/4
+}
+
+func isWide(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) ||
+		(r >= 0x3000 && r <= 0x303f) || (r >= 0xff00 && r <= 0xffef)
+}
+
+// SplitText 将文本切分为估算 token 数不超过 maxTokens 的分段，优先在段落处切分，
+// 其次在句末标点处，仍然过长时按字切分。各分段按顺序拼接后与原文相同，只包含空白的分段除外
+func SplitText(text string, maxTokens int) []string {
+	if maxTokens <= 0 || EstimateTokens(text) <= maxTokens {
+		return []string{text}
+	}
+
+	var chunks []string
+	var current strings.Builder
+	currentTokens := 0
+	flush := func() {
+		if strings.TrimSpace(current.String()) != "" {
+			chunks = append(chunks, current.String())
+		}
+		current.Reset()
+		currentTokens = 0
+	}
+	for _, piece := range splitPieces(text, maxTokens) {
+		tokens := EstimateTokens(piece)
+		if currentTokens+tokens > maxTokens {
+			flush()
+		}
+		current.WriteString(piece)
+		currentTokens += tokens
+	}
+	flush()
+	return chunks
+}
+
+// splitPieces 将文本切分为不超过 maxTokens 的最小单位：段落、句子或一段文字
+func splitPieces(text string, maxTokens int) []string {
+	var pieces []string
+	for _, paragraph := range strings.SplitAfter(text, "\n") {
+		if EstimateTokens(paragraph) <= maxTokens {
+			pieces = append(pieces, paragraph)
+			continue
+		}
+		for _, sentence := range splitSentences(paragraph) {
+			if EstimateTokens(sentence) <= maxTokens {
+				pieces = append(pieces, sentence)
+				continue
+			}
+			pieces = append(pieces, splitRunes(sentence, maxTokens)...)
+		}
+	}
+	return pieces
+}
+
+// splitSentences 在句末标点之后切分，标点保留在句子末尾
+func splitSentences(paragraph string) []string {
+	var sentences []string
+	start := 0
+	for i, r := range paragraph {
+		if strings.ContainsRune(sentenceEnds, r) {
+			end := i + utf8.RuneLen(r)
+			sentences = append(sentences, paragraph[start:end])
+			start = end
+		}
+	}
+	if start < len(paragraph) {
+		sentences = append(sentences, paragraph[start:])
+	}
+	return sentences
+}
+
+// splitRunes 逐字累计，估算的 token 数即将超过 maxTokens 时切分
+func splitRunes(text string, maxTokens int) []string {
+	var pieces []string
+	start, wide, other := 0, 0, 0
+	for i, r := range text {
+		nextWide, nextOther := wide, other
+		if isWide(r) {
+			nextWide++
+		} else {
+			nextOther += utf8.RuneLen(r)
+		}
+		if nextWide+(nextOther+3)/4 > maxTokens && i > start {
+			pieces = append(pieces, text[start:i])
+			start, nextWide, nextOther = i, 0, 0
+			if isWide(r) {
+				nextWide = 1
+			} else {
+				nextOther = utf8.RuneLen(r)
+			}
+		}
+		wide, other = nextWide, nextOther
+	}
+	if start < len(text) {
+		pieces = append(pieces, text[start:])
+	}
+	return pieces
+}
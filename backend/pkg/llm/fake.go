@@ -0,0 +1,82 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"unicode/utf8"
+)
+
+// Fake 不访问网络的假模型，根据输入生成固定格式的结果，相同的输入总是得到相同的输出。
+// 摘要取正文开头的文字，关键字取正文中前几个不重复的词，JSON 模式下以 JSON 对象返回，token 数按字符数计算
+type Fake struct {
+	options Options
+}
+
+func NewFake(options Options) *Fake {
+	return &Fake{options: options}
+}
+
+func (p *Fake) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	chat := p.options.apply(req)
+	if chat.Model == "" {
+		chat.Model = "fake"
+	}
+
+	var content string
+	promptTokens := 0
+	for _, message := range chat.Messages {
+		promptTokens += utf8.RuneCountInString(message.Content)
+		if message.Role == RoleUser {
+			content = message.Content
+		}
+	}
+	words := strings.Fields(content)
+	if len(words) == 0 {
+		return nil, ErrEmptyResponse
+	}
+
+	var keywords []string
+	seen := make(map[string]bool)
+	for _, word := range words {
+		word = truncateRunes(strings.Trim(word, "#*-:：,，.。!！?？()（）[]【】"), 8)
+		if word == "" || seen[word] {
+			continue
+		}
+		seen[word] = true
+		keywords = append(keywords, word)
+		if len(keywords) == 3 {
+			break
+		}
+	}
+
+	abstract := truncateRunes(strings.Join(words, " "), 50)
+	reply := "摘要：" + abstract + "\n关键字：" + strings.Join(keywords, ",")
+	if chat.JSON {
+		data, err := json.Marshal(map[string]interface{}{"abstract": abstract, "keywords": keywords})
+		if err != nil {
+			return nil, err
+		}
+		reply = string(data)
+	}
+	if chat.MaxTokens > 0 {
+		reply = truncateRunes(reply, chat.MaxTokens)
+	}
+	return &ChatResponse{
+		Model:            chat.Model,
+		Content:          reply,
+		PromptTokens:     promptTokens,
+		CompletionTokens: utf8.RuneCountInString(reply),
+	}, nil
+}
+
+func truncateRunes(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	return string(runes[:limit])
+}
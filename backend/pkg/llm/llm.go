@@ -0,0 +1,124 @@
+// Package llm 对接生成摘要用的大模型，支持 OpenAI 兼容接口、Ollama 等本地模型服务，以及离线开发和测试用的假模型
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// 消息的角色
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// ErrEmptyResponse 模型没有返回任何内容
+var ErrEmptyResponse = errors.New("llm: empty response")
+
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// ChatRequest 一次对话请求，模型、温度和最大输出长度为空时使用配置中的值
+type ChatRequest struct {
+	Model       string
+	Messages    []Message
+	Temperature *float64
+	MaxTokens   int
+	// JSON 要求模型只输出一个 JSON 对象，提示词中需要说明 JSON 的格式
+	JSON bool
+}
+
+// ChatResponse 模型的回复和本次调用消耗的 token 数
+type ChatResponse struct {
+	Model            string
+	Content          string
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// Provider 大模型服务
+type Provider interface {
+	Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error)
+}
+
+// Options 各个服务共用的配置
+type Options struct {
+	BaseURL     string
+	APIKey      string
+	Model       string
+	Temperature *float64
+	MaxTokens   int
+	Timeout     time.Duration
+}
+
+// apply 用配置补齐请求中未指定的参数
+func (o Options) apply(req *ChatRequest) ChatRequest {
+	result := *req
+	if result.Model == "" {
+		result.Model = o.Model
+	}
+	if result.Temperature == nil {
+		result.Temperature = o.Temperature
+	}
+	if result.MaxTokens == 0 {
+		result.MaxTokens = o.MaxTokens
+	}
+	return result
+}
+
+// NewProvider 根据 llm.provider 创建模型服务：openai（默认，兼容 DeepSeek、OpenAI、通义千问等）、ollama 或 fake，
+// 其他取值返回错误
+func NewProvider(conf *viper.Viper) (Provider, error) {
+	options := Options{
+		BaseURL:   conf.GetString("llm.base_url"),
+		APIKey:    conf.GetString("llm.api_key"),
+		Model:     conf.GetString("llm.model"),
+		MaxTokens: conf.GetInt("llm.max_tokens"),
+		Timeout:   time.Duration(conf.GetInt("llm.timeout")) * time.Second,
+	}
+	if conf.IsSet("llm.temperature") {
+		temperature := conf.GetFloat64("llm.temperature")
+		options.Temperature = &temperature
+	}
+	// 兼容旧配置
+	if options.APIKey == "" {
+		options.APIKey = os.Getenv("api_key")
+	}
+	if options.APIKey == "" {
+		options.APIKey = conf.GetString("open.api_key")
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = 60 * time.Second
+	}
+
+	switch provider := conf.GetString("llm.provider"); provider {
+	case "", "openai":
+		if options.BaseURL == "" {
+			options.BaseURL = "https://api.deepseek.com"
+		}
+		if options.Model == "" {
+			options.Model = "deepseek-chat"
+		}
+		return NewOpenAI(options), nil
+	case "ollama":
+		if options.BaseURL == "" {
+			options.BaseURL = "http://127.0.0.1:11434"
+		}
+		if options.Model == "" {
+			options.Model = "qwen2.5"
+		}
+		return NewOllama(options), nil
+	case "fake":
+		return NewFake(options), nil
+	default:
+		return nil, fmt.Errorf("llm: unknown provider %q", provider)
+	}
+}
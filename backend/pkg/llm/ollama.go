@@ -0,0 +1,61 @@
+package llm
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// Ollama 本地模型服务的 /api/chat 接口
+type Ollama struct {
+	options Options
+	client  *http.Client
+}
+
+func NewOllama(options Options) *Ollama {
+	return &Ollama{
+		options: options,
+		client:  &http.Client{Timeout: options.Timeout},
+	}
+}
+
+func (p *Ollama) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	chat := p.options.apply(req)
+	options := make(map[string]interface{})
+	if chat.Temperature != nil {
+		options["temperature"] = *chat.Temperature
+	}
+	if chat.MaxTokens > 0 {
+		options["num_predict"] = chat.MaxTokens
+	}
+	payload := map[string]interface{}{
+		"model":    chat.Model,
+		"messages": chat.Messages,
+		"stream":   false,
+		"options":  options,
+	}
+	if chat.JSON {
+		payload["format"] = "json"
+	}
+
+	var response struct {
+		Model           string  `json:"model"`
+		Message         Message `json:"message"`
+		PromptEvalCount int     `json:"prompt_eval_count"`
+		EvalCount       int     `json:"eval_count"`
+	}
+	url := strings.TrimSuffix(p.options.BaseURL, "/") + "/api/chat"
+	if err := postJSON(ctx, p.client, url, p.options.APIKey, payload, &response); err != nil {
+		return nil, err
+	}
+	if response.Message.Content == "" {
+		return nil, ErrEmptyResponse
+	}
+
+	return &ChatResponse{
+		Model:            response.Model,
+		Content:          response.Message.Content,
+		PromptTokens:     response.PromptEvalCount,
+		CompletionTokens: response.EvalCount,
+	}, nil
+}
@@ -0,0 +1,106 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// OpenAI 兼容 OpenAI Chat Completions 接口的服务，BaseURL 不包含 /chat/completions
+type OpenAI struct {
+	options Options
+	client  *http.Client
+}
+
+func NewOpenAI(options Options) *OpenAI {
+	return &OpenAI{
+		options: options,
+		client:  &http.Client{Timeout: options.Timeout},
+	}
+}
+
+func (p *OpenAI) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	chat := p.options.apply(req)
+	payload := map[string]interface{}{
+		"model":    chat.Model,
+		"messages": chat.Messages,
+		"stream":   false,
+	}
+	if chat.Temperature != nil {
+		payload["temperature"] = *chat.Temperature
+	}
+	if chat.MaxTokens > 0 {
+		payload["max_tokens"] = chat.MaxTokens
+	}
+	if chat.JSON {
+		payload["response_format"] = map[string]string{"type": "json_object"}
+	}
+
+	var response struct {
+		Model   string `json:"model"`
+		Choices []struct {
+			Message Message `json:"message"`
+		} `json:"choices"`
+		Usage struct {
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+		} `json:"usage"`
+	}
+	url := strings.TrimSuffix(p.options.BaseURL, "/") + "/chat/completions"
+	if err := postJSON(ctx, p.client, url, p.options.APIKey, payload, &response); err != nil {
+		return nil, err
+	}
+
+	for _, choice := range response.Choices {
+		if choice.Message.Role == RoleAssistant {
+			return &ChatResponse{
+				Model:            response.Model,
+				Content:          choice.Message.Content,
+				PromptTokens:     response.Usage.PromptTokens,
+				CompletionTokens: response.Usage.CompletionTokens,
+			}, nil
+		}
+	}
+	return nil, ErrEmptyResponse
+}
+
+// postJSON 发送 JSON 请求并解析 JSON 响应，apiKey 为空时不带 Authorization
+func postJSON(ctx context.Context, client *http.Client, url string, apiKey string, payload interface{}, out interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("error marshalling JSON: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		if len(data) > 512 {
+			data = data[:512]
+		}
+		return fmt.Errorf("llm: request failed with status %d: %s", resp.StatusCode, data)
+	}
+	if err = json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("error unmarshalling JSON: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+// Package readability 从网页 HTML 中提取标题和正文文本。
+package readability
+
+import (
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// ignoredAtoms 不参与正文提取的元素
+var ignoredAtoms = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Noscript: true,
+	atom.Nav:      true,
+	atom.Header:   true,
+	atom.Footer:   true,
+	atom.Aside:    true,
+	atom.Form:     true,
+	atom.Iframe:   true,
+	atom.Svg:      true,
+	atom.Button:   true,
+	atom.Select:   true,
+}
+
+// blockAtoms 提取文本时在前后换行的块级元素
+var blockAtoms = map[atom.Atom]bool{
+	atom.P:          true,
+	atom.Div:        true,
+	atom.Section:    true,
+	atom.Article:    true,
+	atom.Br:         true,
+	atom.Li:         true,
+	atom.Tr:         true,
+	atom.H1:         true,
+	atom.H2:         true,
+	atom.H3:         true,
+	atom.H4:         true,
+	atom.H5:         true,
+	atom.H6:         true,
+	atom.Blockquote: true,
+	atom.Pre:        true,
+}
+
+// contentIDs 常见站点中正文容器的 id，如微信公众号文章的 js_content
+var contentIDs = []string{"js_content", "content", "article", "main-content"}
+
+// Article 提取出的网页内容
+type Article struct {
+	Title string
+	Text  string
+}
+
+// Extract 解析 HTML 并提取标题和正文。优先使用已知的正文容器，否则选择段落文本最多的元素
+func Extract(r io.Reader) (*Article, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+
+	article := &Article{Title: title(doc)}
+
+	root := findByID(doc, contentIDs)
+	if root == nil {
+		root = bestCandidate(doc)
+	}
+	if root == nil {
+		root = findAtom(doc, atom.Body)
+	}
+	if root == nil {
+		root = doc
+	}
+
+	article.Text = text(root)
+	return article, nil
+}
+
+// title 优先使用 og:title，其次使用 <title>
+func title(doc *html.Node) string {
+	var ogTitle, pageTitle string
+	walk(doc, func(n *html.Node) bool {
+		if n.Type != html.ElementNode {
+			return true
+		}
+		switch n.DataAtom {
+		case atom.Meta:
+			if attr(n, "property") == "og:title" && ogTitle == "" {
+				ogTitle = strings.TrimSpace(attr(n, "content"))
+			}
+		case atom.Title:
+			if pageTitle == "" && n.FirstChild != nil {
+				pageTitle = strings.TrimSpace(n.FirstChild.Data)
+			}
+		}
+		return true
+	})
+	if ogTitle != "" {
+		return ogTitle
+	}
+	return pageTitle
+}
+
+// bestCandidate 按直接包含的段落文本长度为容器打分，返回得分最高的元素
+func bestCandidate(doc *html.Node) *html.Node {
+	scores := make(map[*html.Node]int)
+	walk(doc, func(n *html.Node) bool {
+		if n.Type == html.ElementNode && ignoredAtoms[n.DataAtom] {
+			return false
+		}
+		if n.Type == html.ElementNode && (n.DataAtom == atom.P || n.DataAtom == atom.Pre) && n.Parent != nil {
+			length := len([]rune(strings.TrimSpace(text(n))))
+			if length < 20 {
+				return false
+			}
+			scores[n.Parent] += length
+			// 祖父元素获得一半的分数，兼容段落被再包一层的排版
+			if n.Parent.Parent != nil {
+				scores[n.Parent.Parent] += length / 2
+			}
+			return false
+		}
+		return true
+	})
+
+	var best *html.Node
+	bestScore := 0
+	for n, score := range scores {
+		if score > bestScore {
+			best, bestScore = n, score
+		}
+	}
+	return best
+}
+
+// text 提取元素下的可见文本，块级元素之间换行
+func text(root *html.Node) string {
+	var sb strings.Builder
+	walk(root, func(n *html.Node) bool {
+		switch n.Type {
+		case html.ElementNode:
+			if ignoredAtoms[n.DataAtom] {
+				return false
+			}
+			if blockAtoms[n.DataAtom] {
+				sb.WriteString("\n")
+			}
+		case html.TextNode:
+			sb.WriteString(n.Data)
+		}
+		return true
+	})
+
+	lines := strings.Split(sb.String(), "\n")
+	result := make([]string, 0, len(lines))
+	for _, line := range lines {
+		line = strings.Join(strings.Fields(line), " ")
+		if line != "" {
+			result = append(result, line)
+		}
+	}
+	return strings.Join(result, "\n")
+}
+
+func findByID(doc *html.Node, ids []string) *html.Node {
+	for _, id := range ids {
+		var found *html.Node
+		walk(doc, func(n *html.Node) bool {
+			if found != nil {
+				return false
+			}
+			if n.Type == html.ElementNode && attr(n, "id") == id {
+				found = n
+				return false
+			}
+			return true
+		})
+		if found != nil && strings.TrimSpace(text(found)) != "" {
+			return found
+		}
+	}
+	return nil
+}
+
+func findAtom(doc *html.Node, a atom.Atom) *html.Node {
+	var found *html.Node
+	walk(doc, func(n *html.Node) bool {
+		if found != nil {
+			return false
+		}
+		if n.Type == html.ElementNode && n.DataAtom == a {
+			found = n
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+// walk 深度优先遍历节点，fn 返回 false 时不再进入子节点
+func walk(n *html.Node, fn func(*html.Node) bool) {
+	if !fn(n) {
+		return
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		walk(c, fn)
+	}
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,25 @@
+SET NAMES utf8mb4;
+SET FOREIGN_KEY_CHECKS = 0;
+
+-- ----------------------------
+-- Table structure for ai_usage
+-- ----------------------------
+DROP TABLE IF EXISTS `ai_usage`;
+CREATE TABLE `ai_usage`  (
+  `id` int(11) UNSIGNED NOT NULL AUTO_INCREMENT,
+  `sheet_id` varchar(100) CHARACTER SET utf8 COLLATE utf8_general_ci NULL DEFAULT NULL,
+  `cell_data_id` int(11) UNSIGNED NOT NULL DEFAULT 0,
+  `purpose` varchar(20) CHARACTER SET utf8 COLLATE utf8_general_ci NULL DEFAULT NULL,
+  `model` varchar(100) CHARACTER SET utf8 COLLATE utf8_general_ci NULL DEFAULT NULL,
+  `prompt_tokens` int(11) UNSIGNED NOT NULL DEFAULT 0,
+  `completion_tokens` int(11) UNSIGNED NOT NULL DEFAULT 0,
+  `latency_ms` int(11) UNSIGNED NOT NULL DEFAULT 0,
+  `error` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci NULL DEFAULT NULL,
+  `create_at` timestamp(0) NOT NULL DEFAULT CURRENT_TIMESTAMP(0),
+  PRIMARY KEY (`id`) USING BTREE,
+  INDEX `idx_create_at`(`create_at`) USING BTREE,
+  INDEX `join_sheet_id_create_at`(`sheet_id`, `create_at`) USING BTREE,
+  INDEX `join_cell_data_id`(`cell_data_id`) USING BTREE
+) ENGINE = InnoDB AUTO_INCREMENT = 1 CHARACTER SET = utf8 COLLATE = utf8_general_ci ROW_FORMAT = Dynamic;
+
+SET FOREIGN_KEY_CHECKS = 1;
@@ -24,19 +24,38 @@ DROP TABLE IF EXISTS `cell_data`;
 CREATE TABLE `cell_data`  (
   `id` int(11) UNSIGNED NOT NULL AUTO_INCREMENT,
   `sheet_id` varchar(100) CHARACTER SET utf8 COLLATE utf8_general_ci NULL DEFAULT NULL,
+  `tab_id` varchar(50) CHARACTER SET utf8 COLLATE utf8_general_ci NULL DEFAULT NULL,
+  `tab_title` varchar(255) CHARACTER SET utf8 COLLATE utf8_general_ci NULL DEFAULT NULL,
+  `tab_index` int(11) NULL DEFAULT 0,
   `title` varchar(255) CHARACTER SET utf8 COLLATE utf8_general_ci NULL DEFAULT NULL,
   `link` varchar(255) CHARACTER SET utf8 COLLATE utf8_general_ci NULL DEFAULT NULL,
+  `link_type` varchar(20) CHARACTER SET utf8 COLLATE utf8_general_ci NULL DEFAULT NULL,
+  `record_id` varchar(50) CHARACTER SET utf8 COLLATE utf8_general_ci NULL DEFAULT NULL,
   `release_date` varchar(50) CHARACTER SET utf8 COLLATE utf8_general_ci NULL DEFAULT NULL,
+  `published_at` timestamp(0) NULL DEFAULT NULL,
   `content` mediumtext CHARACTER SET utf8 COLLATE utf8_general_ci NULL,
+  `content_markdown` mediumtext CHARACTER SET utf8 COLLATE utf8_general_ci NULL,
+  `content_hash` char(64) CHARACTER SET utf8 COLLATE utf8_general_ci NULL DEFAULT NULL,
   `abstract` varchar(1000) CHARACTER SET utf8 COLLATE utf8_general_ci NULL DEFAULT NULL,
   `keyword` varchar(1000) CHARACTER SET utf8 COLLATE utf8_general_ci NULL DEFAULT NULL,
+  `ai_extra` text CHARACTER SET utf8 COLLATE utf8_general_ci NULL,
+  `ai_state` varchar(20) CHARACTER SET utf8 COLLATE utf8_general_ci NULL DEFAULT NULL,
+  `ai_error` varchar(255) CHARACTER SET utf8 COLLATE utf8_general_ci NULL DEFAULT NULL,
+  `written_abstract` varchar(1000) CHARACTER SET utf8 COLLATE utf8_general_ci NULL DEFAULT NULL,
+  `written_keyword` varchar(1000) CHARACTER SET utf8 COLLATE utf8_general_ci NULL DEFAULT NULL,
+  `written_at` timestamp(0) NULL DEFAULT NULL,
   `sort_number` int(11) NULL DEFAULT NULL,
+  `extra` text CHARACTER SET utf8 COLLATE utf8_general_ci NULL,
   `create_at` timestamp(0) NOT NULL DEFAULT CURRENT_TIMESTAMP(0),
   `update_at` timestamp(0) NOT NULL DEFAULT CURRENT_TIMESTAMP(0) ON UPDATE CURRENT_TIMESTAMP(0),
   `deleted` tinyint(4) UNSIGNED NULL DEFAULT 0,
+  `deleted_at` timestamp(0) NULL DEFAULT NULL,
   `runtime_state` varchar(255) CHARACTER SET utf8 COLLATE utf8_general_ci NULL DEFAULT NULL,
   PRIMARY KEY (`id`) USING BTREE,
-  INDEX `join_sheet_id_link`(`sheet_id`, `link`) USING BTREE
+  INDEX `join_sheet_id_link`(`sheet_id`, `link`) USING BTREE,
+  INDEX `join_sheet_id_record_id`(`sheet_id`, `record_id`) USING BTREE,
+  INDEX `join_sheet_id_published_at`(`sheet_id`, `published_at`) USING BTREE,
+  INDEX `join_sheet_id_ai_state`(`sheet_id`, `ai_state`) USING BTREE
 ) ENGINE = InnoDB AUTO_INCREMENT = 1 CHARACTER SET = utf8 COLLATE = utf8_general_ci ROW_FORMAT = Dynamic;
 
 SET FOREIGN_KEY_CHECKS = 1;
@@ -25,13 +25,29 @@ CREATE TABLE `sheet_info`  (
   `id` int(11) UNSIGNED NOT NULL AUTO_INCREMENT,
   `sheet_id` varchar(255) CHARACTER SET utf8 COLLATE utf8_general_ci NULL DEFAULT NULL,
   `sheet_name` varchar(255) CHARACTER SET utf8 COLLATE utf8_general_ci NULL DEFAULT NULL,
+  `source_type` varchar(20) CHARACTER SET utf8 COLLATE utf8_general_ci NULL DEFAULT 'sheet',
+  `table_id` varchar(50) CHARACTER SET utf8 COLLATE utf8_general_ci NULL DEFAULT NULL,
+  `origin_sheet_id` varchar(255) CHARACTER SET utf8 COLLATE utf8_general_ci NULL DEFAULT NULL,
+  `origin_sheet_name` varchar(255) CHARACTER SET utf8 COLLATE utf8_general_ci NULL DEFAULT NULL,
+  `mirror_row_count` int(11) NOT NULL DEFAULT 0,
+  `mirror_checksum` varchar(64) CHARACTER SET utf8 COLLATE utf8_general_ci NULL DEFAULT NULL,
+  `mirror_reported_at` timestamp(0) NULL DEFAULT NULL,
   `update_log` varchar(255) CHARACTER SET utf8 COLLATE utf8_general_ci NULL DEFAULT NULL,
+  `status` varchar(20) CHARACTER SET utf8 COLLATE utf8_general_ci NOT NULL DEFAULT 'active',
+  `sync_interval` int(11) NOT NULL DEFAULT 300,
+  `notify_muted` tinyint(4) UNSIGNED NOT NULL DEFAULT 0,
+  `last_sync_at` timestamp(0) NULL DEFAULT NULL,
+  `column_mapping` text CHARACTER SET utf8 COLLATE utf8_general_ci NULL,
+  `latest_modify_time` varchar(20) CHARACTER SET utf8 COLLATE utf8_general_ci NULL DEFAULT NULL,
+  `revision` int(11) NOT NULL DEFAULT 0,
+  `last_sync_result` text CHARACTER SET utf8 COLLATE utf8_general_ci NULL,
   `create_at` timestamp(0) NOT NULL DEFAULT CURRENT_TIMESTAMP(0),
   `update_at` timestamp(0) NOT NULL DEFAULT CURRENT_TIMESTAMP(0) ON UPDATE CURRENT_TIMESTAMP(0),
   `deleted` tinyint(4) UNSIGNED NULL DEFAULT 0,
   `runtime_state` varchar(255) CHARACTER SET utf8 COLLATE utf8_general_ci NULL DEFAULT NULL,
   PRIMARY KEY (`id`) USING BTREE,
-  INDEX `sheet_id`(`sheet_id`) USING BTREE
+  INDEX `sheet_id`(`sheet_id`) USING BTREE,
+  INDEX `origin_sheet_id`(`origin_sheet_id`) USING BTREE
 ) ENGINE = InnoDB AUTO_INCREMENT = 1 CHARACTER SET = utf8 COLLATE = utf8_general_ci ROW_FORMAT = Dynamic;
 
 SET FOREIGN_KEY_CHECKS = 1;
@@ -0,0 +1,22 @@
+SET NAMES utf8mb4;
+SET FOREIGN_KEY_CHECKS = 0;
+
+-- ----------------------------
+-- Table structure for summary_cache
+-- ----------------------------
+DROP TABLE IF EXISTS `summary_cache`;
+CREATE TABLE `summary_cache`  (
+  `id` int(11) UNSIGNED NOT NULL AUTO_INCREMENT,
+  `content_hash` char(64) CHARACTER SET utf8 COLLATE utf8_general_ci NOT NULL,
+  `prompt_version` varchar(20) CHARACTER SET utf8 COLLATE utf8_general_ci NOT NULL,
+  `model` varchar(100) CHARACTER SET utf8 COLLATE utf8_general_ci NOT NULL,
+  `abstract` varchar(1000) CHARACTER SET utf8 COLLATE utf8_general_ci NULL DEFAULT NULL,
+  `keyword` varchar(1000) CHARACTER SET utf8 COLLATE utf8_general_ci NULL DEFAULT NULL,
+  `ai_extra` text CHARACTER SET utf8 COLLATE utf8_general_ci NULL,
+  `ai_state` varchar(20) CHARACTER SET utf8 COLLATE utf8_general_ci NULL DEFAULT NULL,
+  `create_at` timestamp(0) NOT NULL DEFAULT CURRENT_TIMESTAMP(0),
+  PRIMARY KEY (`id`) USING BTREE,
+  UNIQUE INDEX `uniq_content_hash_prompt_version_model`(`content_hash`, `prompt_version`, `model`) USING BTREE
+) ENGINE = InnoDB AUTO_INCREMENT = 1 CHARACTER SET = utf8 COLLATE = utf8_general_ci ROW_FORMAT = Dynamic;
+
+SET FOREIGN_KEY_CHECKS = 1;
@@ -0,0 +1,68 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/service/media.go
+
+// Package mock_service is a generated GoMock package.
+package mock_service
+
+import (
+	context "context"
+	io "io"
+	reflect "reflect"
+	model "shengcai/internal/model"
+	service "shengcai/internal/service"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockMediaService is a mock of MediaService interface.
+type MockMediaService struct {
+	ctrl     *gomock.Controller
+	recorder *MockMediaServiceMockRecorder
+}
+
+// MockMediaServiceMockRecorder is the mock recorder for MockMediaService.
+type MockMediaServiceMockRecorder struct {
+	mock *MockMediaService
+}
+
+// NewMockMediaService creates a new mock instance.
+func NewMockMediaService(ctrl *gomock.Controller) *MockMediaService {
+	mock := &MockMediaService{ctrl: ctrl}
+	mock.recorder = &MockMediaServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockMediaService) EXPECT() *MockMediaServiceMockRecorder {
+	return m.recorder
+}
+
+// Mirror mocks base method.
+func (m *MockMediaService) Mirror(ctx context.Context, appID, appSecret string, document *service.Document) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Mirror", ctx, appID, appSecret, document)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Mirror indicates an expected call of Mirror.
+func (mr *MockMediaServiceMockRecorder) Mirror(ctx, appID, appSecret, document interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Mirror", reflect.TypeOf((*MockMediaService)(nil).Mirror), ctx, appID, appSecret, document)
+}
+
+// Open mocks base method.
+func (m *MockMediaService) Open(ctx context.Context, id int) (*model.Media, io.ReadCloser, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Open", ctx, id)
+	ret0, _ := ret[0].(*model.Media)
+	ret1, _ := ret[1].(io.ReadCloser)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Open indicates an expected call of Open.
+func (mr *MockMediaServiceMockRecorder) Open(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Open", reflect.TypeOf((*MockMediaService)(nil).Open), ctx, id)
+}
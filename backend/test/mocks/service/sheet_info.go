@@ -0,0 +1,122 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/service/sheet_info.go
+
+// Package mock_service is a generated GoMock package.
+package mock_service
+
+import (
+	context "context"
+	reflect "reflect"
+	v1 "shengcai/api/v1"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockSheetInfoService is a mock of SheetInfoService interface.
+type MockSheetInfoService struct {
+	ctrl     *gomock.Controller
+	recorder *MockSheetInfoServiceMockRecorder
+}
+
+// MockSheetInfoServiceMockRecorder is the mock recorder for MockSheetInfoService.
+type MockSheetInfoServiceMockRecorder struct {
+	mock *MockSheetInfoService
+}
+
+// NewMockSheetInfoService creates a new mock instance.
+func NewMockSheetInfoService(ctrl *gomock.Controller) *MockSheetInfoService {
+	mock := &MockSheetInfoService{ctrl: ctrl}
+	mock.recorder = &MockSheetInfoServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSheetInfoService) EXPECT() *MockSheetInfoServiceMockRecorder {
+	return m.recorder
+}
+
+// List mocks base method.
+func (m *MockSheetInfoService) List(ctx context.Context) (*v1.SheetListResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx)
+	ret0, _ := ret[0].(*v1.SheetListResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockSheetInfoServiceMockRecorder) List(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockSheetInfoService)(nil).List), ctx)
+}
+
+// Mute mocks base method.
+func (m *MockSheetInfoService) Mute(ctx context.Context, req *v1.SheetMuteRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Mute", ctx, req)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Mute indicates an expected call of Mute.
+func (mr *MockSheetInfoServiceMockRecorder) Mute(ctx, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Mute", reflect.TypeOf((*MockSheetInfoService)(nil).Mute), ctx, req)
+}
+
+// Register mocks base method.
+func (m *MockSheetInfoService) Register(ctx context.Context, req *v1.SheetRegisterRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Register", ctx, req)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Register indicates an expected call of Register.
+func (mr *MockSheetInfoServiceMockRecorder) Register(ctx, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Register", reflect.TypeOf((*MockSheetInfoService)(nil).Register), ctx, req)
+}
+
+// Remove mocks base method.
+func (m *MockSheetInfoService) Remove(ctx context.Context, req *v1.SheetRemoveRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Remove", ctx, req)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Remove indicates an expected call of Remove.
+func (mr *MockSheetInfoServiceMockRecorder) Remove(ctx, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Remove", reflect.TypeOf((*MockSheetInfoService)(nil).Remove), ctx, req)
+}
+
+// ReportMirror mocks base method.
+func (m *MockSheetInfoService) ReportMirror(ctx context.Context, req *v1.SheetMirrorReportRequest) (*v1.SheetMirrorReportResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReportMirror", ctx, req)
+	ret0, _ := ret[0].(*v1.SheetMirrorReportResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReportMirror indicates an expected call of ReportMirror.
+func (mr *MockSheetInfoServiceMockRecorder) ReportMirror(ctx, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReportMirror", reflect.TypeOf((*MockSheetInfoService)(nil).ReportMirror), ctx, req)
+}
+
+// UpdateStatus mocks base method.
+func (m *MockSheetInfoService) UpdateStatus(ctx context.Context, req *v1.SheetUpdateStatusRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateStatus", ctx, req)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateStatus indicates an expected call of UpdateStatus.
+func (mr *MockSheetInfoServiceMockRecorder) UpdateStatus(ctx, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateStatus", reflect.TypeOf((*MockSheetInfoService)(nil).UpdateStatus), ctx, req)
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"shengcai/internal/handler"
+	"shengcai/internal/model"
+	"shengcai/test/mocks/service"
+)
+
+func TestMediaHandler_Get(t *testing.T) {
+	tests := []struct {
+		name            string
+		media           *model.Media
+		wantContentType string
+		wantDisposition string
+	}{
+		{
+			name:            "image inline",
+			media:           &model.Media{ID: 1, Hash: "a", Size: 3, ContentType: "image/png", FileName: "封面.png"},
+			wantContentType: "image/png",
+			wantDisposition: "inline; filename*=UTF-8''%E5%B0%81%E9%9D%A2.png",
+		},
+		{
+			name:            "html as attachment",
+			media:           &model.Media{ID: 2, Hash: "b", Size: 3, ContentType: "text/html; charset=utf-8", FileName: "x.html"},
+			wantContentType: "application/octet-stream",
+			wantDisposition: "attachment; filename*=UTF-8''x.html",
+		},
+		{
+			name:            "svg as attachment",
+			media:           &model.Media{ID: 3, Hash: "c", Size: 3, ContentType: "image/svg+xml"},
+			wantContentType: "application/octet-stream",
+			wantDisposition: "attachment",
+		},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockMediaService := mock_service.NewMockMediaService(ctrl)
+	mediaHandler := handler.NewMediaHandler(hdl, mockMediaService)
+	router.GET("/media/:id", mediaHandler.Get)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockMediaService.EXPECT().Open(gomock.Any(), tt.media.ID).
+				Return(tt.media, io.NopCloser(strings.NewReader("abc")), nil)
+
+			resp := performRequest(router, "GET", "/media/"+strconv.Itoa(tt.media.ID), bytes.NewBuffer(nil))
+
+			assert.Equal(t, http.StatusOK, resp.Code)
+			assert.Equal(t, tt.wantContentType, resp.Header().Get("Content-Type"))
+			assert.Equal(t, tt.wantDisposition, resp.Header().Get("Content-Disposition"))
+			assert.Equal(t, "nosniff", resp.Header().Get("X-Content-Type-Options"))
+		})
+	}
+}
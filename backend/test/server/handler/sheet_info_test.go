@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	v1 "shengcai/api/v1"
+	"shengcai/internal/handler"
+	"shengcai/test/mocks/service"
+)
+
+func TestSheetInfoHandler_Register(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantCode int
+	}{
+		{name: "ok", wantCode: http.StatusOK},
+		{name: "bad request", err: v1.ErrBadRequest, wantCode: http.StatusBadRequest},
+		{name: "origin mirrored", err: v1.ErrOriginMirrored, wantCode: http.StatusConflict},
+		{name: "internal", err: errors.New("db down"), wantCode: http.StatusInternalServerError},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockSheetInfoService := mock_service.NewMockSheetInfoService(ctrl)
+	sheetInfoHandler := handler.NewSheetInfoHandler(hdl, mockSheetInfoService)
+	router.POST("/sheet/register", sheetInfoHandler.Register)
+
+	params := v1.SheetRegisterRequest{SpreadsheetToken: "shtFakeArticles", SourceType: "bitable"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSheetInfoService.EXPECT().Register(gomock.Any(), &params).Return(tt.err)
+
+			paramsJson, _ := json.Marshal(params)
+			resp := performRequest(router, "POST", "/sheet/register", bytes.NewBuffer(paramsJson))
+
+			assert.Equal(t, tt.wantCode, resp.Code)
+		})
+	}
+}
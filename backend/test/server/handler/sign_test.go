@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/duke-git/lancet/v2/cryptor"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"shengcai/internal/middleware"
+)
+
+func signConf() *viper.Viper {
+	conf := viper.New()
+	conf.Set("security.api_sign.app_key", "test-key")
+	conf.Set("security.api_sign.app_security", "test-security")
+	return conf
+}
+
+// signedRequest 按 SignMiddleware 的规则生成签名
+func signedRequest(body string, timestamp time.Time, nonce string) *http.Request {
+	sum := sha256.Sum256([]byte(body))
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	str := "AppKeytest-key" + "AppVersion1.0.0" + "BodyHash" + hex.EncodeToString(sum[:]) +
+		"Nonce" + nonce + "Timestamp" + ts + "test-security"
+
+	req, _ := http.NewRequest("POST", "/signed", strings.NewReader(body))
+	req.Header.Set("Timestamp", ts)
+	req.Header.Set("Nonce", nonce)
+	req.Header.Set("App-Version", "1.0.0")
+	req.Header.Set("Sign", strings.ToUpper(cryptor.Md5String(str)))
+	return req
+}
+
+func TestSignMiddleware(t *testing.T) {
+	engine := gin.New()
+	engine.POST("/signed", middleware.SignMiddleware(logger, signConf()), func(ctx *gin.Context) {
+		body, _ := ctx.GetRawData()
+		ctx.String(http.StatusOK, string(body))
+	})
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		resp := httptest.NewRecorder()
+		engine.ServeHTTP(resp, req)
+		return resp
+	}
+
+	resp := serve(signedRequest(`{"row_count":3}`, time.Now(), "n1"))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	// 校验签名后处理函数仍能读取请求体
+	assert.Equal(t, `{"row_count":3}`, resp.Body.String())
+
+	// 重放同一个请求
+	assert.Equal(t, http.StatusBadRequest, serve(signedRequest(`{"row_count":3}`, time.Now(), "n1")).Code)
+
+	// 请求体被篡改
+	req := signedRequest(`{"row_count":3}`, time.Now(), "n2")
+	req.Body = io.NopCloser(bytes.NewBufferString(`{"row_count":4}`))
+	assert.Equal(t, http.StatusBadRequest, serve(req).Code)
+
+	// 过期的时间戳
+	assert.Equal(t, http.StatusBadRequest, serve(signedRequest(`{}`, time.Now().Add(-10*time.Minute), "n3")).Code)
+	assert.Equal(t, http.StatusOK, serve(signedRequest(`{}`, time.Now(), "n3")).Code)
+}
+
+func TestSignMiddleware_NotConfigured(t *testing.T) {
+	defaultKey := signConf()
+	defaultKey.Set("security.api_sign.app_security", "123456")
+
+	for name, conf := range map[string]*viper.Viper{"default key": defaultKey, "empty": viper.New()} {
+		t.Run(name, func(t *testing.T) {
+			engine := gin.New()
+			engine.POST("/signed", middleware.SignMiddleware(logger, conf), func(ctx *gin.Context) {
+				ctx.Status(http.StatusOK)
+			})
+			resp := httptest.NewRecorder()
+			engine.ServeHTTP(resp, signedRequest(`{}`, time.Now(), "n1"))
+			assert.Equal(t, http.StatusServiceUnavailable, resp.Code)
+		})
+	}
+}
@@ -0,0 +1,411 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"shengcai/internal/model"
+	"shengcai/internal/repository"
+	"shengcai/pkg/llm"
+)
+
+const aiTestContent = "线下实体复苏 奶茶店 选址 开一家奶茶店到底挣不挣钱"
+
+// newAIRepository 使用临时的 sqlite 数据库记录 token 用量
+func newAIRepository(t *testing.T, conf *viper.Viper, provider llm.Provider) (repository.AIRepository, *gorm.DB) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "ai.db")), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.AIUsage{}, &model.SummaryCache{}))
+
+	repo := repository.NewRepository(logger, db, conf)
+	return repository.NewAIRepository(repo, provider, repository.NewAIUsageRepository(repo)), db
+}
+
+func setupAI(t *testing.T, provider string, baseURL string) (repository.AIRepository, llm.Provider) {
+	conf := viper.New()
+	conf.Set("ai.generate", "open")
+	conf.Set("llm.provider", provider)
+	conf.Set("llm.base_url", baseURL)
+	conf.Set("llm.api_key", "sk-test")
+	conf.Set("llm.model", "test-model")
+	conf.Set("llm.temperature", 0.2)
+	conf.Set("llm.max_tokens", 256)
+
+	chat, err := llm.NewProvider(conf)
+	require.NoError(t, err)
+	ai, _ := newAIRepository(t, conf, chat)
+	return ai, chat
+}
+
+func TestAIRepository_OpenAI(t *testing.T) {
+	var body map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/chat/completions", r.URL.Path)
+		assert.Equal(t, "Bearer sk-test", r.Header.Get("Authorization"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"model": "test-model",
+			"choices": []interface{}{map[string]interface{}{
+				"message": map[string]interface{}{"role": "assistant", "content": "```json\n{\"abstract\": \"开奶茶店要先算清楚账\", \"keywords\": [\"奶茶店\", \" 选址\", \"实体\", \"奶茶店\"], \"extra\": {\"类型\": \"访谈\", \"人数\": 2}}\n```"},
+			}},
+			"usage": map[string]interface{}{"prompt_tokens": 120, "completion_tokens": 30},
+		})
+	}))
+	t.Cleanup(srv.Close)
+
+	ai, provider := setupAI(t, "openai", srv.URL+"/v1/")
+	summary, err := ai.Summarize(context.Background(), repository.SummaryArticle{Content: aiTestContent})
+	require.NoError(t, err)
+	assert.Equal(t, model.AIStateOK, summary.State)
+	assert.Equal(t, "开奶茶店要先算清楚账", summary.Abstract)
+	assert.Equal(t, "奶茶店,选址,实体", summary.Keyword())
+	assert.Equal(t, map[string]string{"类型": "访谈", "人数": "2"}, summary.Extra)
+	assert.Equal(t, map[string]interface{}{"type": "json_object"}, body["response_format"])
+	assert.Equal(t, "test-model", body["model"])
+	assert.Equal(t, 0.2, body["temperature"])
+	assert.Equal(t, float64(256), body["max_tokens"])
+
+	response, err := provider.Chat(context.Background(), &llm.ChatRequest{
+		Messages: []llm.Message{{Role: llm.RoleUser, Content: aiTestContent}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 120, response.PromptTokens)
+	assert.Equal(t, 30, response.CompletionTokens)
+}
+
+func TestAIRepository_Ollama(t *testing.T) {
+	var body map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/chat", r.URL.Path)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"model":             "test-model",
+			"message":           map[string]interface{}{"role": "assistant", "content": "摘要：本地模型的摘要\n关键字：a,b,c"},
+			"prompt_eval_count": 80,
+			"eval_count":        12,
+		})
+	}))
+	t.Cleanup(srv.Close)
+
+	ai, _ := setupAI(t, "ollama", srv.URL)
+	summary, err := ai.Summarize(context.Background(), repository.SummaryArticle{Content: aiTestContent})
+	require.NoError(t, err)
+	// 模型没有按 JSON 返回时按旧的文本格式解析
+	assert.Equal(t, model.AIStateFallback, summary.State)
+	assert.NotEmpty(t, summary.Warning)
+	assert.Equal(t, "本地模型的摘要", summary.Abstract)
+	assert.Equal(t, "a,b,c", summary.Keyword())
+	assert.Equal(t, "json", body["format"])
+	assert.Equal(t, false, body["stream"])
+	options := body["options"].(map[string]interface{})
+	assert.Equal(t, 0.2, options["temperature"])
+	assert.Equal(t, float64(256), options["num_predict"])
+}
+
+func TestNewProvider_Defaults(t *testing.T) {
+	var body map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": map[string]interface{}{"role": "assistant", "content": "ok"},
+		})
+	}))
+	t.Cleanup(srv.Close)
+
+	// 未配置模型时 ollama 使用默认模型
+	conf := viper.New()
+	conf.Set("llm.provider", "ollama")
+	conf.Set("llm.base_url", srv.URL)
+	chat, err := llm.NewProvider(conf)
+	require.NoError(t, err)
+	_, err = chat.Chat(context.Background(), &llm.ChatRequest{Messages: []llm.Message{{Role: llm.RoleUser, Content: "hi"}}})
+	require.NoError(t, err)
+	assert.Equal(t, "qwen2.5", body["model"])
+
+	// 未知的模型服务返回错误而不是 panic
+	conf.Set("llm.provider", "claude")
+	_, err = llm.NewProvider(conf)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `"claude"`)
+}
+
+func TestAIRepository_Fake(t *testing.T) {
+	ai, _ := setupAI(t, "fake", "")
+	summary, err := ai.Summarize(context.Background(), repository.SummaryArticle{Content: aiTestContent})
+	require.NoError(t, err)
+	assert.Equal(t, model.AIStateOK, summary.State)
+	assert.Equal(t, aiTestContent, summary.Abstract)
+	assert.Equal(t, "线下实体复苏,奶茶店,选址", summary.Keyword())
+
+	// 相同的正文使用缓存的摘要
+	again, err := ai.Summarize(context.Background(), repository.SummaryArticle{Content: aiTestContent})
+	require.NoError(t, err)
+	assert.True(t, again.Cached)
+	assert.Equal(t, summary.Abstract, again.Abstract)
+	assert.Equal(t, summary.Keywords, again.Keywords)
+
+	// 没有内容时跳过
+	summary, err = ai.Summarize(context.Background(), repository.SummaryArticle{Content: " "})
+	require.NoError(t, err)
+	assert.Equal(t, model.AIStateSkipped, summary.State)
+}
+
+func TestAIRepository_Unparsable(t *testing.T) {
+	replies := []string{
+		"抱歉，我无法总结这篇文章。",
+		`{"abstract": "", "keywords": ["a"]}`,
+		`{"abstract": "没有关键字", "keywords": []}`,
+	}
+	for _, reply := range replies {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"choices": []interface{}{map[string]interface{}{
+					"message": map[string]interface{}{"role": "assistant", "content": reply},
+				}},
+			})
+		}))
+
+		ai, _ := setupAI(t, "openai", srv.URL)
+		_, err := ai.Summarize(context.Background(), repository.SummaryArticle{Content: aiTestContent})
+		assert.ErrorIs(t, err, repository.ErrSummaryUnparsable, reply)
+		srv.Close()
+	}
+}
+
+func TestAIRepository_MarkdownFallback(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []interface{}{map[string]interface{}{
+				"message": map[string]interface{}{"role": "assistant", "content": "**摘要:** 加盟前先算账\n**关键词:** 加盟、奶茶店"},
+			}},
+		})
+	}))
+	t.Cleanup(srv.Close)
+
+	ai, _ := setupAI(t, "openai", srv.URL)
+	summary, err := ai.Summarize(context.Background(), repository.SummaryArticle{Content: aiTestContent})
+	require.NoError(t, err)
+	assert.Equal(t, model.AIStateFallback, summary.State)
+	assert.Equal(t, "加盟前先算账", summary.Abstract)
+	assert.Equal(t, "加盟,奶茶店", summary.Keyword())
+}
+
+func TestOpenAI_Timeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	t.Cleanup(srv.Close)
+
+	provider := llm.NewOpenAI(llm.Options{BaseURL: srv.URL, Model: "test-model", Timeout: 50 * time.Millisecond})
+	_, err := provider.Chat(context.Background(), &llm.ChatRequest{
+		Messages: []llm.Message{{Role: llm.RoleUser, Content: aiTestContent}},
+	})
+	assert.Error(t, err)
+}
+
+// recordingProvider 记录每次请求，按提示词返回分段、合并或最终的摘要，每次消耗 100 个 token
+type recordingProvider struct {
+	requests []*llm.ChatRequest
+	// reply 根据第几次请求和请求内容返回模型的输出
+	reply func(n int, req *llm.ChatRequest) string
+}
+
+func (p *recordingProvider) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatResponse, error) {
+	p.requests = append(p.requests, req)
+	return &llm.ChatResponse{Content: p.reply(len(p.requests), req), PromptTokens: 60, CompletionTokens: 40}, nil
+}
+
+func setupChunkedAI(t *testing.T, chunkTokens int, maxChunks int, provider llm.Provider) repository.AIRepository {
+	conf := viper.New()
+	conf.Set("ai.generate", "open")
+	conf.Set("ai.chunk_tokens", chunkTokens)
+	conf.Set("ai.max_chunks", maxChunks)
+	ai, _ := newAIRepository(t, conf, provider)
+	return ai
+}
+
+func longArticle(paragraphs int) string {
+	var builder strings.Builder
+	for i := 1; i <= paragraphs; i++ {
+		builder.WriteString(fmt.Sprintf("第%d段：开奶茶店之前要先算清楚房租、人工和原料的成本。选址决定了一半的生意！\n", i))
+	}
+	return builder.String()
+}
+
+func TestSplitText(t *testing.T) {
+	assert.Equal(t, 7, llm.EstimateTokens("奶茶店，选址。"))
+	assert.Equal(t, 3, llm.EstimateTokens("hello world"))
+
+	article := longArticle(20)
+	chunks := llm.SplitText(article, 100)
+	require.Greater(t, len(chunks), 1)
+	assert.Equal(t, article, strings.Join(chunks, ""))
+	for _, chunk := range chunks {
+		assert.LessOrEqual(t, llm.EstimateTokens(chunk), 100)
+		// 优先在段落处切分
+		assert.True(t, strings.HasSuffix(chunk, "\n"), chunk)
+	}
+
+	// 没有换行的长段落在句末标点处切分，仍然过长时按字切分
+	paragraph := strings.Repeat("开奶茶店之前要先算清楚成本。", 10) + strings.Repeat("长", 50)
+	chunks = llm.SplitText(paragraph, 20)
+	assert.Equal(t, paragraph, strings.Join(chunks, ""))
+	assert.Equal(t, "开奶茶店之前要先算清楚成本。", chunks[0])
+	for _, chunk := range chunks {
+		assert.LessOrEqual(t, llm.EstimateTokens(chunk), 20)
+	}
+
+	assert.Equal(t, []string{"短文"}, llm.SplitText("短文", 100))
+}
+
+func TestAIRepository_MapReduce(t *testing.T) {
+	provider := &recordingProvider{reply: func(n int, req *llm.ChatRequest) string {
+		if strings.Contains(req.Messages[0].Content, "各部分的摘要") {
+			return `{"abstract": "开奶茶店要算清成本、选好位置", "keywords": ["奶茶店", "选址", "成本"]}`
+		}
+		return fmt.Sprintf(`{"abstract": "第%d部分的要点", "keywords": ["奶茶店", "要点%d"]}`, n, n)
+	}}
+	ai := setupChunkedAI(t, 200, 20, provider)
+
+	summary, err := ai.Summarize(context.Background(), repository.SummaryArticle{Content: longArticle(20)})
+	require.NoError(t, err)
+	assert.Equal(t, model.AIStateOK, summary.State)
+	assert.Equal(t, "开奶茶店要算清成本、选好位置", summary.Abstract)
+	assert.Equal(t, "奶茶店,选址,成本", summary.Keyword())
+	assert.Empty(t, summary.Warning)
+
+	// 每个分段一次请求，最后一次合并
+	require.Greater(t, len(provider.requests), 2)
+	chunks := len(provider.requests) - 1
+	for i, req := range provider.requests[:chunks] {
+		assert.True(t, req.JSON)
+		assert.Contains(t, req.Messages[0].Content, fmt.Sprintf("第 %d 部分（共 %d 部分）", i+1, chunks))
+		assert.LessOrEqual(t, llm.EstimateTokens(req.Messages[1].Content), 200)
+	}
+	reduce := provider.requests[chunks].Messages[1].Content
+	assert.Contains(t, reduce, "第1部分：第1部分的要点（关键字：奶茶店,要点1）")
+	assert.Contains(t, reduce, fmt.Sprintf("第%d部分：第%d部分的要点", chunks, chunks))
+}
+
+func TestAIRepository_MapReduce_Limits(t *testing.T) {
+	provider := &recordingProvider{reply: func(n int, req *llm.ChatRequest) string {
+		switch {
+		case n == 2:
+			return "无法总结"
+		case strings.Contains(req.Messages[0].Content, "连续几个部分"):
+			return fmt.Sprintf(`{"abstract": "合并%d", "keywords": ["合并"]}`, n)
+		case strings.Contains(req.Messages[0].Content, "各部分的摘要"):
+			return `{"abstract": "全文摘要", "keywords": ["奶茶店"]}`
+		}
+		return fmt.Sprintf(`{"abstract": "%s", "keywords": ["要点%d"]}`, strings.Repeat("要", 30), n)
+	}}
+	ai := setupChunkedAI(t, 100, 6, provider)
+
+	summary, err := ai.Summarize(context.Background(), repository.SummaryArticle{Content: longArticle(40)})
+	require.NoError(t, err)
+	assert.Equal(t, "全文摘要", summary.Abstract)
+	// 只总结前 6 个分段，无法解析的分段跳过
+	assert.Contains(t, summary.Warning, "only the first 6 of")
+	assert.Contains(t, summary.Warning, "part 2:")
+
+	// 5 个分段的摘要超过了单次请求的长度，先合并再综合
+	var merges int
+	for _, req := range provider.requests[6:] {
+		if strings.Contains(req.Messages[0].Content, "连续几个部分") {
+			merges++
+		}
+	}
+	assert.Greater(t, merges, 0)
+	assert.Len(t, provider.requests, 6+merges+1)
+	assert.Contains(t, provider.requests[len(provider.requests)-1].Messages[1].Content, "合并")
+}
+
+func TestAIRepository_Usage(t *testing.T) {
+	provider := &recordingProvider{reply: func(n int, req *llm.ChatRequest) string {
+		return `{"abstract": "开奶茶店要先算清楚账", "keywords": ["奶茶店"]}`
+	}}
+	conf := viper.New()
+	conf.Set("ai.generate", "open")
+	conf.Set("ai.daily_token_budget", 100)
+	conf.Set("llm.model", "test-model")
+	ai, db := newAIRepository(t, conf, provider)
+
+	article := repository.SummaryArticle{SheetID: "shtFake", CellDataID: 7, Content: aiTestContent}
+	_, err := ai.Summarize(context.Background(), article)
+	require.NoError(t, err)
+
+	var usage []*model.AIUsage
+	require.NoError(t, db.Find(&usage).Error)
+	require.Len(t, usage, 1)
+	assert.Equal(t, "shtFake", usage[0].SheetID)
+	assert.Equal(t, 7, usage[0].CellDataID)
+	assert.Equal(t, model.AIPurposeSummary, usage[0].Purpose)
+	assert.Equal(t, "test-model", usage[0].Model)
+	assert.Equal(t, 60, usage[0].PromptTokens)
+	assert.Equal(t, 40, usage[0].CompletionTokens)
+
+	// 当天已消耗 100 个 token，达到预算后不再调用模型，已缓存的摘要不受影响
+	_, err = ai.Summarize(context.Background(), article)
+	assert.NoError(t, err)
+	article.Content = "开奶茶店之前要先算清楚房租"
+	_, err = ai.Summarize(context.Background(), article)
+	assert.ErrorIs(t, err, repository.ErrBudgetExceeded)
+	assert.Len(t, provider.requests, 1)
+
+	// 预算按天计算，前一天的用量不计入
+	require.NoError(t, db.Model(&model.AIUsage{}).Where("id = ?", usage[0].ID).
+		Update("create_at", time.Now().AddDate(0, 0, -1)).Error)
+	_, err = ai.Summarize(context.Background(), article)
+	assert.NoError(t, err)
+}
+
+func TestAIRepository_Cache(t *testing.T) {
+	provider := &recordingProvider{reply: func(n int, req *llm.ChatRequest) string {
+		return fmt.Sprintf(`{"abstract": "摘要%d", "keywords": ["奶茶店", "选址"], "extra": {"类型": "访谈"}}`, n)
+	}}
+	conf := viper.New()
+	conf.Set("ai.generate", "open")
+	conf.Set("llm.model", "model-a")
+	ai, db := newAIRepository(t, conf, provider)
+	ctx := context.Background()
+
+	summary, err := ai.Summarize(ctx, repository.SummaryArticle{SheetID: "shtA", Content: aiTestContent})
+	require.NoError(t, err)
+	assert.False(t, summary.Cached)
+
+	// 只有空白和零宽字符不同的正文视为相同，其他表格中的相同文章直接使用缓存
+	cached, err := ai.Summarize(ctx, repository.SummaryArticle{SheetID: "shtB", Content: "  线下实体复苏\n\n奶茶店\u200b 选址\t开一家奶茶店到底挣不挣钱 "})
+	require.NoError(t, err)
+	assert.True(t, cached.Cached)
+	assert.Equal(t, "摘要1", cached.Abstract)
+	assert.Equal(t, "奶茶店,选址", cached.Keyword())
+	assert.Equal(t, map[string]string{"类型": "访谈"}, cached.Extra)
+	assert.Equal(t, model.AIStateOK, cached.State)
+	assert.Len(t, provider.requests, 1)
+	assert.Equal(t, repository.ContentHash(aiTestContent), repository.ContentHash("线下实体复苏 奶茶店\u200b 选址 开一家奶茶店到底挣不挣钱"))
+
+	// 换用其他模型时重新生成
+	conf.Set("llm.model", "model-b")
+	summary, err = ai.Summarize(ctx, repository.SummaryArticle{SheetID: "shtA", Content: aiTestContent})
+	require.NoError(t, err)
+	assert.False(t, summary.Cached)
+	assert.Equal(t, "摘要2", summary.Abstract)
+
+	var count int64
+	require.NoError(t, db.Model(&model.SummaryCache{}).Count(&count).Error)
+	assert.Equal(t, int64(2), count)
+	require.NoError(t, db.Model(&model.AIUsage{}).Count(&count).Error)
+	assert.Equal(t, int64(2), count)
+}
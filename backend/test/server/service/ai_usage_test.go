@@ -0,0 +1,123 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "shengcai/api/v1"
+	"shengcai/internal/model"
+	"shengcai/internal/service"
+)
+
+func TestAIUsage_BudgetPausesSummaries(t *testing.T) {
+	env := setupFeiShu(t)
+	ctx := context.Background()
+	env.conf.Set("ai.generate", "open")
+	env.conf.Set("open.app_id", fakeAppID)
+	env.conf.Set("open.app_secret", fakeAppSecret)
+	// 第一篇文章就会用完当天的预算
+	env.conf.Set("ai.daily_token_budget", 1)
+
+	_, err := env.saveTableData(t)
+	require.NoError(t, err)
+
+	var pending, summarized []*model.CellData
+	for _, row := range env.cellData(t) {
+		if row.AIState == model.AIStatePending {
+			pending = append(pending, row)
+		} else {
+			summarized = append(summarized, row)
+		}
+	}
+	require.Len(t, summarized, 1)
+	require.Len(t, pending, 2)
+	assert.Equal(t, model.AIStateOK, summarized[0].AIState)
+	assert.NotEmpty(t, summarized[0].Abstract)
+	assert.Empty(t, pending[0].Abstract)
+
+	// 用量记录在文章上
+	var usage []*model.AIUsage
+	require.NoError(t, env.db.Find(&usage).Error)
+	require.Len(t, usage, 1)
+	assert.Equal(t, fakeSpreadsheetToken, usage[0].SheetID)
+	assert.Equal(t, summarized[0].ID, usage[0].CellDataID)
+	assert.Greater(t, usage[0].PromptTokens, 0)
+
+	usageService := service.NewAIUsageService(env.service, env.aiUsageRepo, env.sheetRepo)
+	stats, err := usageService.Stats(ctx, &v1.AIUsageRequest{})
+	require.NoError(t, err)
+	assert.True(t, stats.Budget.Paused)
+	assert.Equal(t, 1, stats.Budget.Daily)
+
+	// 提高预算后，表格没有变化的同步同样会补齐暂停的摘要
+	env.conf.Set("ai.daily_token_budget", 1000000)
+	shengCai := service.NewShengCaiService(env.service, env.feiShu, env.cellDataRepo, env.sheetRepo, env.commentRepo,
+		service.NewNotifierService(env.service, env.client, env.cellDataRepo))
+	require.NoError(t, shengCai.SyncSheet(ctx, fakeSpreadsheetToken))
+	for _, row := range env.cellData(t) {
+		assert.Equal(t, model.AIStateOK, row.AIState, row.Title)
+		assert.NotEmpty(t, row.Abstract, row.Title)
+	}
+
+	today := time.Now().Format("2006-01-02")
+	stats, err = usageService.Stats(ctx, &v1.AIUsageRequest{DateFrom: today, DateTo: today})
+	require.NoError(t, err)
+	assert.False(t, stats.Budget.Paused)
+	assert.Equal(t, 3, stats.Total.Calls)
+	assert.Equal(t, stats.Total.PromptTokens+stats.Total.CompletionTokens, stats.Total.TotalTokens)
+	require.Len(t, stats.Daily, 1)
+	assert.Equal(t, today, stats.Daily[0].Date)
+	assert.Equal(t, stats.Total, stats.Daily[0].AIUsageTotal)
+	require.Len(t, stats.Sheets, 1)
+	assert.Equal(t, fakeSpreadsheetToken, stats.Sheets[0].SheetID)
+	assert.Equal(t, stats.Total, stats.Sheets[0].AIUsageTotal)
+
+	// 默认统计最近 30 天
+	stats, err = usageService.Stats(ctx, &v1.AIUsageRequest{})
+	require.NoError(t, err)
+	assert.Len(t, stats.Daily, 30)
+	assert.Equal(t, today, stats.Daily[29].Date)
+
+	_, err = usageService.Stats(ctx, &v1.AIUsageRequest{DateFrom: today, DateTo: "2000-01-01"})
+	assert.ErrorIs(t, err, v1.ErrBadRequest)
+}
+
+func TestAIUsage_SummarizeAfterCommit(t *testing.T) {
+	env := setupFeiShu(t)
+	ctx := context.Background()
+	env.conf.Set("ai.generate", "open")
+
+	cellData := &model.CellData{SheetID: fakeSpreadsheetToken, Link: "https://example.com/a", Title: "开店前的准备", Content: "选址、装修和招聘"}
+	errRollback := errors.New("rollback")
+	err := env.tm.Transaction(ctx, func(ctx context.Context) error {
+		created, err := env.cellDataRepo.Create(ctx, cellData)
+		require.NoError(t, err)
+		assert.True(t, created)
+		return errRollback
+	})
+	require.ErrorIs(t, err, errRollback)
+	// 事务中只保存文章，不调用模型
+	assert.Equal(t, model.AIStatePending, cellData.AIState)
+	var usageCount, cacheCount int64
+	require.NoError(t, env.db.Model(&model.AIUsage{}).Count(&usageCount).Error)
+	require.NoError(t, env.db.Model(&model.SummaryCache{}).Count(&cacheCount).Error)
+	assert.Equal(t, int64(0), usageCount)
+	assert.Equal(t, int64(0), cacheCount)
+
+	cellData.ID = 0
+	created, err := env.cellDataRepo.Create(ctx, cellData)
+	require.NoError(t, err)
+	assert.True(t, created)
+	require.NoError(t, env.cellDataRepo.Summarize(ctx, cellData.ID, ""))
+
+	var saved model.CellData
+	require.NoError(t, env.db.First(&saved, cellData.ID).Error)
+	assert.Equal(t, model.AIStateOK, saved.AIState)
+	assert.NotEmpty(t, saved.Abstract)
+	require.NoError(t, env.db.Model(&model.AIUsage{}).Where("cell_data_id = ?", cellData.ID).Count(&usageCount).Error)
+	assert.Equal(t, int64(1), usageCount)
+}
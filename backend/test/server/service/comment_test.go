@@ -0,0 +1,80 @@
+package service_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "shengcai/api/v1"
+	"shengcai/internal/service"
+	"shengcai/pkg/fakefeishu"
+)
+
+func TestShengCaiService_Comments(t *testing.T) {
+	env := setupFeiShu(t)
+	ctx := context.Background()
+	shengCai := service.NewShengCaiService(env.service, env.feiShu, env.cellDataRepo, env.sheetRepo, env.commentRepo,
+		service.NewNotifierService(env.service, env.client, env.cellDataRepo))
+
+	_, err := env.saveTableData(t)
+	require.NoError(t, err)
+
+	req := &v1.ShengCaiCommentsRequest{
+		SpreadsheetToken: fakeSpreadsheetToken,
+		Link:             "https://example.feishu.cn/docx/doxFakeMilkTea",
+	}
+	resp, err := shengCai.Comments(ctx, req)
+	require.NoError(t, err)
+	require.Len(t, resp.List, 2)
+
+	first := resp.List[0]
+	assert.Equal(t, "7001", first.CommentID)
+	assert.Equal(t, "选址比装修更重要", first.Quote)
+	require.Len(t, first.Replies, 2)
+	assert.Equal(t, "三线城市的租金一般占营业额多少比较合适？", first.Replies[0].Content)
+	assert.Equal(t, "@ou_reader1 控制在 15% 以内", first.Replies[1].Content)
+	assert.True(t, resp.List[1].Solved)
+
+	// 没有评论的文档返回空列表
+	resp, err = shengCai.Comments(ctx, &v1.ShengCaiCommentsRequest{
+		SpreadsheetToken: fakeSpreadsheetToken,
+		Link:             "https://example.feishu.cn/docx/doxFakeRedBook",
+	})
+	require.NoError(t, err)
+	assert.Empty(t, resp.List)
+
+	// 再次同步时以文档中最新的评论为准
+	fixtures, err := fakefeishu.LoadFixtures("../../fixtures/feishu")
+	require.NoError(t, err)
+	spreadsheet := fixtures.Spreadsheets[fakeSpreadsheetToken]
+	spreadsheet.LatestModifyTime = "1717181818"
+	env.fake.SetSpreadsheet(fakeSpreadsheetToken, spreadsheet)
+	env.fake.SetComments("doxFakeMilkTea", fixtures.Comments["doxFakeMilkTea"][1:])
+
+	_, err = env.saveTableData(t)
+	require.NoError(t, err)
+	resp, err = shengCai.Comments(ctx, req)
+	require.NoError(t, err)
+	require.Len(t, resp.List, 1)
+	assert.Equal(t, "7002", resp.List[0].CommentID)
+}
+
+func TestFeiShuService_CommentDigestInPrompt(t *testing.T) {
+	env := setupFeiShu(t)
+	env.conf.Set("ai.generate", "open")
+	env.conf.Set("ai.comment_digest", true)
+
+	_, err := env.saveTableData(t)
+	require.NoError(t, err)
+
+	// 摘要在事务提交后生成，评论摘录仍然附在提示词中
+	found := false
+	for _, prompt := range env.chat.prompts {
+		if strings.Contains(prompt, "读者评论：") && strings.Contains(prompt, "三线城市的租金") {
+			found = true
+		}
+	}
+	assert.True(t, found, "comment digest not found in prompts")
+}
@@ -0,0 +1,144 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// docxTextBlock 构造文本类的文档块，field 为块类型对应的字段名，如 heading1、bullet
+func docxTextBlock(id string, blockType int, field string, content string, children ...string) map[string]interface{} {
+	return map[string]interface{}{
+		"block_id":   id,
+		"block_type": blockType,
+		"children":   children,
+		field: map[string]interface{}{
+			"elements": []map[string]interface{}{{"text_run": map[string]interface{}{"content": content}}},
+		},
+	}
+}
+
+// docxPage 构造文档的根节点
+func docxPage(children ...string) map[string]interface{} {
+	return map[string]interface{}{"block_id": "doxFakeBlocks", "block_type": 1, "children": children}
+}
+
+func TestFeiShuService_GetDocumentData_DocxMarkdown(t *testing.T) {
+	tests := []struct {
+		name   string
+		blocks []map[string]interface{}
+		want   string
+	}{
+		{
+			name: "headings",
+			blocks: []map[string]interface{}{
+				docxPage("h1", "h2", "p", "h9"),
+				docxTextBlock("h1", 3, "heading1", "开店前的准备"),
+				docxTextBlock("h2", 4, "heading2", "选址"),
+				{
+					"block_id": "p", "block_type": 2,
+					"text": map[string]interface{}{"elements": []map[string]interface{}{
+						{"text_run": map[string]interface{}{"content": "人流量", "text_element_style": map[string]interface{}{"bold": true}}},
+						{"text_run": map[string]interface{}{"content": "决定", "text_element_style": map[string]interface{}{"link": map[string]interface{}{"url": "https%3A%2F%2Fexample.com%2F"}}}},
+					}},
+				},
+				docxTextBlock("h9", 11, "heading9", "附录"),
+			},
+			want: "# 开店前的准备\n\n## 选址\n\n**人流量**[决定](https://example.com/)\n\n###### 附录",
+		},
+		{
+			name: "lists",
+			blocks: []map[string]interface{}{
+				docxPage("b1", "b2", "o1", "o2", "t1"),
+				docxTextBlock("b1", 12, "bullet", "选址"),
+				docxTextBlock("b2", 12, "bullet", "装修"),
+				docxTextBlock("o1", 13, "ordered", "招聘"),
+				docxTextBlock("o2", 13, "ordered", "开业"),
+				{
+					"block_id": "t1", "block_type": 17,
+					"todo": map[string]interface{}{
+						"elements": []map[string]interface{}{{"text_run": map[string]interface{}{"content": "办证"}}},
+						"style":    map[string]interface{}{"done": true},
+					},
+				},
+			},
+			want: "- 选址\n- 装修\n1. 招聘\n2. 开业\n- [x] 办证",
+		},
+		{
+			name: "code",
+			blocks: []map[string]interface{}{
+				docxPage("c"),
+				docxTextBlock("c", 14, "code", "SELECT *\nFROM cell_data"),
+			},
+			want: "```\nSELECT *\nFROM cell_data\n```",
+		},
+		{
+			name: "table",
+			blocks: []map[string]interface{}{
+				docxPage("tbl"),
+				{
+					"block_id": "tbl", "block_type": 31, "children": []string{"c1", "c2", "c3", "c4"},
+					"table": map[string]interface{}{
+						"cells":    []string{"c1", "c2", "c3", "c4"},
+						"property": map[string]interface{}{"column_size": 2},
+					},
+				},
+				{"block_id": "c1", "block_type": 32, "children": []string{"c1t"}},
+				{"block_id": "c2", "block_type": 32, "children": []string{"c2t"}},
+				{"block_id": "c3", "block_type": 32, "children": []string{"c3t"}},
+				{"block_id": "c4", "block_type": 32, "children": []string{"c4t1", "c4t2"}},
+				docxTextBlock("c1t", 2, "text", "项目"),
+				docxTextBlock("c2t", 2, "text", "费用"),
+				docxTextBlock("c3t", 2, "text", "房租"),
+				docxTextBlock("c4t1", 2, "text", "5000|月"),
+				docxTextBlock("c4t2", 2, "text", "押一付三"),
+			},
+			want: "| 项目 | 费用 |\n| --- | --- |\n| 房租 | 5000\\|月<br>押一付三 |",
+		},
+		{
+			name: "media",
+			blocks: []map[string]interface{}{
+				docxPage("img", "file"),
+				{"block_id": "img", "block_type": 27, "image": map[string]interface{}{"token": "boxFakeImage"}},
+				{"block_id": "file", "block_type": 23, "file": map[string]interface{}{"token": "boxFakeFile", "name": "菜单.pdf"}},
+			},
+			want: "![](feishu-media://boxFakeImage)\n\n[菜单.pdf](feishu-media://boxFakeFile)",
+		},
+		{
+			name: "nested",
+			blocks: []map[string]interface{}{
+				docxPage("b1", "quote", "callout"),
+				docxTextBlock("b1", 12, "bullet", "成本", "b11", "o11"),
+				docxTextBlock("b11", 12, "bullet", "房租"),
+				docxTextBlock("o11", 13, "ordered", "人工"),
+				{"block_id": "quote", "block_type": 34, "children": []string{"q1", "q2"}},
+				docxTextBlock("q1", 2, "text", "第一行"),
+				docxTextBlock("q2", 2, "text", "第二行"),
+				// 高亮块等未单独处理的容器只输出其中的内容
+				{"block_id": "callout", "block_type": 19, "children": []string{"ct"}},
+				docxTextBlock("ct", 2, "text", "注意"),
+			},
+			want: "- 成本\n  - 房租\n  1. 人工\n\n> 第一行\n> \n> 第二行\n\n注意",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := setupFeiShu(t)
+			env.conf.Set("feishu.docx_markdown", true)
+			// 只验证转换结果，不下载素材
+			env.conf.Set("media.enabled", false)
+			env.fake.SetDocument("doxFakeBlocks", "raw content")
+			env.fake.SetDocumentBlocks("doxFakeBlocks", tt.blocks)
+
+			document, err := env.feiShu.GetDocumentData(context.Background(), fakeAppID, fakeAppSecret, map[string]string{
+				"link": "https://example.feishu.cn/docx/doxFakeBlocks",
+			})
+			require.NoError(t, err)
+			assert.Equal(t, "raw content", document.Content)
+			assert.Equal(t, tt.want, document.Markdown)
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	v1 "shengcai/api/v1"
+	"shengcai/internal/model"
+	"shengcai/internal/repository"
+	"shengcai/internal/service"
+)
+
+const (
+	eventEncryptKey        = "test-encrypt-key"
+	eventVerificationToken = "test-verification-token"
+)
+
+// countingShengCaiService 只记录 SyncSheet 的调用次数
+type countingShengCaiService struct {
+	service.ShengCaiService
+	syncs int32
+}
+
+func (s *countingShengCaiService) SyncSheet(ctx context.Context, sheetId string) error {
+	atomic.AddInt32(&s.syncs, 1)
+	return nil
+}
+
+func setupEvent(t *testing.T) (service.EventService, *countingShengCaiService) {
+	eventConf := viper.New()
+	eventConf.Set("feishu.event.encrypt_key", eventEncryptKey)
+	eventConf.Set("feishu.event.verification_token", eventVerificationToken)
+	eventConf.Set("feishu.event.debounce", 1)
+	return setupEventWithConf(t, eventConf)
+}
+
+func setupEventWithConf(t *testing.T, eventConf *viper.Viper) (service.EventService, *countingShengCaiService) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "shengcai.db")), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.SheetInfo{}))
+
+	repo := repository.NewRepository(logger, db, eventConf)
+	sheetRepo := repository.NewSheetInfoRepository(repo)
+	require.NoError(t, sheetRepo.Create(context.Background(), &model.SheetInfo{
+		SheetID: fakeSpreadsheetToken,
+		Status:  model.SheetStatusActive,
+	}))
+
+	shengCai := &countingShengCaiService{}
+	srv := service.NewService(repository.NewTransaction(repo), logger, sf, j, eventConf)
+	return service.NewEventService(srv, shengCai, sheetRepo), shengCai
+}
+
+// encryptEvent 按飞书的方式加密事件：AES-256-CBC，PKCS7 填充，IV 放在密文前
+func encryptEvent(t *testing.T, plain []byte) []byte {
+	key := sha256.Sum256([]byte(eventEncryptKey))
+	block, err := aes.NewCipher(key[:])
+	require.NoError(t, err)
+
+	padding := aes.BlockSize - len(plain)%aes.BlockSize
+	plain = append(plain, bytes.Repeat([]byte{byte(padding)}, padding)...)
+
+	data := make([]byte, aes.BlockSize+len(plain))
+	_, err = rand.Read(data[:aes.BlockSize])
+	require.NoError(t, err)
+	cipher.NewCBCEncrypter(block, data[:aes.BlockSize]).CryptBlocks(data[aes.BlockSize:], plain)
+
+	body, err := json.Marshal(map[string]string{"encrypt": base64.StdEncoding.EncodeToString(data)})
+	require.NoError(t, err)
+	return body
+}
+
+func signedEventRequest(body []byte) *service.EventRequest {
+	req := &service.EventRequest{Timestamp: "1717171717", Nonce: "nonce", Body: body}
+	h := sha256.New()
+	h.Write([]byte(req.Timestamp + req.Nonce + eventEncryptKey))
+	h.Write(body)
+	req.Signature = hex.EncodeToString(h.Sum(nil))
+	return req
+}
+
+func editEvent(t *testing.T, fileToken string) []byte {
+	plain, err := json.Marshal(map[string]interface{}{
+		"schema": "2.0",
+		"header": map[string]string{
+			"event_id":   "evt-" + fileToken,
+			"event_type": "drive.file.edit_v1",
+			"token":      eventVerificationToken,
+		},
+		"event": map[string]string{
+			"file_token": fileToken,
+			"file_type":  "sheet",
+		},
+	})
+	require.NoError(t, err)
+	return encryptEvent(t, plain)
+}
+
+func TestEventService_URLVerification(t *testing.T) {
+	eventService, _ := setupEvent(t)
+
+	plain := []byte(`{"challenge":"abc123","token":"` + eventVerificationToken + `","type":"url_verification"}`)
+	resp, err := eventService.Handle(context.Background(), &service.EventRequest{Body: encryptEvent(t, plain)})
+	require.NoError(t, err)
+	assert.Equal(t, "abc123", resp.Challenge)
+}
+
+func TestEventService_WrongToken(t *testing.T) {
+	eventService, _ := setupEvent(t)
+
+	plain := []byte(`{"challenge":"abc123","token":"wrong","type":"url_verification"}`)
+	_, err := eventService.Handle(context.Background(), &service.EventRequest{Body: encryptEvent(t, plain)})
+	assert.ErrorIs(t, err, v1.ErrUnauthorized)
+}
+
+func TestEventService_NotConfigured(t *testing.T) {
+	eventConf := viper.New()
+	eventConf.Set("feishu.event.debounce", 1)
+	eventService, shengCai := setupEventWithConf(t, eventConf)
+
+	// 未配置任何密钥时，明文的事件同样被拒绝，不会触发同步
+	plain, err := json.Marshal(map[string]interface{}{
+		"schema": "2.0",
+		"header": map[string]string{"event_id": "evt-plain", "event_type": "drive.file.edit_v1"},
+		"event":  map[string]string{"file_token": fakeSpreadsheetToken, "file_type": "sheet"},
+	})
+	require.NoError(t, err)
+	_, err = eventService.Handle(context.Background(), &service.EventRequest{Body: plain})
+	assert.ErrorIs(t, err, v1.ErrServiceUnavailable)
+
+	_, err = eventService.Handle(context.Background(), &service.EventRequest{Body: []byte(`{"challenge":"abc123","type":"url_verification"}`)})
+	assert.ErrorIs(t, err, v1.ErrServiceUnavailable)
+
+	time.Sleep(1500 * time.Millisecond)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&shengCai.syncs))
+}
+
+func TestEventService_InvalidSignature(t *testing.T) {
+	eventService, shengCai := setupEvent(t)
+
+	req := signedEventRequest(editEvent(t, fakeSpreadsheetToken))
+	req.Signature = "invalid"
+	_, err := eventService.Handle(context.Background(), req)
+	assert.ErrorIs(t, err, v1.ErrUnauthorized)
+
+	time.Sleep(1500 * time.Millisecond)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&shengCai.syncs))
+}
+
+func TestEventService_DebouncedSync(t *testing.T) {
+	eventService, shengCai := setupEvent(t)
+
+	for i := 0; i < 3; i++ {
+		_, err := eventService.Handle(context.Background(), signedEventRequest(editEvent(t, fakeSpreadsheetToken)))
+		require.NoError(t, err)
+	}
+	// 未登记的文件不会触发同步
+	_, err := eventService.Handle(context.Background(), signedEventRequest(editEvent(t, "shtUnknown")))
+	require.NoError(t, err)
+
+	assert.Equal(t, int32(0), atomic.LoadInt32(&shengCai.syncs))
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&shengCai.syncs) == 1
+	}, 3*time.Second, 100*time.Millisecond)
+
+	time.Sleep(1500 * time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&shengCai.syncs))
+}
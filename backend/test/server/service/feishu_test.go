@@ -0,0 +1,627 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	v1 "shengcai/api/v1"
+	"shengcai/internal/model"
+	"shengcai/internal/repository"
+	"shengcai/internal/service"
+	"shengcai/pkg/blob"
+	"shengcai/pkg/fakefeishu"
+	"shengcai/pkg/feishu"
+	"shengcai/pkg/llm"
+)
+
+const (
+	fakeSpreadsheetToken = "shtFakeArticles"
+	fakeAppID            = "cli_fake"
+	fakeAppSecret        = "fake_secret"
+)
+
+type feiShuTestEnv struct {
+	fake         *fakefeishu.Server
+	db           *gorm.DB
+	conf         *viper.Viper
+	service      *service.Service
+	tm           repository.Transaction
+	client       *feishu.Client
+	feiShu       service.FeiShuService
+	sheetRepo    repository.SheetInfoRepository
+	cellDataRepo repository.CellDataRepository
+	commentRepo  repository.CommentRepository
+	aiUsageRepo  repository.AIUsageRepository
+	chat         *recordingChat
+	media        service.MediaService
+}
+
+// setupFeiShu 启动模拟的飞书服务，并使用临时的 sqlite 数据库组装同步流程
+func setupFeiShu(t *testing.T) *feiShuTestEnv {
+	fixtures, err := fakefeishu.LoadFixtures("../../fixtures/feishu")
+	require.NoError(t, err)
+
+	fake := fakefeishu.NewServer(fixtures)
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "shengcai.db")), &gorm.Config{})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	// 同步时多个协程并发写入，sqlite 只使用一个连接避免锁冲突
+	sqlDB.SetMaxOpenConns(1)
+	require.NoError(t, db.AutoMigrate(&model.SheetInfo{}, &model.CellData{}, &model.Comment{}, &model.Media{}, &model.AIUsage{}, &model.SummaryCache{}))
+
+	feiShuConf := viper.New()
+	feiShuConf.Set("feishu.base_url", srv.URL)
+	feiShuConf.Set("feishu.max_retries", 3)
+	feiShuConf.Set("feishu.comments", true)
+	feiShuConf.Set("ai.generate", "close")
+	feiShuConf.Set("media.enabled", true)
+
+	repo := repository.NewRepository(logger, db, feiShuConf)
+	sheetRepo := repository.NewSheetInfoRepository(repo)
+	aiUsageRepo := repository.NewAIUsageRepository(repo)
+	chat := &recordingChat{Provider: llm.NewFake(llm.Options{})}
+	cellDataRepo := repository.NewCellDataRepository(repo, repository.NewAIRepository(repo, chat, aiUsageRepo))
+	commentRepo := repository.NewCommentRepository(repo)
+	tm := repository.NewTransaction(repo)
+	srvService := service.NewService(tm, logger, sf, j, feiShuConf)
+
+	require.NoError(t, sheetRepo.Create(context.Background(), &model.SheetInfo{
+		SheetID: fakeSpreadsheetToken,
+		Status:  model.SheetStatusActive,
+	}))
+
+	client := feishu.NewClient(feiShuConf)
+	media := service.NewMediaService(srvService, client, repository.NewMediaRepository(repo), blob.NewLocalStore(t.TempDir()))
+	return &feiShuTestEnv{
+		fake:         fake,
+		db:           db,
+		conf:         feiShuConf,
+		service:      srvService,
+		tm:           tm,
+		client:       client,
+		feiShu:       service.NewFeiShuService(srvService, client, sheetRepo, cellDataRepo, commentRepo, media),
+		sheetRepo:    sheetRepo,
+		cellDataRepo: cellDataRepo,
+		commentRepo:  commentRepo,
+		aiUsageRepo:  aiUsageRepo,
+		chat:         chat,
+		media:        media,
+	}
+}
+
+// recordingChat 记录发给模型的提示词
+type recordingChat struct {
+	llm.Provider
+	mu      sync.Mutex
+	prompts []string
+}
+
+func (c *recordingChat) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatResponse, error) {
+	c.mu.Lock()
+	for _, message := range req.Messages {
+		c.prompts = append(c.prompts, message.Content)
+	}
+	c.mu.Unlock()
+	return c.Provider.Chat(ctx, req)
+}
+
+func (e *feiShuTestEnv) saveTableData(t *testing.T) (*model.SyncResult, error) {
+	sheetInfo, err := e.sheetRepo.GetBySheetID(context.Background(), fakeSpreadsheetToken)
+	require.NoError(t, err)
+	return e.feiShu.SaveTableData(context.Background(), fakeAppID, fakeAppSecret, sheetInfo)
+}
+
+func (e *feiShuTestEnv) cellData(t *testing.T) map[string]*model.CellData {
+	var list []*model.CellData
+	require.NoError(t, e.db.Where("sheet_id = ?", fakeSpreadsheetToken).Find(&list).Error)
+
+	result := make(map[string]*model.CellData, len(list))
+	for _, item := range list {
+		result[item.Title] = item
+	}
+	return result
+}
+
+func TestFeiShuService_SaveTableData(t *testing.T) {
+	env := setupFeiShu(t)
+
+	result, err := env.saveTableData(t)
+	require.NoError(t, err)
+	assert.False(t, result.Skipped)
+	assert.Equal(t, 3, result.Rows)
+	assert.Len(t, result.Created, 3)
+
+	rows := env.cellData(t)
+	require.Len(t, rows, 3)
+
+	milkTea := rows["如何开一家奶茶店"]
+	require.NotNil(t, milkTea)
+	assert.Equal(t, "https://example.feishu.cn/docx/doxFakeMilkTea", milkTea.Link)
+	assert.Equal(t, "docx", milkTea.LinkType)
+	assert.Equal(t, "2024/05/01", milkTea.ReleaseDate)
+	assert.Equal(t, "tab001", milkTea.TabID)
+	assert.Contains(t, milkTea.Content, "奶茶店")
+	// 测试中关闭了 AI 生成
+	assert.Equal(t, model.AIStateSkipped, milkTea.AIState)
+
+	interview := rows["线下实体复苏访谈"]
+	require.NotNil(t, interview)
+	assert.Equal(t, "tab002", interview.TabID)
+	assert.Equal(t, 1, interview.TabIndex)
+
+	sheetInfo, err := env.sheetRepo.GetBySheetID(context.Background(), fakeSpreadsheetToken)
+	require.NoError(t, err)
+	assert.Equal(t, "1717171717", sheetInfo.LatestModifyTime)
+	assert.Equal(t, 12, sheetInfo.Revision)
+}
+
+func TestShengCaiService_ListByTab(t *testing.T) {
+	env := setupFeiShu(t)
+	ctx := context.Background()
+	_, err := env.saveTableData(t)
+	require.NoError(t, err)
+	shengCai := service.NewShengCaiService(env.service, env.feiShu, env.cellDataRepo, env.sheetRepo, env.commentRepo,
+		service.NewNotifierService(env.service, env.client, env.cellDataRepo))
+
+	tests := []struct {
+		tabID  string
+		titles []string
+	}{
+		{tabID: "", titles: []string{"如何开一家奶茶店", "小红书起号复盘", "线下实体复苏访谈"}},
+		{tabID: "tab001", titles: []string{"如何开一家奶茶店", "小红书起号复盘"}},
+		{tabID: "tab002", titles: []string{"线下实体复苏访谈"}},
+		{tabID: "tabMissing"},
+	}
+	for _, tt := range tests {
+		list, err := shengCai.List(ctx, &v1.ShengCaiListRequest{SpreadsheetToken: fakeSpreadsheetToken, TabID: tt.tabID, Page: 1})
+		require.NoError(t, err)
+		var titles []string
+		for _, item := range list.List {
+			titles = append(titles, item.Title)
+		}
+		assert.ElementsMatch(t, tt.titles, titles, tt.tabID)
+		assert.Equal(t, len(tt.titles), list.TotalCount, tt.tabID)
+	}
+
+	metaData, err := shengCai.GetMetaData(ctx, &v1.ShengCaiGetMetaDataRequest{SpreadsheetToken: fakeSpreadsheetToken})
+	require.NoError(t, err)
+	require.Len(t, metaData.Tabs, 2)
+	assert.Equal(t, "tab001", metaData.Tabs[0].TabID)
+	assert.Equal(t, "实战访谈", metaData.Tabs[1].TabTitle)
+}
+
+func TestFeiShuService_SaveTableData_Unchanged(t *testing.T) {
+	env := setupFeiShu(t)
+
+	_, err := env.saveTableData(t)
+	require.NoError(t, err)
+	rawContentRequests := env.fake.Requests("/open-apis/docx/")
+
+	result, err := env.saveTableData(t)
+	require.NoError(t, err)
+	assert.True(t, result.Skipped)
+	assert.Equal(t, rawContentRequests, env.fake.Requests("/open-apis/docx/"))
+}
+
+func TestFeiShuService_SaveTableData_RateLimited(t *testing.T) {
+	env := setupFeiShu(t)
+	env.fake.AddFault(fakefeishu.RateLimited("/open-apis/sheets/v2/", 2))
+
+	result, err := env.saveTableData(t)
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Rows)
+	assert.Len(t, env.cellData(t), 3)
+}
+
+func TestFeiShuService_SaveTableData_DocumentForbidden(t *testing.T) {
+	env := setupFeiShu(t)
+	env.fake.AddFault(fakefeishu.Forbidden("/open-apis/docx/v1/documents/doxFakeRedBook", 0))
+
+	_, err := env.saveTableData(t)
+	require.NoError(t, err)
+
+	rows := env.cellData(t)
+	require.Len(t, rows, 3)
+	assert.True(t, strings.HasPrefix(rows["小红书起号复盘"].Content, "Error processing link"))
+	assert.Contains(t, rows["如何开一家奶茶店"].Content, "奶茶店")
+}
+
+func TestFeiShuService_SaveTableData_RowRemoved(t *testing.T) {
+	env := setupFeiShu(t)
+
+	_, err := env.saveTableData(t)
+	require.NoError(t, err)
+
+	env.fake.SetSpreadsheet(fakeSpreadsheetToken, &fakefeishu.Spreadsheet{
+		LatestModifyTime: "1717181818",
+		Revision:         13,
+		Sheets: []*fakefeishu.Sheet{{
+			SheetID: "tab001",
+			Title:   "精华帖",
+			Values: [][]interface{}{
+				{"序号", "标题", "作者", "发布日期"},
+				{1, []interface{}{map[string]interface{}{"type": "url", "text": "如何开一家奶茶店", "link": "https://example.feishu.cn/docx/doxFakeMilkTea"}}, "易生", "2024/05/01"},
+			},
+		}},
+	})
+
+	result, err := env.saveTableData(t)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		"https://example.feishu.cn/docx/doxFakeRedBook",
+		"https://example.feishu.cn/docx/doxFakeInterview",
+	}, result.Deleted)
+
+	rows := env.cellData(t)
+	assert.False(t, rows["如何开一家奶茶店"].Deleted)
+	assert.True(t, rows["小红书起号复盘"].Deleted)
+}
+
+func TestFeiShuService_GetDocumentData_Slow(t *testing.T) {
+	env := setupFeiShu(t)
+	env.fake.AddFault(fakefeishu.Slow("/open-apis/docx/", time.Second, 0))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	_, err := env.feiShu.GetDocumentData(ctx, fakeAppID, fakeAppSecret, map[string]string{
+		"link": "https://example.feishu.cn/docx/doxFakeMilkTea",
+	})
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
+
+func TestFeiShuService_GetDocumentData_NotFound(t *testing.T) {
+	env := setupFeiShu(t)
+
+	_, err := env.feiShu.GetDocumentData(context.Background(), fakeAppID, fakeAppSecret, map[string]string{
+		"link": "https://example.feishu.cn/docx/doxMissing",
+	})
+	assert.True(t, errors.Is(err, feishu.ErrNotFound))
+}
+
+func TestFeiShuService_GetDocumentData_Wiki(t *testing.T) {
+	env := setupFeiShu(t)
+	env.fake.SetWikiNode("wikcnFakeSheet", &fakefeishu.WikiNode{ObjToken: fakeSpreadsheetToken, ObjType: "sheet"})
+	env.fake.SetWikiNode("wikcnFakeBitable", &fakefeishu.WikiNode{ObjToken: fakeBitableToken, ObjType: "bitable"})
+
+	tests := []struct {
+		nodeToken string
+		linkType  string
+		token     string
+	}{
+		{nodeToken: "wikcnFakeMilkTea", linkType: "docx", token: "doxFakeMilkTea"},
+		{nodeToken: "wikcnFakeSheet", linkType: "sheet", token: fakeSpreadsheetToken},
+		{nodeToken: "wikcnFakeBitable", linkType: "bitable", token: fakeBitableToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.linkType, func(t *testing.T) {
+			link := map[string]string{"link": "https://example.feishu.cn/wiki/" + tt.nodeToken + "?from=from_copylink"}
+			document, err := env.feiShu.GetDocumentData(context.Background(), fakeAppID, fakeAppSecret, link)
+			require.NoError(t, err)
+			assert.Equal(t, tt.linkType, document.LinkType)
+			assert.Equal(t, tt.token, document.Token)
+			assert.NotEmpty(t, document.Content)
+
+			// 再次获取时节点从缓存中读取
+			before := env.fake.Requests("/open-apis/wiki/")
+			_, err = env.feiShu.GetDocumentData(context.Background(), fakeAppID, fakeAppSecret, link)
+			require.NoError(t, err)
+			assert.Equal(t, before, env.fake.Requests("/open-apis/wiki/"))
+		})
+	}
+	assert.Equal(t, len(tests), env.fake.Requests("/open-apis/wiki/"))
+
+	document, err := env.feiShu.GetDocumentData(context.Background(), fakeAppID, fakeAppSecret, map[string]string{
+		"link": "https://example.feishu.cn/wiki/wikcnMissing",
+	})
+	assert.ErrorIs(t, err, feishu.ErrNotFound)
+	assert.Equal(t, "wiki", document.LinkType)
+}
+
+func TestFeiShuService_GetDocumentData_ExternalPrivateAddress(t *testing.T) {
+	env := setupFeiShu(t)
+	requested := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = true
+	}))
+	defer srv.Close()
+
+	for _, link := range []string{srv.URL, "http://169.254.169.254/latest/meta-data/", "http://10.0.0.1/", "http://100.64.0.1/"} {
+		document, err := env.feiShu.GetDocumentData(context.Background(), fakeAppID, fakeAppSecret, map[string]string{
+			"link": link,
+		})
+		assert.ErrorContains(t, err, "is not allowed", link)
+		assert.Equal(t, "external", document.LinkType)
+	}
+	assert.False(t, requested)
+}
+
+func TestFeiShuService_GetDocumentData_Minutes(t *testing.T) {
+	env := setupFeiShu(t)
+
+	document, err := env.feiShu.GetDocumentData(context.Background(), fakeAppID, fakeAppSecret, map[string]string{
+		"link": "https://example.feishu.cn/minutes/obcnFakeMinutes",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "minutes", document.LinkType)
+	assert.Empty(t, document.Content)
+	// 妙记不请求飞书接口
+	assert.Equal(t, 0, env.fake.Requests("/open-apis/minutes/"))
+}
+
+const fakeBitableToken = "bascnFakeArticles"
+
+func (e *feiShuTestEnv) saveBitableData(t *testing.T) (*model.SyncResult, error) {
+	sheetInfo, err := e.sheetRepo.GetBySheetID(context.Background(), fakeBitableToken)
+	require.NoError(t, err)
+	return e.feiShu.SaveBitableData(context.Background(), fakeAppID, fakeAppSecret, sheetInfo)
+}
+
+func (e *feiShuTestEnv) bitableData(t *testing.T) map[string]*model.CellData {
+	var list []*model.CellData
+	require.NoError(t, e.db.Where("sheet_id = ?", fakeBitableToken).Find(&list).Error)
+
+	result := make(map[string]*model.CellData, len(list))
+	for _, item := range list {
+		result[item.RecordID] = item
+	}
+	return result
+}
+
+func TestFeiShuService_SaveBitableData(t *testing.T) {
+	env := setupFeiShu(t)
+	require.NoError(t, env.sheetRepo.Create(context.Background(), &model.SheetInfo{
+		SheetID:    fakeBitableToken,
+		SourceType: model.SourceTypeBitable,
+		Status:     model.SheetStatusActive,
+		ColumnMapping: model.ColumnMapping{
+			Title: "标题",
+			Link:  "链接",
+			Date:  "发布日期",
+			Extra: map[string]string{"author": "作者"},
+		},
+	}))
+
+	result, err := env.saveBitableData(t)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Rows)
+
+	rows := env.bitableData(t)
+	require.Len(t, rows, 2)
+	milkTea := rows["recFakeMilkTea"]
+	require.NotNil(t, milkTea)
+	assert.Equal(t, "奶茶店从 0 到月入 10 万的复盘", milkTea.Title)
+	assert.Equal(t, "https://example.feishu.cn/docx/doxFakeMilkTea", milkTea.Link)
+	assert.Equal(t, "2024-06-01", milkTea.ReleaseDate)
+	assert.Equal(t, "tblFakeArticles", milkTea.TabID)
+	assert.Equal(t, "阿杰", milkTea.Extra["author"])
+	assert.Contains(t, milkTea.Content, "奶茶店")
+
+	// 修改记录的链接后仍然按 record_id 更新同一行，删除的记录被标记
+	env.fake.SetBitable(fakeBitableToken, &fakefeishu.Bitable{
+		LatestModifyTime: "1717300000",
+		Tables: []*fakefeishu.BitableTable{{
+			TableID: "tblFakeArticles",
+			Name:    "精华帖",
+			Records: []*fakefeishu.BitableRecord{{
+				RecordID: "recFakeMilkTea",
+				Fields: map[string]interface{}{
+					"标题": []interface{}{map[string]interface{}{"type": "text", "text": "奶茶店复盘（修订版）"}},
+					"链接": map[string]interface{}{"link": "https://example.feishu.cn/docx/doxFakeInterview", "text": "原文"},
+				},
+			}},
+		}},
+	})
+
+	result, err = env.saveBitableData(t)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://example.feishu.cn/docx/doxFakeRedBook"}, result.Deleted)
+
+	rows = env.bitableData(t)
+	require.Len(t, rows, 2)
+	assert.Equal(t, "https://example.feishu.cn/docx/doxFakeInterview", rows["recFakeMilkTea"].Link)
+	assert.False(t, rows["recFakeMilkTea"].Deleted)
+	assert.True(t, rows["recFakeRedBook"].Deleted)
+
+	result, err = env.saveBitableData(t)
+	require.NoError(t, err)
+	assert.True(t, result.Skipped)
+}
+
+func TestFeiShuService_SaveTableData_ReadWindow(t *testing.T) {
+	env := setupFeiShu(t)
+	// 每次读取 3 行，7 行数据需要分 3 批读取
+	env.conf.Set("feishu.read_window", 3)
+
+	values := [][]interface{}{{"序号", "标题", "作者", "发布日期"}}
+	for i := 1; i <= 7; i++ {
+		link := fmt.Sprintf("https://example.feishu.cn/docx/doxFakeMilkTea?row=%d", i)
+		values = append(values, []interface{}{i, []interface{}{map[string]interface{}{"type": "url", "text": fmt.Sprintf("文章 %d", i), "link": link}}, "易生", "2024/05/01"})
+	}
+	env.fake.SetSpreadsheet(fakeSpreadsheetToken, &fakefeishu.Spreadsheet{
+		LatestModifyTime: "1717171717",
+		Sheets:           []*fakefeishu.Sheet{{SheetID: "tab001", Title: "精华帖", Values: values}},
+	})
+
+	result, err := env.saveTableData(t)
+	require.NoError(t, err)
+	assert.Equal(t, 7, result.Rows)
+	assert.Len(t, result.Created, 7)
+	// 3 批数据，另有一次读取 A1 获取表格的版本号
+	assert.Equal(t, 3+1, env.fake.Requests("/open-apis/sheets/v2/spreadsheets/"+fakeSpreadsheetToken+"/values/"))
+
+	rows := env.cellData(t)
+	require.Len(t, rows, 7)
+	for i := 1; i <= 7; i++ {
+		row := rows[fmt.Sprintf("文章 %d", i)]
+		require.NotNil(t, row, i)
+		assert.Equal(t, i-1, row.SortNumber)
+	}
+}
+
+func TestFeiShuService_SaveTableData_ReorderedColumns(t *testing.T) {
+	env := setupFeiShu(t)
+	ctx := context.Background()
+
+	// 列的顺序与默认布局不同，表头也改了名字，按表头名称和列字母混合配置
+	sheetInfo, err := env.sheetRepo.GetBySheetID(ctx, fakeSpreadsheetToken)
+	require.NoError(t, err)
+	sheetInfo.ColumnMapping = model.ColumnMapping{
+		Title: "文章标题",
+		Link:  "原文链接",
+		Date:  "A",
+		Extra: map[string]string{"author": "作者 ", "no": "E"},
+	}
+	require.NoError(t, env.sheetRepo.Save(ctx, sheetInfo))
+
+	env.fake.SetSpreadsheet(fakeSpreadsheetToken, &fakefeishu.Spreadsheet{
+		LatestModifyTime: "1717171717",
+		Sheets: []*fakefeishu.Sheet{{
+			SheetID: "tab001",
+			Title:   "精华帖",
+			Values: [][]interface{}{
+				{"上线日期", "原文链接", " 作者", "文章标题", "编号"},
+				{"2024/05/01", "https://example.feishu.cn/docx/doxFakeMilkTea", "易生", "如何开一家奶茶店", 1},
+				{"2024/05/08", []interface{}{map[string]interface{}{"type": "url", "text": "链接", "link": "https://example.feishu.cn/docx/doxFakeRedBook"}}, "亮哥", "小红书起号复盘", 2},
+			},
+		}},
+	})
+
+	result, err := env.saveTableData(t)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Rows)
+
+	rows := env.cellData(t)
+	require.Len(t, rows, 2)
+	milkTea := rows["如何开一家奶茶店"]
+	require.NotNil(t, milkTea)
+	assert.Equal(t, "https://example.feishu.cn/docx/doxFakeMilkTea", milkTea.Link)
+	assert.Equal(t, "2024/05/01", milkTea.ReleaseDate)
+	assert.Equal(t, map[string]string{"author": "易生", "no": "1"}, milkTea.Extra)
+	redBook := rows["小红书起号复盘"]
+	require.NotNil(t, redBook)
+	assert.Equal(t, "https://example.feishu.cn/docx/doxFakeRedBook", redBook.Link)
+	assert.Equal(t, "2024/05/08", redBook.ReleaseDate)
+
+	var extra string
+	require.NoError(t, env.db.Model(&model.CellData{}).Select("extra").Where("id = ?", redBook.ID).Scan(&extra).Error)
+	assert.JSONEq(t, `{"author":"亮哥","no":"2"}`, extra)
+}
+
+func TestFeiShuService_SaveTableData_LetterLikeHeaders(t *testing.T) {
+	env := setupFeiShu(t)
+	ctx := context.Background()
+
+	// "URL"、"Tag" 同时也是合法的列字母，按表头名称匹配，不能解析为超出表格范围的列
+	sheetInfo, err := env.sheetRepo.GetBySheetID(ctx, fakeSpreadsheetToken)
+	require.NoError(t, err)
+	sheetInfo.ColumnMapping = model.ColumnMapping{
+		Title: "标题",
+		Link:  "URL",
+		Date:  "D",
+		Extra: map[string]string{"tag": "Tag"},
+	}
+	require.NoError(t, env.sheetRepo.Save(ctx, sheetInfo))
+
+	env.fake.SetSpreadsheet(fakeSpreadsheetToken, &fakefeishu.Spreadsheet{
+		LatestModifyTime: "1717171717",
+		Sheets: []*fakefeishu.Sheet{{
+			SheetID: "tab001",
+			Title:   "精华帖",
+			Values: [][]interface{}{
+				{"Tag", "标题", "URL", "发布日期"},
+				{"开店", "如何开一家奶茶店", "https://example.feishu.cn/docx/doxFakeMilkTea", "2024/05/01"},
+			},
+		}},
+	})
+
+	result, err := env.saveTableData(t)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Rows)
+
+	milkTea := env.cellData(t)["如何开一家奶茶店"]
+	require.NotNil(t, milkTea)
+	assert.Equal(t, "https://example.feishu.cn/docx/doxFakeMilkTea", milkTea.Link)
+	assert.Equal(t, "2024/05/01", milkTea.ReleaseDate)
+	assert.Equal(t, map[string]string{"tag": "开店"}, milkTea.Extra)
+}
+
+func TestFeiShuService_SaveTableData_WriteBack(t *testing.T) {
+	env := setupFeiShu(t)
+	ctx := context.Background()
+
+	sheetInfo, err := env.sheetRepo.GetBySheetID(ctx, fakeSpreadsheetToken)
+	require.NoError(t, err)
+	sheetInfo.ColumnMapping = model.ColumnMapping{Title: "B", Date: "D", Abstract: "E", Keyword: "关键字"}
+	require.NoError(t, env.sheetRepo.Save(ctx, sheetInfo))
+
+	header := []interface{}{"序号", "标题", "作者", "发布日期", "摘要", "关键字"}
+	spreadsheet := &fakefeishu.Spreadsheet{
+		LatestModifyTime: "1717171717",
+		Revision:         12,
+		Sheets: []*fakefeishu.Sheet{{
+			SheetID: "tab001",
+			Title:   "精华帖",
+			Values: [][]interface{}{
+				header,
+				{1, []interface{}{map[string]interface{}{"type": "url", "text": "如何开一家奶茶店", "link": "https://example.feishu.cn/docx/doxFakeMilkTea"}}, "易生", "2024/05/01", nil, nil},
+				{2, []interface{}{map[string]interface{}{"type": "url", "text": "小红书起号复盘", "link": "https://example.feishu.cn/docx/doxFakeRedBook"}}, "亮哥", "2024/05/08", "编辑写的摘要", nil},
+			},
+		}},
+	}
+	env.fake.SetSpreadsheet(fakeSpreadsheetToken, spreadsheet)
+
+	// 关闭了 AI 生成，第一次同步没有可以回写的内容
+	result, err := env.saveTableData(t)
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Written)
+
+	require.NoError(t, env.db.Model(&model.CellData{}).Where("sheet_id = ?", fakeSpreadsheetToken).
+		Updates(map[string]interface{}{"abstract": "AI 摘要", "keyword": "奶茶,创业"}).Error)
+	spreadsheet.LatestModifyTime = "1717181818"
+
+	result, err = env.saveTableData(t)
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Written)
+	assert.Equal(t, "AI 摘要", spreadsheet.Sheets[0].Values[1][4])
+	assert.Equal(t, "奶茶,创业", spreadsheet.Sheets[0].Values[1][5])
+	assert.Equal(t, "编辑写的摘要", spreadsheet.Sheets[0].Values[2][4])
+	assert.Equal(t, "奶茶,创业", spreadsheet.Sheets[0].Values[2][5])
+	assert.Equal(t, 1, env.fake.Requests("/open-apis/sheets/v2/spreadsheets/"+fakeSpreadsheetToken+"/values_batch_update"))
+
+	// 回写后记录了新的版本号，不会因为自己的写入再次同步
+	result, err = env.saveTableData(t)
+	require.NoError(t, err)
+	assert.True(t, result.Skipped)
+
+	// 编辑修改过的单元格不再覆盖，仍是上次写入内容的单元格随摘要更新
+	spreadsheet.Sheets[0].Values[1][4] = "编辑改过的摘要"
+	spreadsheet.LatestModifyTime = "1717191919"
+	require.NoError(t, env.db.Model(&model.CellData{}).Where("sheet_id = ?", fakeSpreadsheetToken).
+		Updates(map[string]interface{}{"abstract": "新的 AI 摘要", "keyword": "奶茶,创业,复盘"}).Error)
+
+	result, err = env.saveTableData(t)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Written)
+	assert.Equal(t, "编辑改过的摘要", spreadsheet.Sheets[0].Values[1][4])
+	assert.Equal(t, "奶茶,创业,复盘", spreadsheet.Sheets[0].Values[1][5])
+	assert.Equal(t, "编辑写的摘要", spreadsheet.Sheets[0].Values[2][4])
+	assert.Equal(t, "奶茶,创业,复盘", spreadsheet.Sheets[0].Values[2][5])
+}
@@ -0,0 +1,90 @@
+package service_test
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "shengcai/api/v1"
+	"shengcai/internal/model"
+	"shengcai/internal/service"
+	"shengcai/pkg/fakefeishu"
+	"shengcai/pkg/feishu"
+)
+
+func TestMediaService_Mirror(t *testing.T) {
+	env := setupFeiShu(t)
+	ctx := context.Background()
+
+	cover, err := fakefeishu.LoadFixtures("../../fixtures/feishu")
+	require.NoError(t, err)
+	data := cover.Media["boxFakeCover"].Data
+	// 同一张图片以附件的形式再上传一次，内容相同但 token 不同
+	env.fake.SetMedia("boxFakeCoverCopy", &fakefeishu.Media{FileName: "封面.png", ContentType: "image/png", Data: data})
+
+	document := &service.Document{
+		Content:  "开店前的准备\nboxFakeCover.png\n附件 boxFakeCoverCopy\n失效的图片 boxFakeMissing.png",
+		Markdown: "# 开店前的准备\n\n![](feishu-media://boxFakeCover)\n\n[封面.png](feishu-media://boxFakeCoverCopy)\n\n![](feishu-media://boxFakeMissing)\n",
+	}
+	require.NoError(t, env.media.Mirror(ctx, fakeAppID, fakeAppSecret, document))
+
+	var list []*model.Media
+	require.NoError(t, env.db.Order("id").Find(&list).Error)
+	require.Len(t, list, 2)
+	assert.Equal(t, "boxFakeCover", list[0].Token)
+	assert.Equal(t, "image/png", list[0].ContentType)
+	assert.Equal(t, "boxFakeCover.png", list[0].FileName)
+	assert.Equal(t, "封面.png", list[1].FileName)
+	// 内容相同的素材只保存一份
+	assert.Equal(t, list[0].StorageKey, list[1].StorageKey)
+
+	coverURL := "/v1/media/" + strconv.Itoa(list[0].ID)
+	copyURL := "/v1/media/" + strconv.Itoa(list[1].ID)
+	assert.Contains(t, document.Markdown, "![]("+coverURL+")")
+	assert.Contains(t, document.Markdown, "[封面.png]("+copyURL+")")
+	// 下载失败的素材保留原来的引用，下次同步时重试
+	assert.Contains(t, document.Markdown, "feishu-media://boxFakeMissing")
+	assert.Contains(t, document.Content, "\n"+coverURL+"\n")
+	assert.Contains(t, document.Content, "附件 "+copyURL+"\n")
+	assert.Contains(t, document.Content, "boxFakeMissing.png")
+
+	media, reader, err := env.media.Open(ctx, list[0].ID)
+	require.NoError(t, err)
+	defer reader.Close()
+	assert.Equal(t, "image/png", media.ContentType)
+	content, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, data, content)
+
+	_, _, err = env.media.Open(ctx, list[1].ID+1)
+	assert.ErrorIs(t, err, v1.ErrNotFound)
+
+	// 再次同步时复用已下载的素材
+	again := &service.Document{Markdown: "![](feishu-media://boxFakeCover)"}
+	require.NoError(t, env.media.Mirror(ctx, fakeAppID, fakeAppSecret, again))
+	assert.Equal(t, "![]("+coverURL+")", again.Markdown)
+	var count int64
+	require.NoError(t, env.db.Model(&model.Media{}).Count(&count).Error)
+	assert.Equal(t, int64(2), count)
+}
+
+func TestMediaService_Mirror_TooLarge(t *testing.T) {
+	env := setupFeiShu(t)
+	ctx := context.Background()
+	env.conf.Set("media.max_size", 16)
+	env.fake.SetMedia("boxFakeLarge", &fakefeishu.Media{FileName: "large.png", ContentType: "image/png", Data: make([]byte, 1024)})
+
+	_, _, err := env.client.Download(ctx, fakeAppID, fakeAppSecret, "/open-apis/drive/v1/medias/boxFakeLarge/download", 16)
+	assert.ErrorIs(t, err, feishu.ErrTooLarge)
+
+	// 超过上限的素材不保存，保留原来的引用
+	document := &service.Document{Markdown: "![](feishu-media://boxFakeLarge)"}
+	require.NoError(t, env.media.Mirror(ctx, fakeAppID, fakeAppSecret, document))
+	assert.Equal(t, "![](feishu-media://boxFakeLarge)", document.Markdown)
+	var count int64
+	require.NoError(t, env.db.Model(&model.Media{}).Count(&count).Error)
+	assert.Equal(t, int64(0), count)
+}
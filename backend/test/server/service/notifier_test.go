@@ -0,0 +1,124 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"shengcai/internal/model"
+	"shengcai/internal/service"
+	"shengcai/pkg/feishu"
+)
+
+// botRecorder 模拟群自定义机器人，记录收到的消息
+type botRecorder struct {
+	mu       sync.Mutex
+	messages []map[string]interface{}
+}
+
+func (b *botRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var message map[string]interface{}
+	_ = json.NewDecoder(r.Body).Decode(&message)
+	b.mu.Lock()
+	b.messages = append(b.messages, message)
+	b.mu.Unlock()
+	_, _ = w.Write([]byte(`{"code":0,"msg":"success","data":{}}`))
+}
+
+func (b *botRecorder) received() []map[string]interface{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.messages
+}
+
+func startBot(t *testing.T) (*botRecorder, string) {
+	bot := &botRecorder{}
+	srv := httptest.NewServer(bot)
+	t.Cleanup(srv.Close)
+	return bot, srv.URL
+}
+
+func TestNotifierService_NotifyCreated(t *testing.T) {
+	env := setupFeiShu(t)
+	ctx := context.Background()
+
+	result, err := env.saveTableData(t)
+	require.NoError(t, err)
+	require.Len(t, result.Created, 3)
+
+	allSheets, allSheetsURL := startBot(t)
+	otherSheet, otherSheetURL := startBot(t)
+	env.conf.Set("notify.max_articles", 2)
+	env.conf.Set("notify.webhooks", []map[string]interface{}{
+		{"name": "运营群", "url": allSheetsURL, "secret": "bot_secret"},
+		{"name": "其他表格", "url": otherSheetURL, "sheets": []string{"shtOther"}},
+	})
+	notifier := service.NewNotifierService(env.service, env.client, env.cellDataRepo)
+
+	sheetInfo, err := env.sheetRepo.GetBySheetID(ctx, fakeSpreadsheetToken)
+	require.NoError(t, err)
+	require.NoError(t, notifier.NotifyCreated(ctx, sheetInfo, result.Created))
+
+	// 一次同步只发送一张卡片，只发给路由到该表格的机器人
+	messages := allSheets.received()
+	require.Len(t, messages, 1)
+	assert.Empty(t, otherSheet.received())
+
+	message := messages[0]
+	assert.Equal(t, "interactive", message["msg_type"])
+	timestamp, err := strconv.ParseInt(message["timestamp"].(string), 10, 64)
+	require.NoError(t, err)
+	assert.Equal(t, feishu.SignBotMessage(timestamp, "bot_secret"), message["sign"])
+
+	card := message["card"].(map[string]interface{})
+	title := card["header"].(map[string]interface{})["title"].(map[string]interface{})["content"]
+	assert.Equal(t, fakeSpreadsheetToken+" 新增 3 篇文章", title)
+	elements := card["elements"].([]interface{})
+	// 两篇文章、一条分割线和剩余文章数的提示
+	require.Len(t, elements, 4)
+	assert.Equal(t, "note", elements[3].(map[string]interface{})["tag"])
+
+	// 关闭通知后不再发送
+	sheetInfo.NotifyMuted = true
+	require.NoError(t, notifier.NotifyCreated(ctx, sheetInfo, result.Created))
+	assert.Len(t, allSheets.received(), 1)
+}
+
+func TestNotifierService_NotifyCreated_EscapeMarkdown(t *testing.T) {
+	env := setupFeiShu(t)
+	ctx := context.Background()
+
+	_, err := env.saveTableData(t)
+	require.NoError(t, err)
+
+	// 表格中的标题、链接和模型生成的摘要中含有 Markdown 字符
+	link := "https://example.com/a_(b) c"
+	require.NoError(t, env.db.Model(&model.CellData{}).
+		Where("link = ?", "https://example.feishu.cn/docx/doxFakeMilkTea").
+		Updates(map[string]interface{}{
+			"title":    "[点我](https://evil.example/) **加粗**",
+			"link":     link,
+			"abstract": "<at id=all></at> 摘要",
+		}).Error)
+
+	bot, botURL := startBot(t)
+	env.conf.Set("notify.webhooks", []map[string]interface{}{{"name": "运营群", "url": botURL}})
+	notifier := service.NewNotifierService(env.service, env.client, env.cellDataRepo)
+	sheetInfo, err := env.sheetRepo.GetBySheetID(ctx, fakeSpreadsheetToken)
+	require.NoError(t, err)
+	require.NoError(t, notifier.NotifyCreated(ctx, sheetInfo, []string{link}))
+
+	messages := bot.received()
+	require.Len(t, messages, 1)
+	elements := messages[0]["card"].(map[string]interface{})["elements"].([]interface{})
+	text := elements[0].(map[string]interface{})["text"].(map[string]interface{})
+	assert.Equal(t, "lark_md", text["tag"])
+	assert.Equal(t, "**[&#91;点我&#93;&#40;https://evil.example/&#41; &#42;&#42;加粗&#42;&#42;](https://example.com/a_%28b%29%20c)**\n"+
+		"&lt;at id=all&gt;&lt;/at&gt; 摘要", text["content"])
+}
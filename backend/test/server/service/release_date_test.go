@@ -0,0 +1,98 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "shengcai/api/v1"
+	"shengcai/internal/service"
+	"shengcai/pkg/dateparse"
+)
+
+func TestDateParse(t *testing.T) {
+	shanghai := time.FixedZone("CST", 8*3600)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, shanghai)
+
+	tests := []struct {
+		raw  string
+		want time.Time
+	}{
+		{"2023年9月11日", time.Date(2023, 9, 11, 0, 0, 0, 0, shanghai)},
+		{" 2023年09月11号 ", time.Date(2023, 9, 11, 0, 0, 0, 0, shanghai)},
+		{"2023年9月11日（周一）", time.Date(2023, 9, 11, 0, 0, 0, 0, shanghai)},
+		{"２０２３年９月１１日", time.Date(2023, 9, 11, 0, 0, 0, 0, shanghai)},
+		{"2023/9/11", time.Date(2023, 9, 11, 0, 0, 0, 0, shanghai)},
+		{"2023.09.11", time.Date(2023, 9, 11, 0, 0, 0, 0, shanghai)},
+		{"23/9/11", time.Date(2023, 9, 11, 0, 0, 0, 0, shanghai)},
+		{"2023-09-11 08:30", time.Date(2023, 9, 11, 8, 30, 0, 0, shanghai)},
+		{"2023年9月", time.Date(2023, 9, 1, 0, 0, 0, 0, shanghai)},
+		{"20230911", time.Date(2023, 9, 11, 0, 0, 0, 0, shanghai)},
+		// Excel 序列号
+		{"45180", time.Date(2023, 9, 11, 0, 0, 0, 0, shanghai)},
+		{"45180.5", time.Date(2023, 9, 11, 12, 0, 0, 0, shanghai)},
+		// 秒和毫秒时间戳
+		{"1694361600", time.Date(2023, 9, 11, 0, 0, 0, 0, shanghai)},
+		{"1694361600000", time.Date(2023, 9, 11, 0, 0, 0, 0, shanghai)},
+		{"2023-09-11T00:00:00Z", time.Date(2023, 9, 11, 8, 0, 0, 0, shanghai)},
+		// 省略年份时不会落在当前时间之后
+		{"4月30日", time.Date(2024, 4, 30, 0, 0, 0, 0, shanghai)},
+		{"9月11日", time.Date(2023, 9, 11, 0, 0, 0, 0, shanghai)},
+		{"5/1", time.Date(2024, 5, 1, 0, 0, 0, 0, shanghai)},
+	}
+	for _, tt := range tests {
+		got, err := dateparse.Parse(tt.raw, shanghai, now)
+		if assert.NoError(t, err, tt.raw) {
+			assert.True(t, tt.want.Equal(got), "%s: want %s, got %s", tt.raw, tt.want, got)
+		}
+	}
+
+	for _, raw := range []string{"", "明天", "2023/2/30", "2023-13-01", "待定"} {
+		_, err := dateparse.Parse(raw, shanghai, now)
+		assert.ErrorIs(t, err, dateparse.ErrInvalid, raw)
+	}
+}
+
+func TestShengCaiService_List_DateFilter(t *testing.T) {
+	env := setupFeiShu(t)
+	ctx := context.Background()
+	env.conf.Set("feishu.timezone", "UTC")
+	shengCai := service.NewShengCaiService(env.service, env.feiShu, env.cellDataRepo, env.sheetRepo, env.commentRepo,
+		service.NewNotifierService(env.service, env.client, env.cellDataRepo))
+
+	_, err := env.saveTableData(t)
+	require.NoError(t, err)
+
+	rows := env.cellData(t)
+	milkTea := rows["如何开一家奶茶店"]
+	require.NotNil(t, milkTea.PublishedAt)
+	assert.True(t, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC).Equal(*milkTea.PublishedAt))
+	// 原始文本保持不变
+	assert.Equal(t, "2024/05/01", milkTea.ReleaseDate)
+
+	list, err := shengCai.List(ctx, &v1.ShengCaiListRequest{
+		SpreadsheetToken: fakeSpreadsheetToken,
+		Page:             1,
+		Sort:             v1.ListSortDateDesc,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, list.TotalCount)
+	assert.Equal(t, "2024/06/12", list.List[0].ReleaseDate)
+	assert.Equal(t, "2024/05/08", list.List[1].ReleaseDate)
+	assert.Equal(t, "2024/05/01", list.List[2].ReleaseDate)
+
+	// 截止日期包含当天
+	list, err = shengCai.List(ctx, &v1.ShengCaiListRequest{
+		SpreadsheetToken: fakeSpreadsheetToken,
+		Page:             1,
+		DateFrom:         "2024-05-02",
+		DateTo:           "2024-06-12",
+		Sort:             v1.ListSortDateAsc,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, list.TotalCount)
+	assert.Equal(t, "2024/05/08", list.List[0].ReleaseDate)
+	assert.Equal(t, "2024/06/12", list.List[1].ReleaseDate)
+}
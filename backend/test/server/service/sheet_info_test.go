@@ -0,0 +1,142 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "shengcai/api/v1"
+	"shengcai/internal/model"
+	"shengcai/internal/service"
+	"shengcai/pkg/fakefeishu"
+)
+
+const fakeOriginSheetID = "shtFakeEncrypted"
+
+// recordingEventService 只记录需要同步的表格
+type recordingEventService struct {
+	service.EventService
+	scheduled []string
+}
+
+func (s *recordingEventService) ScheduleSync(ctx context.Context, sheetId string) error {
+	s.scheduled = append(s.scheduled, sheetId)
+	return nil
+}
+
+func TestSheetInfoService_ReportMirror(t *testing.T) {
+	env := setupFeiShu(t)
+	ctx := context.Background()
+	env.conf.Set("open.app_id", fakeAppID)
+	env.conf.Set("open.app_secret", fakeAppSecret)
+	events := &recordingEventService{}
+	sheetInfo := service.NewSheetInfoService(env.service, env.sheetRepo, events)
+	shengCai := service.NewShengCaiService(env.service, env.feiShu, env.cellDataRepo, env.sheetRepo, env.commentRepo,
+		service.NewNotifierService(env.service, env.client, env.cellDataRepo))
+
+	// 没有登记原始表格时必须在上报中说明拷贝的来源
+	_, err := sheetInfo.ReportMirror(ctx, &v1.SheetMirrorReportRequest{
+		SpreadsheetToken: fakeSpreadsheetToken,
+		RowCount:         3,
+		Checksum:         "c1",
+	})
+	assert.ErrorIs(t, err, v1.ErrBadRequest)
+
+	report := &v1.SheetMirrorReportRequest{
+		SpreadsheetToken: fakeSpreadsheetToken,
+		OriginSheetID:    fakeOriginSheetID,
+		OriginSheetName:  "航海手册（加密）",
+		RowCount:         3,
+		Checksum:         "c1",
+	}
+	result, err := sheetInfo.ReportMirror(ctx, report)
+	require.NoError(t, err)
+	assert.True(t, result.SyncScheduled)
+	assert.Equal(t, []string{fakeSpreadsheetToken}, events.scheduled)
+
+	// 拷贝内容没有变化时不再同步
+	result, err = sheetInfo.ReportMirror(ctx, report)
+	require.NoError(t, err)
+	assert.False(t, result.SyncScheduled)
+	assert.Len(t, events.scheduled, 1)
+
+	// 镜像表格不能被另一个原始表格的拷贝覆盖
+	_, err = sheetInfo.ReportMirror(ctx, &v1.SheetMirrorReportRequest{
+		SpreadsheetToken: fakeSpreadsheetToken,
+		OriginSheetID:    "shtAnotherOrigin",
+		Checksum:         "c2",
+	})
+	assert.ErrorIs(t, err, v1.ErrOriginMirrored)
+
+	// 原始表格已有镜像时，不能再登记另一个镜像
+	err = sheetInfo.Register(ctx, &v1.SheetRegisterRequest{
+		SpreadsheetToken: "shtAnotherMirror",
+		OriginSheetID:    fakeOriginSheetID,
+	})
+	assert.ErrorIs(t, err, v1.ErrOriginMirrored)
+
+	require.NoError(t, shengCai.SyncSheet(ctx, fakeSpreadsheetToken))
+	list, err := sheetInfo.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, list.List, 1)
+	assert.Equal(t, fakeOriginSheetID, list.List[0].OriginSheetID)
+	assert.Equal(t, 3, list.List[0].MirrorRowCount)
+	assert.False(t, list.List[0].MirrorStale)
+
+	// 文章以原始表格的身份对外展示
+	articles, err := shengCai.List(ctx, &v1.ShengCaiListRequest{SpreadsheetToken: fakeOriginSheetID, Page: 1})
+	require.NoError(t, err)
+	require.Equal(t, 3, articles.TotalCount)
+	for _, article := range articles.List {
+		assert.Equal(t, fakeOriginSheetID, article.SheetID)
+	}
+	meta, err := shengCai.GetMetaData(ctx, &v1.ShengCaiGetMetaDataRequest{SpreadsheetToken: fakeOriginSheetID})
+	require.NoError(t, err)
+	assert.Equal(t, "航海手册（加密）", meta.SheetName)
+	detail, err := shengCai.Detail(ctx, &v1.ShengCaiDetailRequest{
+		SpreadsheetToken: fakeOriginSheetID,
+		Link:             "https://example.feishu.cn/docx/doxFakeMilkTea",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, fakeOriginSheetID, detail.SheetID)
+
+	// RPA 拷贝了 5 行，同步只读到 3 行，说明镜像表格没有更新完整
+	report.RowCount = 5
+	report.Checksum = "c3"
+	result, err = sheetInfo.ReportMirror(ctx, report)
+	require.NoError(t, err)
+	assert.True(t, result.SyncScheduled)
+	fixtures, err := fakefeishu.LoadFixtures("../../fixtures/feishu")
+	require.NoError(t, err)
+	spreadsheet := fixtures.Spreadsheets[fakeSpreadsheetToken]
+	spreadsheet.LatestModifyTime = "1717181818"
+	env.fake.SetSpreadsheet(fakeSpreadsheetToken, spreadsheet)
+	require.NoError(t, shengCai.SyncSheet(ctx, fakeSpreadsheetToken))
+	list, err = sheetInfo.List(ctx)
+	require.NoError(t, err)
+	assert.True(t, list.List[0].MirrorStale)
+}
+
+func TestShengCaiService_RegisterConfiguredSheet(t *testing.T) {
+	env := setupFeiShu(t)
+	ctx := context.Background()
+	t.Setenv("spreadsheet_token", "")
+	env.conf.Set("feishu.spreadsheet_token", "shtFakeConfigured")
+	shengCai := service.NewShengCaiService(env.service, env.feiShu, env.cellDataRepo, env.sheetRepo, env.commentRepo,
+		service.NewNotifierService(env.service, env.client, env.cellDataRepo))
+
+	require.NoError(t, shengCai.RegisterConfiguredSheet(ctx))
+	sheetInfo, err := env.sheetRepo.GetBySheetID(ctx, "shtFakeConfigured")
+	require.NoError(t, err)
+	assert.Equal(t, model.SheetStatusActive, sheetInfo.Status)
+
+	// 重复登记不会修改已有的配置
+	sheetInfo.Status = model.SheetStatusPaused
+	require.NoError(t, env.sheetRepo.Save(ctx, sheetInfo))
+	require.NoError(t, shengCai.RegisterConfiguredSheet(ctx))
+	var list []*model.SheetInfo
+	require.NoError(t, env.db.Where("sheet_id = ?", "shtFakeConfigured").Find(&list).Error)
+	require.Len(t, list, 1)
+	assert.Equal(t, model.SheetStatusPaused, list[0].Status)
+}
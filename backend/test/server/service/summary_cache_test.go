@@ -0,0 +1,129 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"shengcai/internal/model"
+	"shengcai/pkg/fakefeishu"
+)
+
+func articleRow(number int, title string, link string, date string) []interface{} {
+	return []interface{}{number, []interface{}{map[string]interface{}{"type": "url", "text": title, "link": link}}, "易生", date}
+}
+
+func TestFeiShuService_SummaryCache(t *testing.T) {
+	env := setupFeiShu(t)
+	ctx := context.Background()
+	env.conf.Set("ai.generate", "open")
+
+	_, err := env.saveTableData(t)
+	require.NoError(t, err)
+	before := env.cellData(t)
+	var calls int64
+	require.NoError(t, env.db.Model(&model.AIUsage{}).Count(&calls).Error)
+	require.Equal(t, int64(3), calls)
+
+	// 在表格顶部插入一行，其余文章的序号和发布日期变化，只更新元数据
+	header := []interface{}{"序号", "标题", "作者", "发布日期"}
+	env.fake.SetSpreadsheet(fakeSpreadsheetToken, &fakefeishu.Spreadsheet{
+		LatestModifyTime: "1717181818",
+		Revision:         13,
+		Sheets: []*fakefeishu.Sheet{{
+			SheetID: "tab001",
+			Title:   "精华帖",
+			Values: [][]interface{}{
+				header,
+				articleRow(1, "线下实体复苏访谈（转载）", "https://example.feishu.cn/docx/doxFakeInterview", "2024/06/13"),
+				articleRow(2, "如何开一家奶茶店", "https://example.feishu.cn/docx/doxFakeMilkTea", "2024/05/02"),
+				articleRow(3, "小红书起号复盘", "https://example.feishu.cn/docx/doxFakeRedBook", "2024/05/08"),
+			},
+		}},
+	})
+	_, err = env.saveTableData(t)
+	require.NoError(t, err)
+
+	after := env.cellData(t)
+	milkTea := after["如何开一家奶茶店"]
+	assert.Equal(t, before["如何开一家奶茶店"].SortNumber+1, milkTea.SortNumber)
+	assert.Equal(t, "2024/05/02", milkTea.ReleaseDate)
+	assert.Equal(t, before["如何开一家奶茶店"].Abstract, milkTea.Abstract)
+	assert.Equal(t, before["如何开一家奶茶店"].ContentHash, milkTea.ContentHash)
+	assert.Len(t, milkTea.ContentHash, 64)
+
+	// 同一篇文档出现在另一个工作表中，使用缓存的摘要
+	repost := after["线下实体复苏访谈（转载）"]
+	require.NotNil(t, repost)
+	assert.Equal(t, before["线下实体复苏访谈"].Abstract, repost.Abstract)
+	require.NoError(t, env.db.Model(&model.AIUsage{}).Count(&calls).Error)
+	assert.Equal(t, int64(3), calls)
+
+	// 其他表格中相同的文章同样只总结一次
+	const otherToken = "shtFakeOther"
+	require.NoError(t, env.sheetRepo.Create(ctx, &model.SheetInfo{SheetID: otherToken, Status: model.SheetStatusActive}))
+	env.fake.SetSpreadsheet(otherToken, &fakefeishu.Spreadsheet{
+		LatestModifyTime: "1717191919",
+		Revision:         1,
+		Sheets: []*fakefeishu.Sheet{{
+			SheetID: "tab101",
+			Title:   "合集",
+			Values: [][]interface{}{
+				header,
+				articleRow(1, "如何开一家奶茶店", "https://example.feishu.cn/docx/doxFakeMilkTea", "2024/05/01"),
+			},
+		}},
+	})
+	other, err := env.sheetRepo.GetBySheetID(ctx, otherToken)
+	require.NoError(t, err)
+	_, err = env.feiShu.SaveTableData(ctx, fakeAppID, fakeAppSecret, other)
+	require.NoError(t, err)
+
+	var copied model.CellData
+	require.NoError(t, env.db.Where("sheet_id = ?", otherToken).First(&copied).Error)
+	assert.Equal(t, milkTea.Abstract, copied.Abstract)
+	assert.Equal(t, model.AIStateOK, copied.AIState)
+	require.NoError(t, env.db.Model(&model.AIUsage{}).Count(&calls).Error)
+	assert.Equal(t, int64(3), calls)
+}
+
+func TestFeiShuService_SummaryCache_FetchFailures(t *testing.T) {
+	env := setupFeiShu(t)
+	env.conf.Set("ai.generate", "open")
+	env.conf.Set("ai.comment_digest", true)
+	env.conf.Set("feishu.docx_markdown", true)
+	env.conf.Set("media.enabled", false)
+	env.fake.SetDocumentBlocks("doxFakeMilkTea", []map[string]interface{}{
+		docxPage("h1"),
+		docxTextBlock("h1", 3, "heading1", "如何开一家奶茶店"),
+	})
+
+	_, err := env.saveTableData(t)
+	require.NoError(t, err)
+	before := env.cellData(t)["如何开一家奶茶店"]
+	require.NotNil(t, before)
+	require.Equal(t, "# 如何开一家奶茶店", before.ContentMarkdown)
+	var calls int64
+	require.NoError(t, env.db.Model(&model.AIUsage{}).Count(&calls).Error)
+	require.Equal(t, int64(3), calls)
+
+	// 正文不变，Markdown 和评论获取失败时不重新生成摘要
+	fixtures, err := fakefeishu.LoadFixtures("../../fixtures/feishu")
+	require.NoError(t, err)
+	spreadsheet := fixtures.Spreadsheets[fakeSpreadsheetToken]
+	spreadsheet.LatestModifyTime = "1717181818"
+	env.fake.SetSpreadsheet(fakeSpreadsheetToken, spreadsheet)
+	env.fake.AddFault(fakefeishu.Forbidden("/open-apis/docx/v1/documents/doxFakeMilkTea/blocks", 0))
+	env.fake.AddFault(fakefeishu.Forbidden("/open-apis/drive/v1/files/", 0))
+
+	_, err = env.saveTableData(t)
+	require.NoError(t, err)
+	after := env.cellData(t)["如何开一家奶茶店"]
+	require.NotNil(t, after)
+	assert.Equal(t, before.ContentHash, after.ContentHash)
+	assert.Equal(t, before.Abstract, after.Abstract)
+	assert.Equal(t, before.ContentMarkdown, after.ContentMarkdown)
+	require.NoError(t, env.db.Model(&model.AIUsage{}).Count(&calls).Error)
+	assert.Equal(t, int64(3), calls)
+}
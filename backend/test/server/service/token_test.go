@@ -0,0 +1,71 @@
+package service_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"shengcai/pkg/fakefeishu"
+	"shengcai/pkg/feishu"
+)
+
+const fakeTokenPath = "/open-apis/auth/"
+
+func TestTokenProvider_Concurrent(t *testing.T) {
+	fake := fakefeishu.NewServer(nil)
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	// 放慢获取凭证的接口，让所有协程都在第一次请求返回前发起调用
+	fake.AddFault(fakefeishu.Slow(fakeTokenPath, 200*time.Millisecond, 1))
+
+	provider := feishu.NewTokenProvider(http.DefaultClient, srv.URL)
+	var wg sync.WaitGroup
+	tokens := make([]string, 20)
+	errs := make([]error, 20)
+	for i := range tokens {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tokens[i], errs[i] = provider.Token(context.Background(), fakeAppID, fakeAppSecret)
+		}(i)
+	}
+	wg.Wait()
+
+	for i := range tokens {
+		require.NoError(t, errs[i])
+		assert.Equal(t, tokens[0], tokens[i])
+	}
+	assert.NotEmpty(t, tokens[0])
+	assert.Equal(t, 1, fake.Requests(fakeTokenPath))
+
+	// 缓存未过期时不再请求
+	token, err := provider.Token(context.Background(), fakeAppID, fakeAppSecret)
+	require.NoError(t, err)
+	assert.Equal(t, tokens[0], token)
+	assert.Equal(t, 1, fake.Requests(fakeTokenPath))
+}
+
+func TestClient_ExpireTokens(t *testing.T) {
+	env := setupFeiShu(t)
+	ctx := context.Background()
+	path := "/open-apis/sheets/v3/spreadsheets/" + fakeSpreadsheetToken + "/sheets/query"
+
+	var response map[string]interface{}
+	require.NoError(t, env.client.Do(ctx, fakeAppID, fakeAppSecret, http.MethodGet, path, nil, &response))
+	assert.Equal(t, 1, env.fake.Requests(fakeTokenPath))
+
+	// 凭证在飞书侧失效后，客户端刷新凭证并重试一次
+	env.fake.ExpireTokens()
+	require.NoError(t, env.client.Do(ctx, fakeAppID, fakeAppSecret, http.MethodGet, path, nil, &response))
+	assert.Equal(t, 2, env.fake.Requests(fakeTokenPath))
+	assert.Equal(t, 3, env.fake.Requests(path))
+
+	// 刷新后的凭证继续使用缓存
+	require.NoError(t, env.client.Do(ctx, fakeAppID, fakeAppSecret, http.MethodGet, path, nil, &response))
+	assert.Equal(t, 2, env.fake.Requests(fakeTokenPath))
+}
@@ -12,6 +12,7 @@ import (
 	"testing"
 
 	"github.com/golang/mock/gomock"
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/crypto/bcrypt"
 	"shengcai/internal/model"
@@ -25,6 +26,7 @@ var (
 	logger *log.Logger
 	j      *jwt.JWT
 	sf     *sid.Sid
+	conf   *viper.Viper
 )
 
 func TestMain(m *testing.M) {
@@ -37,7 +39,7 @@ func TestMain(m *testing.M) {
 
 	var envConf = flag.String("conf", "config/local.yml", "config path, eg: -conf ./config/local.yml")
 	flag.Parse()
-	conf := config.NewConfig(*envConf)
+	conf = config.NewConfig(*envConf)
 
 	logger = log.NewLog(conf)
 	j = jwt.NewJwt(conf)
@@ -55,7 +57,7 @@ func TestUserService_Register(t *testing.T) {
 
 	mockUserRepo := mock_repository.NewMockUserRepository(ctrl)
 	mockTm := mock_repository.NewMockTransaction(ctrl)
-	srv := service.NewService(mockTm, logger, sf, j)
+	srv := service.NewService(mockTm, logger, sf, j, conf)
 
 	userService := service.NewUserService(srv, mockUserRepo)
 
@@ -79,7 +81,7 @@ func TestUserService_Register_UsernameExists(t *testing.T) {
 
 	mockUserRepo := mock_repository.NewMockUserRepository(ctrl)
 	mockTm := mock_repository.NewMockTransaction(ctrl)
-	srv := service.NewService(mockTm, logger, sf, j)
+	srv := service.NewService(mockTm, logger, sf, j, conf)
 	userService := service.NewUserService(srv, mockUserRepo)
 
 	ctx := context.Background()
@@ -101,7 +103,7 @@ func TestUserService_Login(t *testing.T) {
 
 	mockUserRepo := mock_repository.NewMockUserRepository(ctrl)
 	mockTm := mock_repository.NewMockTransaction(ctrl)
-	srv := service.NewService(mockTm, logger, sf, j)
+	srv := service.NewService(mockTm, logger, sf, j, conf)
 	userService := service.NewUserService(srv, mockUserRepo)
 
 	ctx := context.Background()
@@ -130,7 +132,7 @@ func TestUserService_Login_UserNotFound(t *testing.T) {
 
 	mockUserRepo := mock_repository.NewMockUserRepository(ctrl)
 	mockTm := mock_repository.NewMockTransaction(ctrl)
-	srv := service.NewService(mockTm, logger, sf, j)
+	srv := service.NewService(mockTm, logger, sf, j, conf)
 	userService := service.NewUserService(srv, mockUserRepo)
 
 	ctx := context.Background()
@@ -152,7 +154,7 @@ func TestUserService_GetProfile(t *testing.T) {
 
 	mockUserRepo := mock_repository.NewMockUserRepository(ctrl)
 	mockTm := mock_repository.NewMockTransaction(ctrl)
-	srv := service.NewService(mockTm, logger, sf, j)
+	srv := service.NewService(mockTm, logger, sf, j, conf)
 	userService := service.NewUserService(srv, mockUserRepo)
 
 	ctx := context.Background()
@@ -175,7 +177,7 @@ func TestUserService_UpdateProfile(t *testing.T) {
 
 	mockUserRepo := mock_repository.NewMockUserRepository(ctrl)
 	mockTm := mock_repository.NewMockTransaction(ctrl)
-	srv := service.NewService(mockTm, logger, sf, j)
+	srv := service.NewService(mockTm, logger, sf, j, conf)
 	userService := service.NewUserService(srv, mockUserRepo)
 
 	ctx := context.Background()
@@ -202,7 +204,7 @@ func TestUserService_UpdateProfile_UserNotFound(t *testing.T) {
 
 	mockUserRepo := mock_repository.NewMockUserRepository(ctrl)
 	mockTm := mock_repository.NewMockTransaction(ctrl)
-	srv := service.NewService(mockTm, logger, sf, j)
+	srv := service.NewService(mockTm, logger, sf, j, conf)
 	userService := service.NewUserService(srv, mockUserRepo)
 
 	ctx := context.Background()